@@ -30,6 +30,36 @@ Arguments
 
 -certPath <the location of a client certificate>
 
+-configDriftInterval <how often the running configuration of devices is compared with the intended one>
+
+-remediationPolicy <when the intended configuration is pushed to devices again: none, connect, drift or always>
+
+-modelRemediationPolicies <remediation policies by model e.g. Devicesim-1.0.0=connect,Stratum-1.0.0=drift>
+
+-credentialsCheckInterval <how often the certificate files of devices are checked for changes>
+
+-opStateSubscription <how operational state is subscribed to e.g. mode=sample,sample=10s,heartbeat=1m,suppress=true,grouping=root,ignore=unsupported yet,ttl=5m>
+
+-modelOpStateSubscriptions <operational state subscriptions by model e.g. Devicesim-1.0.0:mode=on_change;Stratum-1.0.0:poll=true,sample=30s>
+
+-opStateReplicationInterval <how often the operational state of devices is replicated to the other onos-config nodes>
+
+-opStateHistorySize <how many samples of each operational state path are kept in the history>
+
+-opStateHistoryAge <how long samples of operational state are kept in the history>
+
+-southboundErrorsSize <how many recent southbound errors are kept per device>
+
+-strictModels <refuse to configure devices that do not advertise the models of their plugin>
+
+-autoDetectModels <detect the type and version of devices without them from their capabilities>
+
+-standalone <run as a single node with local stores, without Atomix or onos-topo>
+
+-devices <the YAML or JSON file listing the devices to manage in standalone mode>
+
+-database <the file keeping the changes and snapshots in standalone mode, rather than memory>
+
 
 See ../../docs/run.md for how to run the application.
 */
@@ -45,11 +75,14 @@ import (
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 	"github.com/onosproject/onos-lib-go/pkg/cluster"
 
+	"github.com/onosproject/onos-config/pkg/alarms"
 	"github.com/onosproject/onos-config/pkg/config"
 	"github.com/onosproject/onos-config/pkg/manager"
 	"github.com/onosproject/onos-config/pkg/northbound/admin"
 	"github.com/onosproject/onos-config/pkg/northbound/diags"
 	"github.com/onosproject/onos-config/pkg/northbound/gnmi"
+	"github.com/onosproject/onos-config/pkg/southbound/synchronizer"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
 	"github.com/onosproject/onos-config/pkg/store/change/device"
 	"github.com/onosproject/onos-config/pkg/store/change/device/rbac"
 	"github.com/onosproject/onos-config/pkg/store/change/device/state"
@@ -58,11 +91,13 @@ import (
 	"github.com/onosproject/onos-config/pkg/store/device/cache"
 	"github.com/onosproject/onos-config/pkg/store/leadership"
 	"github.com/onosproject/onos-config/pkg/store/mastership"
+	"github.com/onosproject/onos-config/pkg/store/opstate"
 	devicesnap "github.com/onosproject/onos-config/pkg/store/snapshot/device"
 	networksnap "github.com/onosproject/onos-config/pkg/store/snapshot/network"
 	"github.com/onosproject/onos-lib-go/pkg/certs"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"google.golang.org/grpc"
 )
 
 // OIDCServerURL - address of an OpenID Connect server
@@ -71,6 +106,17 @@ const OIDCServerURL = "OIDC_SERVER_URL"
 // RbacVersionedID - the internal device where RBAC rules are configured
 const RbacVersionedID = "rbac:1.0.0"
 
+// AlarmsVersionedID - the internal device where alarm rules are configured. There is no model
+// plugin for it, changes to it are validated against the alarm rules instead
+const AlarmsVersionedID = "alarms:1.0.0"
+
+const (
+	// standaloneClusterID is the cluster of the local stores in standalone mode
+	standaloneClusterID = "onos-config"
+	// standaloneNodeID is the node in standalone mode
+	standaloneNodeID = cluster.NodeID("onos-config")
+)
+
 var log = logging.GetLogger("main")
 
 // ClusterFactory creates the cluster
@@ -89,6 +135,21 @@ func main() {
 	keyPath := flag.String("keyPath", "", "path to client private key")
 	certPath := flag.String("certPath", "", "path to client certificate")
 	topoEndpoint := flag.String("topoEndpoint", "onos-topo:5150", "topology service endpoint")
+	remediationPolicy := flag.String("remediationPolicy", string(synchronizer.RemediationNone), "when the intended configuration is pushed to devices again: none, connect, drift or always")
+	modelRemediationPolicies := flag.String("modelRemediationPolicies", "", "remediation policies by model e.g. Devicesim-1.0.0=connect")
+	credentialsCheckInterval := flag.Duration("credentialsCheckInterval", synchronizer.DefaultCredentialsCheckInterval, "interval for checking device certificate files for changes; 0 disables it")
+	opStateSubscription := flag.String("opStateSubscription", "", "how operational state is subscribed to e.g. mode=sample,sample=10s,heartbeat=1m,suppress=true,grouping=root")
+	modelOpStateSubscriptions := flag.String("modelOpStateSubscriptions", "", "operational state subscriptions by model e.g. Devicesim-1.0.0:mode=on_change")
+	opStateReplicationInterval := flag.Duration("opStateReplicationInterval", synchronizer.DefaultOpStateReplicationInterval, "interval for replicating the operational state of devices to the other nodes")
+	opStateHistorySize := flag.Int("opStateHistorySize", 0, "number of samples kept per operational state path; 0 with no age disables the history")
+	opStateHistoryAge := flag.Duration("opStateHistoryAge", 0, "age up to which operational state samples are kept; 0 with no size disables the history")
+	southboundErrorsSize := flag.Int("southboundErrorsSize", synchronizer.DefaultSouthboundErrorsSize, "number of recent southbound errors kept per device; 0 disables keeping them")
+	strictModels := flag.Bool("strictModels", false, "refuse to configure devices that do not advertise the models of their plugin in their capabilities")
+	autoDetectModels := flag.Bool("autoDetectModels", false, "detect the type and version of devices without them from the models they advertise in their capabilities")
+	standalone := flag.Bool("standalone", false, "run as a single node with local stores and the devices of a file rather than Atomix and onos-topo")
+	devicesFile := flag.String("devices", "", "YAML or JSON file listing the devices to manage in standalone mode")
+	databaseFile := flag.String("database", "", "file keeping the changes and snapshots in standalone mode; when not set they are kept in memory")
+	configDriftInterval := flag.Duration("configDriftInterval", 0, "interval for checking device configuration drift; 0 checks only on connect")
 	//This flag is used in logging.init()
 	flag.Bool("debug", false, "enable debug logging")
 	flag.Parse()
@@ -100,100 +161,248 @@ func main() {
 		log.Fatal(err)
 	}
 
+	var stores *configStores
+	if *standalone {
+		stores, err = newLocalStores(*devicesFile, *databaseFile, *autoDetectModels)
+	} else {
+		stores, err = newAtomixStores(*topoEndpoint, *autoDetectModels, opts...)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	deviceStateStore, err := state.NewStore(stores.networkChanges, stores.deviceSnapshots)
+	if err != nil {
+		log.Fatal("Cannot load device state store ", err)
+	}
+
+	deviceCache, err := cache.NewCache(stores.networkChanges, stores.deviceSnapshots)
+	if err != nil {
+		log.Fatal("Cannot load device cache", err)
+	}
+
+	authorization := false
+	var rbacCache rbac.Cache
+	if oidcURL := os.Getenv(OIDCServerURL); oidcURL != "" {
+		authorization = true
+		rbacCache, err = rbac.NewRbacCache(stores.deviceChanges, stores.deviceSnapshots, RbacVersionedID)
+		if err != nil {
+			log.Fatal("Cannot create RBAC cache %v", err)
+		}
+		log.Infof("Authorization enabled. %s=%s", OIDCServerURL, oidcURL)
+		// OIDCServerURL is also referenced in jwt.go (from onos-lib-go)
+	} else {
+		log.Infof("Authorization not enabled %s", os.Getenv(OIDCServerURL))
+	}
+
+	modelRegistry, err := modelregistry.NewModelRegistry(modelregistry.Config{})
+	if err != nil {
+		log.Fatal("Failed to load model registry:", err)
+	}
+
+	mgr := manager.NewManager(stores.leadership, stores.mastership, stores.deviceChanges,
+		deviceStateStore, stores.devices, deviceCache, stores.networkChanges, stores.networkSnapshots,
+		stores.deviceSnapshots, *allowUnvalidatedConfig, rbacCache, modelRegistry)
+	mgr.ConfigDriftInterval = *configDriftInterval
+	mgr.CredentialsCheckInterval = *credentialsCheckInterval
+	mgr.OpStateStore = stores.opState
+	mgr.OpStateReplicationInterval = *opStateReplicationInterval
+	mgr.OpStateHistory = synchronizer.NewOpStateHistory(*opStateHistorySize, *opStateHistoryAge)
+	mgr.SouthboundErrors = synchronizer.NewSouthboundErrors(*southboundErrorsSize)
+	mgr.StrictModels = *strictModels
+	mgr.AutoDetectModels = *autoDetectModels
+	mgr.RemediationPolicy, err = synchronizer.ParseRemediationPolicy(*remediationPolicy)
+	if err != nil {
+		log.Fatal("Invalid remediation policy ", err)
+	}
+	mgr.ModelRemediationPolicies, err = synchronizer.ParseModelRemediationPolicies(*modelRemediationPolicies)
+	if err != nil {
+		log.Fatal("Invalid model remediation policies ", err)
+	}
+	mgr.OpStateSubscription, err = synchronizer.ParseOpStateSubscription(*opStateSubscription)
+	if err != nil {
+		log.Fatal("Invalid operational state subscription ", err)
+	}
+	mgr.ModelOpStateSubscriptions, err = synchronizer.ParseModelOpStateSubscriptions(*modelOpStateSubscriptions)
+	if err != nil {
+		log.Fatal("Invalid model operational state subscriptions ", err)
+	}
+	mgr.Alarms, err = alarms.NewAlarms(stores.deviceChanges, stores.deviceSnapshots, mgr.Dispatcher, mgr.OperationalStateChannel,
+		mgr.OperationalStateCache, mgr.OperationalStateCacheLock, AlarmsVersionedID)
+	if err != nil {
+		log.Fatal("Cannot start alarms ", err)
+	}
+	log.Info("Manager created")
+
+	defer func() {
+		close(mgr.TopoChannel)
+		log.Info("Shutting down onos-config")
+		time.Sleep(time.Second)
+	}()
+
+	mgr.Run()
+
+	err = startServer(*caPath, *keyPath, *certPath, authorization)
+	if err != nil {
+		log.Fatal("Unable to start onos-config ", err)
+	}
+}
+
+// configStores are the stores onos-config runs with
+type configStores struct {
+	leadership       leadership.Store
+	mastership       mastership.Store
+	deviceChanges    device.Store
+	networkChanges   network.Store
+	networkSnapshots networksnap.Store
+	deviceSnapshots  devicesnap.Store
+	opState          opstate.Store
+	devices          devicestore.Store
+}
+
+// newAtomixStores creates the stores shared with the other nodes through Atomix, and the
+// device store of the topo service, which has untyped devices if their models are detected
+func newAtomixStores(topoEndpoint string, autoDetectModels bool, opts ...grpc.DialOption) (*configStores, error) {
 	configuration, err := config.GetConfig()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	cluster, err := ClusterFactory(configuration)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	leadershipStore, err := leadership.NewAtomixStore(cluster, configuration)
+	stores := &configStores{}
+	stores.leadership, err = leadership.NewAtomixStore(cluster, configuration)
 	if err != nil {
-		log.Fatal("Cannot load leadership atomix store ", err)
+		return nil, fmt.Errorf("cannot load leadership atomix store: %v", err)
 	}
 
-	mastershipStore, err := mastership.NewAtomixStore(cluster, configuration)
+	stores.mastership, err = mastership.NewAtomixStore(cluster, configuration)
 	if err != nil {
-		log.Fatal("Cannot load mastership atomix store ", err)
+		return nil, fmt.Errorf("cannot load mastership atomix store: %v", err)
 	}
 
-	deviceChangesStore, err := device.NewAtomixStore(configuration)
+	stores.deviceChanges, err = device.NewAtomixStore(configuration)
 	if err != nil {
-		log.Fatal("Cannot load device atomix store ", err)
+		return nil, fmt.Errorf("cannot load device atomix store: %v", err)
 	}
 
-	networkChangesStore, err := network.NewAtomixStore(cluster, configuration)
+	stores.networkChanges, err = network.NewAtomixStore(cluster, configuration)
 	if err != nil {
-		log.Fatal("Cannot load network atomix store ", err)
+		return nil, fmt.Errorf("cannot load network atomix store: %v", err)
 	}
 
-	networkSnapshotStore, err := networksnap.NewAtomixStore(cluster, configuration)
+	stores.networkSnapshots, err = networksnap.NewAtomixStore(cluster, configuration)
 	if err != nil {
-		log.Fatal("Cannot load network snapshot atomix store ", err)
+		return nil, fmt.Errorf("cannot load network snapshot atomix store: %v", err)
 	}
 
-	deviceSnapshotStore, err := devicesnap.NewAtomixStore(configuration)
+	stores.deviceSnapshots, err = devicesnap.NewAtomixStore(configuration)
 	if err != nil {
-		log.Fatal("Cannot load network atomix store ", err)
+		return nil, fmt.Errorf("cannot load device snapshot atomix store: %v", err)
 	}
 
-	deviceStateStore, err := state.NewStore(networkChangesStore, deviceSnapshotStore)
+	stores.opState, err = opstate.NewAtomixStore(configuration)
 	if err != nil {
-		log.Fatal("Cannot load device store with address %s:", *topoEndpoint, err)
+		return nil, fmt.Errorf("cannot load operational state atomix store: %v", err)
 	}
-	log.Infof("Topology service connected with endpoint %s", *topoEndpoint)
 
-	deviceCache, err := cache.NewCache(networkChangesStore, deviceSnapshotStore)
+	stores.devices, err = devicestore.NewTopoStore(topoEndpoint, autoDetectModels, opts...)
 	if err != nil {
-		log.Fatal("Cannot load device cache", err)
+		return nil, fmt.Errorf("cannot load device store with address %s: %v", topoEndpoint, err)
 	}
+	log.Infof("Topology service connected with endpoint %s", topoEndpoint)
+	return stores, nil
+}
 
-	deviceStore, err := devicestore.NewTopoStore(*topoEndpoint, opts...)
+// newLocalStores creates in-process stores for running as a single node, and the device
+// store of the devices listed in a file. The changes and snapshots are kept in the database
+// file if one is given, and otherwise in memory
+func newLocalStores(devicesFile string, databaseFile string, autoDetectModels bool) (*configStores, error) {
+	if devicesFile == "" {
+		return nil, fmt.Errorf("a devices file is required in standalone mode")
+	}
+	var err error
+	stores := &configStores{}
+	stores.leadership, err = leadership.NewLocalStore(standaloneClusterID, standaloneNodeID)
 	if err != nil {
-		log.Fatal("Cannot load device store with address %s:", *topoEndpoint, err)
+		return nil, fmt.Errorf("cannot load leadership local store: %v", err)
 	}
-	log.Infof("Topology service connected with endpoint %s", *topoEndpoint)
 
-	authorization := false
-	var rbacCache rbac.Cache
-	if oidcURL := os.Getenv(OIDCServerURL); oidcURL != "" {
-		authorization = true
-		rbacCache, err = rbac.NewRbacCache(deviceChangesStore, deviceSnapshotStore, RbacVersionedID)
-		if err != nil {
-			log.Fatal("Cannot create RBAC cache %v", err)
+	stores.mastership, err = mastership.NewLocalStore(standaloneClusterID, standaloneNodeID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load mastership local store: %v", err)
+	}
+
+	if databaseFile != "" {
+		if err := stores.openBoltStores(databaseFile); err != nil {
+			return nil, err
 		}
-		log.Infof("Authorization enabled. %s=%s", OIDCServerURL, oidcURL)
-		// OIDCServerURL is also referenced in jwt.go (from onos-lib-go)
 	} else {
-		log.Infof("Authorization not enabled %s", os.Getenv(OIDCServerURL))
+		stores.deviceChanges, err = device.NewLocalStore()
+		if err != nil {
+			return nil, fmt.Errorf("cannot load device local store: %v", err)
+		}
+
+		stores.networkChanges, err = network.NewLocalStore()
+		if err != nil {
+			return nil, fmt.Errorf("cannot load network local store: %v", err)
+		}
+
+		stores.networkSnapshots, err = networksnap.NewLocalStore()
+		if err != nil {
+			return nil, fmt.Errorf("cannot load network snapshot local store: %v", err)
+		}
+
+		stores.deviceSnapshots, err = devicesnap.NewLocalStore()
+		if err != nil {
+			return nil, fmt.Errorf("cannot load device snapshot local store: %v", err)
+		}
 	}
 
-	modelRegistry, err := modelregistry.NewModelRegistry(modelregistry.Config{})
+	stores.opState, err = opstate.NewLocalStore()
 	if err != nil {
-		log.Fatal("Failed to load model registry:", err)
+		return nil, fmt.Errorf("cannot load operational state local store: %v", err)
 	}
 
-	mgr := manager.NewManager(leadershipStore, mastershipStore, deviceChangesStore,
-		deviceStateStore, deviceStore, deviceCache, networkChangesStore, networkSnapshotStore,
-		deviceSnapshotStore, *allowUnvalidatedConfig, rbacCache, modelRegistry)
-	log.Info("Manager created")
+	stores.devices, err = devicestore.NewFileStore(devicesFile, autoDetectModels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load devices from %s: %v", devicesFile, err)
+	}
+	log.Infof("Running standalone with the devices of %s", devicesFile)
+	return stores, nil
+}
 
-	defer func() {
-		close(mgr.TopoChannel)
-		log.Info("Shutting down onos-config")
-		time.Sleep(time.Second)
-	}()
+// openBoltStores creates the change and snapshot stores kept in a database file
+func (s *configStores) openBoltStores(databaseFile string) error {
+	db, err := boltdb.Open(databaseFile)
+	if err != nil {
+		return err
+	}
 
-	mgr.Run()
+	s.deviceChanges, err = device.NewBoltStore(db)
+	if err != nil {
+		return fmt.Errorf("cannot load device bolt store: %v", err)
+	}
 
-	err = startServer(*caPath, *keyPath, *certPath, authorization)
+	s.networkChanges, err = network.NewBoltStore(db)
 	if err != nil {
-		log.Fatal("Unable to start onos-config ", err)
+		return fmt.Errorf("cannot load network bolt store: %v", err)
+	}
+
+	s.networkSnapshots, err = networksnap.NewBoltStore(db)
+	if err != nil {
+		return fmt.Errorf("cannot load network snapshot bolt store: %v", err)
+	}
+
+	s.deviceSnapshots, err = devicesnap.NewBoltStore(db)
+	if err != nil {
+		return fmt.Errorf("cannot load device snapshot bolt store: %v", err)
 	}
+	log.Infof("Keeping the changes and snapshots in %s", databaseFile)
+	return nil
 }
 
 // Creates gRPC server and registers various services; then serves.
@@ -0,0 +1,496 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package alarms raises alarms on the operational state of devices. The alarm rules are
+// configured on an internal device, whose operational state holds the active alarms
+// so that they can be read and subscribed to through gNMI like those of any device.
+package alarms
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/dispatcher"
+	"github.com/onosproject/onos-config/pkg/events"
+	devicechangestore "github.com/onosproject/onos-config/pkg/store/change/device"
+	devicesnapshotstore "github.com/onosproject/onos-config/pkg/store/snapshot/device"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("alarms")
+
+// maxHistory is the number of raise and clear events kept
+const maxHistory = 1000
+
+// alarmPath is the path of the leaves of an active alarm in the operational state of
+// the alarms device
+const alarmPath = "/alarms/alarm[id=%s]/state/%s"
+
+// Alarm is an alarm raised by a rule on a path of a device
+type Alarm struct {
+	// ID identifies the alarm by its rule, device and path
+	ID string
+	// RuleID is the identifier of the rule that raised the alarm
+	RuleID string
+	// DeviceID is the device the alarm is raised on
+	DeviceID topodevice.ID
+	// Path is the path the alarm is raised on
+	Path string
+	// Severity is the severity of the alarm
+	Severity Severity
+	// Description describes the alarm
+	Description string
+	// Value is the value that raised the alarm
+	Value string
+	// Raised is when the alarm was raised
+	Raised time.Time
+	// Cleared is when the alarm was cleared, zero while it is active
+	Cleared time.Time
+}
+
+// EventType is the type of an alarm event
+type EventType string
+
+const (
+	// EventRaised is the raising of an alarm
+	EventRaised EventType = "raised"
+	// EventCleared is the clearing of an alarm
+	EventCleared EventType = "cleared"
+)
+
+// Event is the raising or clearing of an alarm
+type Event struct {
+	// Type is the type of the event
+	Type EventType
+	// Alarm is the alarm as of the event
+	Alarm Alarm
+}
+
+// Alarms evaluates the alarm rules on the operational state of devices
+type Alarms interface {
+	io.Closer
+
+	// DeviceID returns the internal device the rules are configured on
+	DeviceID() device.VersionedID
+
+	// Rules returns the alarm rules, by ID
+	Rules() map[string]*Rule
+
+	// Active returns the active alarms, the oldest first
+	Active() []Alarm
+
+	// History returns the last raise and clear events, the oldest first
+	History() []Event
+}
+
+// sample is a value of a path of a device as seen by the rules
+type sample struct {
+	text      string
+	number    float64
+	numeric   bool
+	timestamp int64
+}
+
+// NewAlarms starts evaluating the alarm rules configured on the given internal device on
+// the operational state events of the dispatcher. Active alarms are kept in the operational
+// state cache as values of the alarms device and notified on the operational state channel.
+// There is no model plugin for the alarms device, changes to it are validated with ValidateRules
+func NewAlarms(deviceChangesStore devicechangestore.Store, deviceSnapshotStore devicesnapshotstore.Store, dispatcher *dispatcher.Dispatcher,
+	opStateChan chan<- events.OperationalStateEvent, opStateCache map[topodevice.ID]devicechange.TypedValueMap,
+	opStateCacheLock *sync.RWMutex, alarmsInternalDevice device.VersionedID) (Alarms, error) {
+	a := newAlarms(opStateCache, opStateCacheLock, alarmsInternalDevice)
+	a.opStateChan = opStateChan
+	a.dispatcher = dispatcher
+	if err := a.listen(deviceChangesStore, deviceSnapshotStore); err != nil {
+		return nil, err
+	}
+	opStateEvents, err := dispatcher.RegisterOpState(dispatcherListener)
+	if err != nil {
+		return nil, err
+	}
+	go a.publish()
+	go func() {
+		for event := range opStateEvents {
+			a.evaluate(event)
+		}
+	}()
+	return a, nil
+}
+
+// dispatcherListener is the name the alarms listen to the dispatcher with
+const dispatcherListener = "alarms"
+
+func newAlarms(opStateCache map[topodevice.ID]devicechange.TypedValueMap, opStateCacheLock *sync.RWMutex,
+	alarmsInternalDevice device.VersionedID) *alarms {
+	a := &alarms{
+		id:               alarmsInternalDevice,
+		deviceID:         topodevice.ID(alarmsInternalDevice.GetID()),
+		config:           make(devicechange.TypedValueMap),
+		rules:            make(map[string]*Rule),
+		active:           make(map[string]*Alarm),
+		samples:          make(map[string]sample),
+		history:          make([]Event, 0),
+		opStateCache:     opStateCache,
+		opStateCacheLock: opStateCacheLock,
+		published:        make(chan struct{}, 1),
+	}
+	a.opStateCacheLock.Lock()
+	a.opStateCache[a.deviceID] = make(devicechange.TypedValueMap)
+	a.opStateCacheLock.Unlock()
+	return a
+}
+
+// alarms is the default implementation of Alarms
+type alarms struct {
+	id               device.VersionedID
+	deviceID         topodevice.ID
+	config           devicechange.TypedValueMap
+	rules            map[string]*Rule
+	active           map[string]*Alarm
+	samples          map[string]sample
+	history          []Event
+	opStateCache     map[topodevice.ID]devicechange.TypedValueMap
+	opStateCacheLock *sync.RWMutex
+	opStateChan      chan<- events.OperationalStateEvent
+	dispatcher       *dispatcher.Dispatcher
+	streamCtx        stream.Context
+	// pending holds the events to notify, which are sent apart from the evaluation as
+	// the dispatcher waits on the evaluation while delivering operational state events
+	pending   []events.OperationalStateEvent
+	published chan struct{}
+	closed    bool
+	mu        sync.RWMutex
+}
+
+// listen maintains the rules from the snapshot and the changes of the alarms device. The
+// changes compacted in to the snapshot are no longer in the device changes store, so the
+// rules are loaded from the snapshot first and the changes are replayed from its index on
+func (a *alarms) listen(deviceChangesStore devicechangestore.Store, deviceSnapshotStore devicesnapshotstore.Store) error {
+	log.Infof("Starting alarm rules listener on %s", a.id)
+	watchOpt := devicechangestore.WithReplay()
+	snapshot, err := deviceSnapshotStore.Load(a.id)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else if snapshot != nil {
+		a.handleSnapshot(snapshot.Values)
+		watchOpt = devicechangestore.WithIndex(snapshot.ChangeIndex + 1)
+	}
+
+	ch := make(chan stream.Event)
+	ctx, err := deviceChangesStore.Watch(a.id, ch, watchOpt)
+	if err != nil {
+		return err
+	}
+	a.streamCtx = ctx
+	go func() {
+		for event := range ch {
+			if change, ok := event.Object.(*devicechange.DeviceChange); ok {
+				a.handleChanges(change.Change.Values)
+			}
+		}
+	}()
+	return nil
+}
+
+// handleSnapshot applies the values of a snapshot of the alarms device to the rules
+func (a *alarms) handleSnapshot(values []*devicechange.PathValue) {
+	changes := make([]*devicechange.ChangeValue, 0, len(values))
+	for _, value := range values {
+		changes = append(changes, &devicechange.ChangeValue{
+			Path:  value.Path,
+			Value: value.Value,
+		})
+	}
+	a.handleChanges(changes)
+}
+
+// handleChanges applies changes of the alarms device to the rules. The alarms of rules
+// that are removed are cleared
+func (a *alarms) handleChanges(changes []*devicechange.ChangeValue) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, change := range changes {
+		if change.Removed {
+			removeValues(a.config, change.Path)
+		} else {
+			a.config[change.Path] = change.Value
+		}
+	}
+	a.rules = parseRules(a.config)
+	for _, alarm := range a.sortedActive() {
+		if rule, ok := a.rules[alarm.RuleID]; !ok || !rule.matches(string(alarm.DeviceID), alarm.Path) {
+			a.clear(alarm, time.Now())
+		}
+	}
+	log.Infof("Alarm rules updated: %d rules, %d active alarms", len(a.rules), len(a.active))
+}
+
+// evaluate evaluates the rules on an operational state event
+func (a *alarms) evaluate(event events.OperationalStateEvent) {
+	deviceID := event.Subject()
+	if deviceID == string(a.deviceID) {
+		return
+	}
+	key := fmt.Sprintf("%s:%s", deviceID, event.Path())
+	now := time.Unix(0, event.Timestamp())
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if event.ItemAction() == events.EventItemDeleted || event.Value() == nil {
+		delete(a.samples, key)
+		for _, alarm := range a.sortedActive() {
+			if string(alarm.DeviceID) == deviceID && alarm.Path == event.Path() {
+				a.clear(alarm, now)
+			}
+		}
+		return
+	}
+
+	current := newSample(event.Value(), event.Timestamp())
+	previous, hasPrevious := a.samples[key]
+	a.samples[key] = current
+	var rate float64
+	hasRate := hasPrevious && previous.numeric && current.numeric && current.timestamp > previous.timestamp
+	if hasRate {
+		rate = (current.number - previous.number) / (float64(current.timestamp-previous.timestamp) / float64(time.Second))
+	}
+
+	for _, rule := range a.sortedRules() {
+		if !rule.matches(deviceID, event.Path()) {
+			continue
+		}
+		alarm, active := a.active[alarmID(rule.ID, deviceID, event.Path())]
+		if !active && rule.Condition.holds(current, rate, hasRate) {
+			a.raise(rule, topodevice.ID(deviceID), event.Path(), current.text, now)
+		} else if active {
+			cleared := !rule.Condition.holds(current, rate, hasRate)
+			if rule.Clear != nil {
+				cleared = rule.Clear.holds(current, rate, hasRate)
+			}
+			if cleared {
+				a.clear(alarm, now)
+			}
+		}
+	}
+}
+
+// raise raises an alarm. It must be called with the lock held
+func (a *alarms) raise(rule *Rule, deviceID topodevice.ID, path string, value string, raised time.Time) {
+	alarm := &Alarm{
+		ID:          alarmID(rule.ID, string(deviceID), path),
+		RuleID:      rule.ID,
+		DeviceID:    deviceID,
+		Path:        path,
+		Severity:    rule.Severity,
+		Description: rule.Description,
+		Value:       value,
+		Raised:      raised,
+	}
+	log.Infof("Raising %s alarm %s on %s %s: %s", alarm.Severity, rule.ID, deviceID, path, value)
+	a.active[alarm.ID] = alarm
+	a.record(Event{Type: EventRaised, Alarm: *alarm})
+
+	leaves := map[string]*devicechange.TypedValue{
+		"rule":        devicechange.NewTypedValueString(alarm.RuleID),
+		"device":      devicechange.NewTypedValueString(string(alarm.DeviceID)),
+		"path":        devicechange.NewTypedValueString(alarm.Path),
+		"severity":    devicechange.NewTypedValueString(string(alarm.Severity)),
+		"description": devicechange.NewTypedValueString(alarm.Description),
+		"value":       devicechange.NewTypedValueString(alarm.Value),
+		"raised":      devicechange.NewTypedValueUint(uint(alarm.Raised.UnixNano()), 64),
+	}
+	a.opStateCacheLock.Lock()
+	cache := a.opStateCache[a.deviceID]
+	for leaf, leafValue := range leaves {
+		cache[fmt.Sprintf(alarmPath, alarm.ID, leaf)] = leafValue
+	}
+	a.opStateCacheLock.Unlock()
+	for leaf, leafValue := range leaves {
+		a.notify(events.NewOperationalStateEventWithTimestamp(string(a.deviceID),
+			fmt.Sprintf(alarmPath, alarm.ID, leaf), leafValue, events.EventItemUpdated, raised.UnixNano()))
+	}
+}
+
+// clear clears an active alarm. It must be called with the lock held
+func (a *alarms) clear(alarm *Alarm, cleared time.Time) {
+	log.Infof("Clearing %s alarm %s on %s %s", alarm.Severity, alarm.RuleID, alarm.DeviceID, alarm.Path)
+	delete(a.active, alarm.ID)
+	alarm.Cleared = cleared
+	a.record(Event{Type: EventCleared, Alarm: *alarm})
+
+	prefix := fmt.Sprintf(alarmPath, alarm.ID, "")
+	paths := make([]string, 0)
+	a.opStateCacheLock.Lock()
+	cache := a.opStateCache[a.deviceID]
+	for path := range cache {
+		if len(path) > len(prefix) && path[:len(prefix)] == prefix {
+			paths = append(paths, path)
+			delete(cache, path)
+		}
+	}
+	a.opStateCacheLock.Unlock()
+	sort.Strings(paths)
+	for _, path := range paths {
+		a.notify(events.NewOperationalStateEvent(string(a.deviceID), path, nil, events.EventItemDeleted))
+	}
+}
+
+// record adds an event to the history. It must be called with the lock held
+func (a *alarms) record(event Event) {
+	if len(a.history) == maxHistory {
+		a.history = append(a.history[:0], a.history[1:]...)
+	}
+	a.history = append(a.history, event)
+}
+
+// notify queues an operational state event of the alarms device. It must be called with
+// the lock held
+func (a *alarms) notify(event events.OperationalStateEvent) {
+	if a.opStateChan == nil {
+		return
+	}
+	a.pending = append(a.pending, event)
+	select {
+	case a.published <- struct{}{}:
+	default:
+	}
+}
+
+// publish sends the queued events on the operational state channel until closed
+func (a *alarms) publish() {
+	for range a.published {
+		a.mu.Lock()
+		pending := a.pending
+		a.pending = nil
+		closed := a.closed
+		a.mu.Unlock()
+		if closed {
+			return
+		}
+		for _, event := range pending {
+			a.opStateChan <- event
+		}
+	}
+}
+
+// sortedRules returns the rules ordered by ID. It must be called with the lock held
+func (a *alarms) sortedRules() []*Rule {
+	rules := make([]*Rule, 0, len(a.rules))
+	for _, rule := range a.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// sortedActive returns the active alarms, the oldest first. It must be called with the
+// lock held
+func (a *alarms) sortedActive() []*Alarm {
+	active := make([]*Alarm, 0, len(a.active))
+	for _, alarm := range a.active {
+		active = append(active, alarm)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].Raised.Equal(active[j].Raised) {
+			return active[i].ID < active[j].ID
+		}
+		return active[i].Raised.Before(active[j].Raised)
+	})
+	return active
+}
+
+func (a *alarms) DeviceID() device.VersionedID {
+	return a.id
+}
+
+func (a *alarms) Rules() map[string]*Rule {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	rules := make(map[string]*Rule, len(a.rules))
+	for id, rule := range a.rules {
+		rules[id] = rule
+	}
+	return rules
+}
+
+func (a *alarms) Active() []Alarm {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	active := make([]Alarm, 0, len(a.active))
+	for _, alarm := range a.sortedActive() {
+		active = append(active, *alarm)
+	}
+	return active
+}
+
+func (a *alarms) History() []Event {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	history := make([]Event, len(a.history))
+	copy(history, a.history)
+	return history
+}
+
+func (a *alarms) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.published)
+	a.mu.Unlock()
+	if a.dispatcher != nil {
+		a.dispatcher.UnregisterOperationalState(dispatcherListener)
+	}
+	if a.streamCtx != nil {
+		a.streamCtx.Close()
+	}
+	return nil
+}
+
+// alarmID identifies the alarm of a rule on a path of a device. The device and path are
+// hashed as they can't be part of a key of the alarms tree
+func alarmID(ruleID string, deviceID string, path string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(deviceID))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(path))
+	return fmt.Sprintf("%s-%08x", ruleID, h.Sum32())
+}
+
+// newSample converts a value for evaluation
+func newSample(value *devicechange.TypedValue, timestamp int64) sample {
+	s := sample{
+		text:      value.ValueToString(),
+		timestamp: timestamp,
+	}
+	number, err := strconv.ParseFloat(s.text, 64)
+	s.number, s.numeric = number, err == nil
+	return s
+}
@@ -0,0 +1,217 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alarms
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	devicesnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	devicechangestore "github.com/onosproject/onos-config/pkg/store/change/device"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	mockstore "github.com/onosproject/onos-config/pkg/test/mocks/store"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	alarmsDevice   = "alarms:1.0.0"
+	device1        = "device1"
+	cpuPath        = "/components/component[name=cpu0]/cpu/utilization/instant"
+	inOctetsPath   = "/interfaces/interface[name=eth1]/state/counters/in-octets"
+	operStatusPath = "/interfaces/interface[name=eth1]/state/oper-status"
+)
+
+func setupAlarms(t *testing.T) (*alarms, map[topodevice.ID]devicechange.TypedValueMap, chan events.OperationalStateEvent) {
+	cache := make(map[topodevice.ID]devicechange.TypedValueMap)
+	a := newAlarms(cache, &sync.RWMutex{}, alarmsDevice)
+	opStateChan := make(chan events.OperationalStateEvent, 100)
+	a.opStateChan = opStateChan
+	go a.publish()
+	t.Cleanup(func() { _ = a.Close() })
+
+	a.handleChanges([]*devicechange.ChangeValue{
+		{Path: fmt.Sprintf(rulePathPath, "cpu"), Value: devicechange.NewTypedValueString("/components/component[name=*]/cpu/utilization/instant")},
+		{Path: fmt.Sprintf(ruleConditionPath, "cpu"), Value: devicechange.NewTypedValueString("> 90")},
+		{Path: fmt.Sprintf(ruleClearPath, "cpu"), Value: devicechange.NewTypedValueString("< 80")},
+		{Path: fmt.Sprintf(ruleSeverityPath, "cpu"), Value: devicechange.NewTypedValueString("major")},
+		{Path: fmt.Sprintf(rulePathPath, "octets"), Value: devicechange.NewTypedValueString("/interfaces/interface[name=*]/state/counters/in-octets")},
+		{Path: fmt.Sprintf(ruleConditionPath, "octets"), Value: devicechange.NewTypedValueString("rate > 1000")},
+		{Path: fmt.Sprintf(rulePathPath, "oper"), Value: devicechange.NewTypedValueString("/interfaces/interface[name=*]/state/oper-status")},
+		{Path: fmt.Sprintf(ruleConditionPath, "oper"), Value: devicechange.NewTypedValueString("== DOWN")},
+		{Path: fmt.Sprintf(ruleDescriptionPath, "oper"), Value: devicechange.NewTypedValueString("Interface down")},
+	})
+	return a, cache, opStateChan
+}
+
+func opStateEvent(path string, value *devicechange.TypedValue, timestamp time.Time) events.OperationalStateEvent {
+	return events.NewOperationalStateEventWithTimestamp(device1, path, value, events.EventItemUpdated, timestamp.UnixNano())
+}
+
+func Test_listenLoadsSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	snapshots := mockstore.NewMockDeviceSnapshotStore(ctrl)
+	changes := mockstore.NewMockDeviceChangesStore(ctrl)
+
+	// The rule is set in the snapshot, and the changes compacted in to it are not replayed
+	snapshots.EXPECT().Load(gomock.Any()).Return(&devicesnapshot.Snapshot{
+		ChangeIndex: 5,
+		Values: []*devicechange.PathValue{
+			{Path: fmt.Sprintf(rulePathPath, "cpu"), Value: devicechange.NewTypedValueString(cpuPath)},
+			{Path: fmt.Sprintf(ruleConditionPath, "cpu"), Value: devicechange.NewTypedValueString("> 90")},
+		},
+	}, nil)
+	changes.EXPECT().Watch(gomock.Any(), gomock.Any(), devicechangestore.WithIndex(6)).DoAndReturn(
+		func(_ interface{}, ch chan<- stream.Event, _ ...devicechangestore.WatchOption) (stream.Context, error) {
+			go func() {
+				ch <- stream.Event{
+					Type: stream.Created,
+					Object: &devicechange.DeviceChange{
+						Index: 6,
+						Change: &devicechange.Change{
+							Values: []*devicechange.ChangeValue{
+								{Path: fmt.Sprintf(ruleSeverityPath, "cpu"), Value: devicechange.NewTypedValueString("minor")},
+							},
+						},
+					},
+				}
+				close(ch)
+			}()
+			return stream.NewCancelContext(func() {}), nil
+		})
+
+	a := newAlarms(make(map[topodevice.ID]devicechange.TypedValueMap), &sync.RWMutex{}, alarmsDevice)
+	t.Cleanup(func() { _ = a.Close() })
+	assert.NoError(t, a.listen(changes, snapshots))
+	assert.Len(t, a.Rules(), 1)
+	assert.Eventually(t, func() bool {
+		rule, ok := a.Rules()["cpu"]
+		return ok && rule.Severity == SeverityMinor
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_listenWithoutSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	snapshots := mockstore.NewMockDeviceSnapshotStore(ctrl)
+	changes := mockstore.NewMockDeviceChangesStore(ctrl)
+
+	snapshots.EXPECT().Load(gomock.Any()).Return(nil, errors.NewNotFound("no snapshot"))
+	changes.EXPECT().Watch(gomock.Any(), gomock.Any(), devicechangestore.WithReplay()).
+		Return(stream.NewCancelContext(func() {}), nil)
+
+	a := newAlarms(make(map[topodevice.ID]devicechange.TypedValueMap), &sync.RWMutex{}, alarmsDevice)
+	t.Cleanup(func() { _ = a.Close() })
+	assert.NoError(t, a.listen(changes, snapshots))
+	assert.Empty(t, a.Rules())
+}
+
+func Test_thresholdAlarm(t *testing.T) {
+	a, cache, opStateChan := setupAlarms(t)
+	assert.Len(t, a.Rules(), 3)
+	now := time.Now()
+
+	a.evaluate(opStateEvent(cpuPath, devicechange.NewTypedValueUint(50, 8), now))
+	assert.Empty(t, a.Active())
+
+	a.evaluate(opStateEvent(cpuPath, devicechange.NewTypedValueUint(95, 8), now.Add(time.Second)))
+	active := a.Active()
+	assert.Len(t, active, 1)
+	alarm := active[0]
+	assert.Equal(t, "cpu", alarm.RuleID)
+	assert.Equal(t, topodevice.ID(device1), alarm.DeviceID)
+	assert.Equal(t, SeverityMajor, alarm.Severity)
+	assert.Equal(t, "95", alarm.Value)
+
+	// The alarm is published as operational state of the alarms device
+	a.opStateCacheLock.RLock()
+	assert.Equal(t, "95", cache["alarms"][fmt.Sprintf(alarmPath, alarm.ID, "value")].ValueToString())
+	assert.Equal(t, device1, cache["alarms"][fmt.Sprintf(alarmPath, alarm.ID, "device")].ValueToString())
+	a.opStateCacheLock.RUnlock()
+	for i := 0; i < 7; i++ {
+		event := <-opStateChan
+		assert.Equal(t, "alarms", event.Subject())
+		assert.Equal(t, events.EventItemUpdated, event.ItemAction())
+	}
+
+	// Between the raise and clear thresholds the alarm stays active
+	a.evaluate(opStateEvent(cpuPath, devicechange.NewTypedValueUint(85, 8), now.Add(2*time.Second)))
+	assert.Len(t, a.Active(), 1)
+
+	a.evaluate(opStateEvent(cpuPath, devicechange.NewTypedValueUint(70, 8), now.Add(3*time.Second)))
+	assert.Empty(t, a.Active())
+	a.opStateCacheLock.RLock()
+	assert.Empty(t, cache["alarms"])
+	a.opStateCacheLock.RUnlock()
+	for i := 0; i < 7; i++ {
+		event := <-opStateChan
+		assert.Equal(t, events.EventItemDeleted, event.ItemAction())
+	}
+
+	history := a.History()
+	assert.Len(t, history, 2)
+	assert.Equal(t, EventRaised, history[0].Type)
+	assert.Equal(t, EventCleared, history[1].Type)
+	assert.False(t, history[1].Alarm.Cleared.IsZero())
+}
+
+func Test_rateAlarm(t *testing.T) {
+	a, _, _ := setupAlarms(t)
+	now := time.Now()
+
+	a.evaluate(opStateEvent(inOctetsPath, devicechange.NewTypedValueUint(100000, 64), now))
+	assert.Empty(t, a.Active())
+	a.evaluate(opStateEvent(inOctetsPath, devicechange.NewTypedValueUint(100500, 64), now.Add(time.Second)))
+	assert.Empty(t, a.Active())
+	a.evaluate(opStateEvent(inOctetsPath, devicechange.NewTypedValueUint(104500, 64), now.Add(3*time.Second)))
+	assert.Len(t, a.Active(), 1)
+	// Without a clear condition the alarm clears when the condition no longer holds
+	a.evaluate(opStateEvent(inOctetsPath, devicechange.NewTypedValueUint(105000, 64), now.Add(4*time.Second)))
+	assert.Empty(t, a.Active())
+}
+
+func Test_clearAlarm(t *testing.T) {
+	a, _, _ := setupAlarms(t)
+	now := time.Now()
+
+	a.evaluate(opStateEvent(operStatusPath, devicechange.NewTypedValueString("DOWN"), now))
+	active := a.Active()
+	assert.Len(t, active, 1)
+	assert.Equal(t, "Interface down", active[0].Description)
+
+	// Events of the alarms device itself are not evaluated
+	a.evaluate(events.NewOperationalStateEvent("alarms", operStatusPath,
+		devicechange.NewTypedValueString("DOWN"), events.EventItemUpdated))
+	assert.Len(t, a.Active(), 1)
+
+	// Alarms are cleared when their path is deleted
+	a.evaluate(events.NewOperationalStateEvent(device1, operStatusPath, nil, events.EventItemDeleted))
+	assert.Empty(t, a.Active())
+
+	// and when their rule is removed
+	a.evaluate(opStateEvent(operStatusPath, devicechange.NewTypedValueString("DOWN"), now))
+	assert.Len(t, a.Active(), 1)
+	a.handleChanges([]*devicechange.ChangeValue{
+		{Path: "/alarms/rule[id=oper]", Removed: true},
+	})
+	assert.Len(t, a.Rules(), 2)
+	assert.Empty(t, a.Active())
+	assert.Len(t, a.History(), 4)
+}
@@ -0,0 +1,313 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alarms
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+const (
+	// ruleLeafExact matches the leaves of a rule, capturing its ID and the leaf name
+	ruleLeafExact = `^/alarms/rule\[id=([^\]]+)]/([a-z-]+)$`
+	// validPathExact matches the path patterns rules accept, which compile safely
+	// with utils.MatchWildcardRegexp
+	validPathExact = `^(/[a-zA-Z0-9_:\-\.*]+(\[[a-zA-Z0-9_:\-\.]+=[a-zA-Z0-9_:,\-\.*/ ]+])*)+$`
+)
+
+var (
+	ruleLeafSearch = regexp.MustCompile(ruleLeafExact)
+	validPath      = regexp.MustCompile(validPathExact)
+)
+
+// Severity is the severity of an alarm
+type Severity string
+
+const (
+	// SeverityCritical is for alarms needing immediate action
+	SeverityCritical Severity = "critical"
+	// SeverityMajor is for alarms needing urgent action
+	SeverityMajor Severity = "major"
+	// SeverityMinor is for alarms needing action
+	SeverityMinor Severity = "minor"
+	// SeverityWarning is for conditions that may lead to an alarm
+	SeverityWarning Severity = "warning"
+)
+
+// Rule raises an alarm for each operational state path of a device matching its path
+// pattern while its condition holds
+type Rule struct {
+	// ID is the identifier of the rule
+	ID string
+	// Path is the path pattern the rule applies to, with * wildcards
+	Path string
+	// Target is the device pattern the rule applies to, all devices if empty
+	Target string
+	// Condition raises the alarm when it holds
+	Condition *Condition
+	// Clear clears the alarm when it holds. If nil, the alarm is cleared when the
+	// condition no longer holds
+	Clear *Condition
+	// Severity is the severity of the alarms raised by the rule
+	Severity Severity
+	// Description describes the alarms raised by the rule
+	Description string
+
+	pathRegexp   *regexp.Regexp
+	targetRegexp *regexp.Regexp
+}
+
+// matches indicates whether the rule applies to a path of a device
+func (r *Rule) matches(deviceID string, path string) bool {
+	if r.targetRegexp != nil && !r.targetRegexp.MatchString(deviceID) {
+		return false
+	}
+	return r.pathRegexp.MatchString(path)
+}
+
+// Condition compares a value, or its rate of change per second, with a constant
+type Condition struct {
+	// Rate compares the rate of change of the value instead of the value
+	Rate bool
+	// Operator is one of ==, !=, >, >=, < and <=
+	Operator string
+	// Value is the constant the value is compared with
+	Value string
+
+	number  float64
+	numeric bool
+}
+
+var operators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// ParseCondition parses a condition in the form "[rate] <operator> <value>", e.g. "> 90",
+// "== DOWN" or "rate > 1000"
+func ParseCondition(condition string) (*Condition, error) {
+	c := &Condition{}
+	expr := strings.TrimSpace(condition)
+	if strings.HasPrefix(expr, "rate ") {
+		c.Rate = true
+		expr = strings.TrimSpace(strings.TrimPrefix(expr, "rate "))
+	}
+	for _, operator := range operators {
+		if strings.HasPrefix(expr, operator) {
+			c.Operator = operator
+			c.Value = strings.TrimSpace(strings.TrimPrefix(expr, operator))
+			break
+		}
+	}
+	if c.Operator == "" || c.Value == "" {
+		return nil, errors.NewInvalid("invalid alarm condition %q", condition)
+	}
+	number, err := strconv.ParseFloat(c.Value, 64)
+	c.number, c.numeric = number, err == nil
+	if !c.numeric && c.Operator != "==" && c.Operator != "!=" {
+		return nil, errors.NewInvalid("alarm condition %q compares a non numeric value", condition)
+	}
+	if c.Rate && !c.numeric {
+		return nil, errors.NewInvalid("alarm condition %q compares a rate with a non numeric value", condition)
+	}
+	return c, nil
+}
+
+// String returns the condition as it is parsed
+func (c *Condition) String() string {
+	if c.Rate {
+		return fmt.Sprintf("rate %s %s", c.Operator, c.Value)
+	}
+	return fmt.Sprintf("%s %s", c.Operator, c.Value)
+}
+
+// holds evaluates the condition on a sample. The rate is only used by rate conditions,
+// which don't hold until a rate is known
+func (c *Condition) holds(s sample, rate float64, hasRate bool) bool {
+	if c.Rate {
+		return hasRate && compareNumbers(rate, c.Operator, c.number)
+	}
+	if c.numeric && s.numeric {
+		return compareNumbers(s.number, c.Operator, c.number)
+	}
+	switch c.Operator {
+	case "==":
+		return s.text == c.Value
+	case "!=":
+		return s.text != c.Value
+	}
+	return false
+}
+
+func compareNumbers(a float64, operator string, b float64) bool {
+	switch operator {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+// ruleLeaves are the leaves a rule is configured with
+var ruleLeaves = map[string]bool{
+	"id":          true,
+	"path":        true,
+	"target":      true,
+	"condition":   true,
+	"clear":       true,
+	"severity":    true,
+	"description": true,
+}
+
+// ReadWritePaths are the paths of the alarms device that can be set, standing in for those of
+// the model plugin the alarms device does not have. Every leaf of a rule is a string
+var ReadWritePaths = func() modelregistry.ReadWritePathMap {
+	paths := make(modelregistry.ReadWritePathMap)
+	for leaf := range ruleLeaves {
+		paths[fmt.Sprintf("/alarms/rule[id=*]/%s", leaf)] = modelregistry.ReadWritePathElem{
+			ReadOnlyAttrib: modelregistry.ReadOnlyAttrib{
+				ValueType: devicechange.ValueType_STRING,
+				IsAKey:    leaf == "id",
+				AttrName:  leaf,
+			},
+		}
+	}
+	return paths
+}()
+
+// parseRules builds the rules configured in the values of the alarms device. Rules that
+// are incomplete or invalid are skipped with a warning
+func parseRules(values devicechange.TypedValueMap) map[string]*Rule {
+	leaves := ruleLeafValues(values)
+	ids := make([]string, 0, len(leaves))
+	for id := range leaves {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	rules := make(map[string]*Rule)
+	for _, id := range ids {
+		rule, err := parseRule(id, leaves[id])
+		if err != nil {
+			log.Warnf("Ignoring alarm rule %s: %v", id, err)
+			continue
+		}
+		rules[id] = rule
+	}
+	return rules
+}
+
+// ValidateRules validates the configuration of the alarms device in place of a model plugin.
+// Every value must be a leaf of a rule, and every rule must be complete and valid
+func ValidateRules(values devicechange.TypedValueMap) error {
+	for path := range values {
+		match := ruleLeafSearch.FindStringSubmatch(path)
+		if match == nil || !ruleLeaves[match[2]] {
+			return errors.NewInvalid("%s is not a leaf of an alarm rule", path)
+		}
+	}
+	for id, leaves := range ruleLeafValues(values) {
+		if _, err := parseRule(id, leaves); err != nil {
+			return errors.NewInvalid("alarm rule %s is not valid: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// ruleLeafValues groups the values of the leaves of rules by rule ID
+func ruleLeafValues(values devicechange.TypedValueMap) map[string]map[string]string {
+	leaves := make(map[string]map[string]string)
+	for path, value := range values {
+		match := ruleLeafSearch.FindStringSubmatch(path)
+		if match == nil || value == nil {
+			continue
+		}
+		if _, ok := leaves[match[1]]; !ok {
+			leaves[match[1]] = make(map[string]string)
+		}
+		leaves[match[1]][match[2]] = value.ValueToString()
+	}
+	return leaves
+}
+
+// parseRule builds a rule from its leaves
+func parseRule(id string, leaves map[string]string) (*Rule, error) {
+	rule := &Rule{
+		ID:          id,
+		Path:        leaves["path"],
+		Target:      leaves["target"],
+		Severity:    Severity(leaves["severity"]),
+		Description: leaves["description"],
+	}
+	if rule.Path == "" {
+		return nil, errors.NewInvalid("no path")
+	}
+	if leaves["condition"] == "" {
+		return nil, errors.NewInvalid("no condition")
+	}
+	condition, err := ParseCondition(leaves["condition"])
+	if err != nil {
+		return nil, err
+	}
+	rule.Condition = condition
+	if leaves["clear"] != "" {
+		clear, err := ParseCondition(leaves["clear"])
+		if err != nil {
+			return nil, err
+		}
+		rule.Clear = clear
+	}
+	switch rule.Severity {
+	case "":
+		rule.Severity = SeverityMinor
+	case SeverityCritical, SeverityMajor, SeverityMinor, SeverityWarning:
+	default:
+		return nil, errors.NewInvalid("unknown severity %s", rule.Severity)
+	}
+	if !validPath.MatchString(rule.Path) {
+		return nil, errors.NewInvalid("invalid path %s", rule.Path)
+	}
+	rule.pathRegexp = utils.MatchWildcardRegexp(rule.Path, true)
+	if rule.Target != "" {
+		rule.targetRegexp = regexp.MustCompile(fmt.Sprintf("^%s$",
+			strings.ReplaceAll(regexp.QuoteMeta(rule.Target), `\*`, ".*")))
+	}
+	return rule, nil
+}
+
+// removeValues removes a value of the alarms device and the values under it
+func removeValues(values devicechange.TypedValueMap, path string) {
+	delete(values, path)
+	prefix := path + "/"
+	for key := range values {
+		if strings.HasPrefix(key, prefix) {
+			delete(values, key)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alarms
+
+import (
+	"fmt"
+	"testing"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	rulePathPath        = "/alarms/rule[id=%s]/path"
+	ruleTargetPath      = "/alarms/rule[id=%s]/target"
+	ruleConditionPath   = "/alarms/rule[id=%s]/condition"
+	ruleClearPath       = "/alarms/rule[id=%s]/clear"
+	ruleSeverityPath    = "/alarms/rule[id=%s]/severity"
+	ruleDescriptionPath = "/alarms/rule[id=%s]/description"
+)
+
+func Test_ParseCondition(t *testing.T) {
+	condition, err := ParseCondition("> 90")
+	assert.NoError(t, err)
+	assert.False(t, condition.Rate)
+	assert.Equal(t, ">", condition.Operator)
+	assert.Equal(t, "90", condition.Value)
+	assert.True(t, condition.holds(sample{text: "91", number: 91, numeric: true}, 0, false))
+	assert.False(t, condition.holds(sample{text: "90", number: 90, numeric: true}, 0, false))
+	assert.False(t, condition.holds(sample{text: "UP"}, 0, false))
+
+	condition, err = ParseCondition("== DOWN")
+	assert.NoError(t, err)
+	assert.Equal(t, "== DOWN", condition.String())
+	assert.True(t, condition.holds(sample{text: "DOWN"}, 0, false))
+	assert.False(t, condition.holds(sample{text: "UP"}, 0, false))
+
+	condition, err = ParseCondition("rate >= 1000")
+	assert.NoError(t, err)
+	assert.Equal(t, "rate >= 1000", condition.String())
+	assert.False(t, condition.holds(sample{text: "5000", number: 5000, numeric: true}, 0, false))
+	assert.True(t, condition.holds(sample{text: "5000", number: 5000, numeric: true}, 1000, true))
+
+	for _, invalid := range []string{"", "90", ">", "> UP", "rate == UP", "~ 1"} {
+		_, err = ParseCondition(invalid)
+		assert.True(t, errors.IsInvalid(err), invalid)
+	}
+}
+
+func Test_parseRules(t *testing.T) {
+	values := devicechange.TypedValueMap{
+		fmt.Sprintf(rulePathPath, "cpu"):          devicechange.NewTypedValueString("/components/component[name=*]/cpu/utilization/instant"),
+		fmt.Sprintf(ruleConditionPath, "cpu"):     devicechange.NewTypedValueString("> 90"),
+		fmt.Sprintf(ruleClearPath, "cpu"):         devicechange.NewTypedValueString("< 80"),
+		fmt.Sprintf(ruleSeverityPath, "cpu"):      devicechange.NewTypedValueString("major"),
+		fmt.Sprintf(ruleTargetPath, "cpu"):        devicechange.NewTypedValueString("leaf*"),
+		fmt.Sprintf(rulePathPath, "oper"):         devicechange.NewTypedValueString("/interfaces/interface[name=*]/state/oper-status"),
+		fmt.Sprintf(ruleConditionPath, "oper"):    devicechange.NewTypedValueString("== DOWN"),
+		fmt.Sprintf(rulePathPath, "nocond"):       devicechange.NewTypedValueString("/system/state/hostname"),
+		fmt.Sprintf(rulePathPath, "badsev"):       devicechange.NewTypedValueString("/system/state/hostname"),
+		fmt.Sprintf(ruleConditionPath, "badsev"):  devicechange.NewTypedValueString("== x"),
+		fmt.Sprintf(ruleSeverityPath, "badsev"):   devicechange.NewTypedValueString("fatal"),
+		fmt.Sprintf(rulePathPath, "badpath"):      devicechange.NewTypedValueString("/system/(state)"),
+		fmt.Sprintf(ruleConditionPath, "badpath"): devicechange.NewTypedValueString("== x"),
+	}
+	rules := parseRules(values)
+	assert.Len(t, rules, 2)
+
+	cpu := rules["cpu"]
+	assert.Equal(t, SeverityMajor, cpu.Severity)
+	assert.Equal(t, "< 80", cpu.Clear.String())
+	assert.True(t, cpu.matches("leaf1", "/components/component[name=cpu0]/cpu/utilization/instant"))
+	assert.False(t, cpu.matches("spine1", "/components/component[name=cpu0]/cpu/utilization/instant"))
+	assert.False(t, cpu.matches("leaf1", "/components/component[name=cpu0]/cpu/utilization/avg"))
+
+	oper := rules["oper"]
+	assert.Equal(t, SeverityMinor, oper.Severity)
+	assert.Nil(t, oper.Clear)
+	assert.True(t, oper.matches("spine1", "/interfaces/interface[name=eth2]/state/oper-status"))
+
+	removeValues(values, "/alarms/rule[id=cpu]")
+	rules = parseRules(values)
+	assert.Len(t, rules, 1)
+	assert.Contains(t, rules, "oper")
+	assert.Empty(t, values[fmt.Sprintf(ruleDescriptionPath, "cpu")])
+}
+
+func TestValidateRules(t *testing.T) {
+	values := devicechange.TypedValueMap{
+		fmt.Sprintf(rulePathPath, "cpu"):      devicechange.NewTypedValueString("/system/cpus/cpu[index=*]/state/total/instant"),
+		fmt.Sprintf(ruleConditionPath, "cpu"): devicechange.NewTypedValueString("> 90"),
+	}
+	assert.NoError(t, ValidateRules(values))
+
+	values[fmt.Sprintf(ruleSeverityPath, "cpu")] = devicechange.NewTypedValueString("fatal")
+	assert.True(t, errors.IsInvalid(ValidateRules(values)))
+	delete(values, fmt.Sprintf(ruleSeverityPath, "cpu"))
+
+	values["/alarms/rule[id=cpu]/unknown"] = devicechange.NewTypedValueString("x")
+	assert.True(t, errors.IsInvalid(ValidateRules(values)))
+	delete(values, "/alarms/rule[id=cpu]/unknown")
+
+	values["/system/hostname"] = devicechange.NewTypedValueString("x")
+	assert.True(t, errors.IsInvalid(ValidateRules(values)))
+	delete(values, "/system/hostname")
+
+	// Every leaf of a rule can be set
+	for leaf := range ruleLeaves {
+		_, ok := ReadWritePaths[fmt.Sprintf("/alarms/rule[id=*]/%s", leaf)]
+		assert.True(t, ok, leaf)
+	}
+}
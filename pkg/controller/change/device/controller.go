@@ -19,6 +19,7 @@ import (
 	"github.com/onosproject/onos-api/go/onos/topo"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"strings"
+	"sync"
 
 	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
@@ -37,6 +38,9 @@ import (
 
 var log = logging.GetLogger("controller", "change", "device")
 
+// setRetries is the number of times a change whose Set fails with a retryable error is requeued
+const setRetries = 3
+
 // NewController returns a new network controller
 func NewController(mastership mastershipstore.Store, devices devicestore.Store,
 	cache cache.Cache, changes changestore.Store) *controller.Controller {
@@ -71,6 +75,9 @@ func (r *Resolver) Resolve(id controller.ID) (topodevice.ID, error) {
 type Reconciler struct {
 	devices devicestore.Store
 	changes changestore.Store
+	// attempts counts the failed attempts to send each requeued change
+	attempts map[devicechange.ID]int
+	mu       sync.Mutex
 }
 
 // Reconcile reconciles the state of a device change
@@ -100,6 +107,10 @@ func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
 		return controller.Result{}, err
 	} else if getProtocolState(device) != topo.ChannelState_CONNECTED {
 		return controller.Result{}, errors.NewNotFound("device '%s' is not connected", change.Change.DeviceID)
+	} else if mismatch, ok := device.Attributes[topodevice.ModelMismatchAttribute]; ok && getServiceState(device) == topo.ServiceState_UNAVAILABLE {
+		// The device does not advertise the models of its plugin and onos-config is strict about it
+		return r.failChange(change, errors.NewForbidden("device '%s' does not advertise the models of its plugin: %s",
+			change.Change.DeviceID, mismatch))
 	}
 
 	// Handle the change for each phase
@@ -116,11 +127,16 @@ func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
 func (r *Reconciler) reconcileChange(change *devicechange.DeviceChange) (controller.Result, error) {
 	// Attempt to apply the change to the device and update the change with the result
 	if err := r.doChange(change); err != nil {
+		retry, err := r.retryChange(change, err)
+		if retry {
+			return controller.Result{}, err
+		}
 		change.Status.State = changetypes.State_FAILED
 		change.Status.Reason = changetypes.Reason_ERROR
 		change.Status.Message = err.Error()
 		log.Infof("Failing DeviceChange %v", change)
 	} else {
+		r.resetChange(change)
 		change.Status.State = changetypes.State_COMPLETE
 		log.Infof("Completing DeviceChange %s", change.ID)
 		log.Debug(change)
@@ -134,6 +150,19 @@ func (r *Reconciler) reconcileChange(change *devicechange.DeviceChange) (control
 	return controller.Result{}, nil
 }
 
+// failChange fails a change in the RUNNING state without applying it to the device
+func (r *Reconciler) failChange(change *devicechange.DeviceChange, err error) (controller.Result, error) {
+	change.Status.State = changetypes.State_FAILED
+	change.Status.Reason = changetypes.Reason_ERROR
+	change.Status.Message = err.Error()
+	log.Infof("Failing DeviceChange %v", change)
+	if err := r.changes.Update(change); err != nil {
+		log.Warnf("error updating device change %s %v", err.Error(), change)
+		return controller.Result{}, err
+	}
+	return controller.Result{}, nil
+}
+
 // doChange pushes the given change to the device
 func (r *Reconciler) doChange(change *devicechange.DeviceChange) error {
 	log.Infof("Applying change %v ", change.Change)
@@ -144,11 +173,16 @@ func (r *Reconciler) doChange(change *devicechange.DeviceChange) error {
 func (r *Reconciler) reconcileRollback(change *devicechange.DeviceChange) (controller.Result, error) {
 	// Attempt to roll back the change to the device and update the change with the result
 	if err := r.doRollback(change); err != nil {
+		retry, err := r.retryChange(change, err)
+		if retry {
+			return controller.Result{}, err
+		}
 		change.Status.State = changetypes.State_FAILED
 		change.Status.Reason = changetypes.Reason_ERROR
 		change.Status.Message = err.Error()
 		log.Infof("Failing DeviceChange %v", change)
 	} else {
+		r.resetChange(change)
 		change.Status.State = changetypes.State_COMPLETE
 		log.Infof("Completing DeviceChange %v", change.ID)
 		log.Debug(change)
@@ -195,6 +229,36 @@ func (r *Reconciler) translateAndSendChange(change *devicechange.Change) error {
 	return nil
 }
 
+// retryChange counts a failed attempt to send a change to its device. It returns whether the
+// change is to be requeued and sent again, and otherwise the error to fail the change with.
+// Requeued changes are reconciled again after the backoff of the controller
+func (r *Reconciler) retryChange(change *devicechange.DeviceChange, err error) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !southbound.IsRetryable(err) {
+		delete(r.attempts, change.ID)
+		return false, err
+	}
+	if r.attempts == nil {
+		r.attempts = make(map[devicechange.ID]int)
+	}
+	r.attempts[change.ID]++
+	attempts := r.attempts[change.ID]
+	if attempts <= setRetries {
+		log.Infof("Set of DeviceChange %s failed. Requeuing Attempt %d: %v", change.ID, attempts, err)
+		return true, err
+	}
+	delete(r.attempts, change.ID)
+	return false, fmt.Errorf("device %s unavailable after %d attempts: %w", change.Change.DeviceID, attempts, err)
+}
+
+// resetChange forgets the failed attempts to send a change
+func (r *Reconciler) resetChange(change *devicechange.DeviceChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, change.ID)
+}
+
 func getProtocolState(device *topodevice.Device) topo.ChannelState {
 	// Find the gNMI protocol state for the device
 	var protocol *topo.ProtocolState
@@ -210,6 +274,15 @@ func getProtocolState(device *topodevice.Device) topo.ChannelState {
 	return protocol.ChannelState
 }
 
+func getServiceState(device *topodevice.Device) topo.ServiceState {
+	for _, p := range device.Protocols {
+		if p.Protocol == topo.Protocol_GNMI {
+			return p.ServiceState
+		}
+	}
+	return topo.ServiceState_UNKNOWN_SERVICE_STATE
+}
+
 // computeRollback returns a change containing the previous value for each path of the rollbackChange
 func (r *Reconciler) computeRollback(deviceChange *devicechange.DeviceChange) (*devicechange.Change, error) {
 	//TODO We might want to consider doing reverse iteration to get the previous value for a path instead of
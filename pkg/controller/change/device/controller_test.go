@@ -42,6 +42,8 @@ import (
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"io"
 	"sync"
 	"testing"
@@ -54,6 +56,7 @@ const (
 	device2     = device.ID("device-2")
 	device2Addr = "device-2:5150"
 	dcDevice    = device.ID("disconnected")
+	mmDevice    = device.ID("model-mismatch")
 	v1          = "1.0.0"
 	stratumType = "Stratum"
 )
@@ -474,6 +477,33 @@ func TestReconcilerRemoveThenRollback(t *testing.T) {
 
 }
 
+func TestReconcilerModelMismatch(t *testing.T) {
+	devices, deviceChanges := newStores(t)
+	defer deviceChanges.Close()
+
+	reconciler := &Reconciler{
+		devices: devices,
+		changes: deviceChanges,
+	}
+
+	// A change to a device that does not advertise the models of its plugin in strict
+	// mode fails without being pushed
+	deviceChange := newChange(1, mmDevice, v1)
+	err := deviceChanges.Create(deviceChange)
+	assert.NoError(t, err)
+	deviceChange.Status.Incarnation++
+	err = deviceChanges.Update(deviceChange)
+	assert.NoError(t, err)
+
+	_, err = reconciler.Reconcile(controller.NewID(string(deviceChange.ID)))
+	assert.NoError(t, err)
+
+	deviceChange, err = deviceChanges.Get(deviceChange.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, changetypes.State_FAILED, deviceChange.Status.State)
+	assert.Contains(t, deviceChange.Status.Message, "openconfig-interfaces 2.4.3 not advertised")
+}
+
 func newStores(t *testing.T) (devicestore.Store, devicechanges.Store) {
 	ctrl := gomock.NewController(t)
 
@@ -517,12 +547,30 @@ func newStores(t *testing.T) (devicestore.Store, devicechanges.Store) {
 				},
 			},
 		},
+		topodevice.ID(mmDevice): {
+			ID:      topodevice.ID(mmDevice),
+			Version: v1,
+			Type:    stratumType,
+			Address: "model-mismatch:5150",
+			Protocols: []*topo.ProtocolState{
+				{
+					Protocol:          topo.Protocol_GNMI,
+					ConnectivityState: topo.ConnectivityState_REACHABLE,
+					ChannelState:      topo.ChannelState_CONNECTED,
+					ServiceState:      topo.ServiceState_UNAVAILABLE,
+				},
+			},
+			Attributes: map[string]string{
+				topodevice.ModelMismatchAttribute: "openconfig-interfaces 2.4.3 not advertised",
+			},
+		},
 	}
 
 	stream := mocks.NewMockTopo_WatchClient(ctrl)
 	stream.EXPECT().Recv().Return(&topo.WatchResponse{Event: topo.Event{Object: *topodevice.ToObject(devices[topodevice.ID(device1)])}}, nil)
 	stream.EXPECT().Recv().Return(&topo.WatchResponse{Event: topo.Event{Object: *topodevice.ToObject(devices[topodevice.ID(device2)])}}, nil)
 	stream.EXPECT().Recv().Return(&topo.WatchResponse{Event: topo.Event{Object: *topodevice.ToObject(devices[topodevice.ID(dcDevice)])}}, nil)
+	stream.EXPECT().Recv().Return(&topo.WatchResponse{Event: topo.Event{Object: *topodevice.ToObject(devices[topodevice.ID(mmDevice)])}}, nil)
 	stream.EXPECT().Recv().Return(nil, io.EOF)
 
 	client := mocks.NewMockTopoClient(ctrl)
@@ -691,3 +739,77 @@ func newChangeInterfaceRemove(index devicechange.Index, device device.ID, versio
 		},
 	}
 }
+
+func TestReconcilerChangeRetries(t *testing.T) {
+	devices, deviceChanges := newStores(t)
+	defer deviceChanges.Close()
+
+	reconciler := &Reconciler{
+		devices: devices,
+		changes: deviceChanges,
+	}
+	ctrl := gomock.NewController(t)
+	ctx := context.TODO()
+	newPendingChange := func(index devicechange.Index, networkChangeID types.ID, device device.ID) *devicechange.DeviceChange {
+		deviceChange := newChange(index, device, v1)
+		deviceChange.NetworkChange.ID = networkChangeID
+		assert.NoError(t, deviceChanges.Create(deviceChange))
+		deviceChange.Status.Incarnation++
+		assert.NoError(t, deviceChanges.Update(deviceChange))
+		return deviceChange
+	}
+
+	// A change to a device that is unavailable at first is requeued until the device recovers
+	target := southboundmock.NewMockTargetIf(ctrl)
+	target.EXPECT().Context().Return(&ctx).AnyTimes()
+	gomock.InOrder(
+		target.EXPECT().Set(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unavailable, "unavailable")).Times(2),
+		target.EXPECT().Set(gomock.Any(), gomock.Any()).Return(&gnmi.SetResponse{}, nil).Times(1),
+	)
+	southbound.Targets[topodevice.ID(device1)] = target
+	deviceChange := newPendingChange(1, "recovers", device1)
+	for i := 0; i < 2; i++ {
+		_, err := reconciler.Reconcile(controller.NewID(string(deviceChange.ID)))
+		assert.Error(t, err)
+		deviceChange, err = deviceChanges.Get(deviceChange.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, changetypes.State_PENDING, deviceChange.Status.State)
+	}
+	_, err := reconciler.Reconcile(controller.NewID(string(deviceChange.ID)))
+	assert.NoError(t, err)
+	deviceChange, err = deviceChanges.Get(deviceChange.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, changetypes.State_COMPLETE, deviceChange.Status.State)
+
+	// A change to a device that stays unavailable fails after a bounded number of attempts
+	target = southboundmock.NewMockTargetIf(ctrl)
+	target.EXPECT().Context().Return(&ctx).AnyTimes()
+	target.EXPECT().Set(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unavailable, "unavailable")).Times(setRetries + 1)
+	southbound.Targets[topodevice.ID(device2)] = target
+	deviceChange = newPendingChange(1, "unavailable", device2)
+	for i := 0; i < setRetries; i++ {
+		_, err = reconciler.Reconcile(controller.NewID(string(deviceChange.ID)))
+		assert.Error(t, err)
+	}
+	_, err = reconciler.Reconcile(controller.NewID(string(deviceChange.ID)))
+	assert.NoError(t, err)
+	deviceChange, err = deviceChanges.Get(deviceChange.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, changetypes.State_FAILED, deviceChange.Status.State)
+	assert.Contains(t, deviceChange.Status.Message, "unavailable after 4 attempts")
+
+	// A Set that timed out may have been applied, so the change is not sent again
+	target = southboundmock.NewMockTargetIf(ctrl)
+	target.EXPECT().Context().Return(&ctx).AnyTimes()
+	target.EXPECT().Set(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")).Times(1)
+	southbound.Targets[topodevice.ID(device1)] = target
+	deviceChange = newPendingChange(2, "timeout", device1)
+	_, err = reconciler.Reconcile(controller.NewID(string(deviceChange.ID)))
+	assert.NoError(t, err)
+	deviceChange, err = deviceChanges.Get(deviceChange.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, changetypes.State_FAILED, deviceChange.Status.State)
+	assert.Contains(t, deviceChange.Status.Message, "deadline exceeded")
+	assert.NotContains(t, deviceChange.Status.Message, "attempts")
+	ctrl.Finish()
+}
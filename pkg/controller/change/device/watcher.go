@@ -52,11 +52,14 @@ func (w *Watcher) Start(ch chan<- controller.ID) error {
 	deviceCacheCh := make(chan stream.Event)
 	go func() {
 		for eventObj := range deviceCacheCh {
-			// TODO: Handle device deletes
-			if eventObj.Type == stream.None || eventObj.Type == stream.Created {
-				event := eventObj.Object.(*cache.Info)
+			event := eventObj.Object.(*cache.Info)
+			switch eventObj.Type {
+			case stream.None, stream.Created:
 				log.Infof("Received device event for device %v %v", event.DeviceID, event.Version)
 				w.watchDevice(devicetype.NewVersionedID(event.DeviceID, event.Version), ch)
+			case stream.Deleted:
+				log.Infof("Received device delete event for device %v %v", event.DeviceID, event.Version)
+				w.unwatchDevice(devicetype.NewVersionedID(event.DeviceID, event.Version))
 			}
 		}
 		w.mu.Lock()
@@ -103,6 +106,19 @@ func (w *Watcher) watchDevice(deviceID devicetype.VersionedID, ch chan<- control
 	}()
 }
 
+// unwatchDevice stops watching changes for the given device
+func (w *Watcher) unwatchDevice(deviceID devicetype.VersionedID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ctx := w.streams[deviceID]
+	if ctx == nil {
+		return
+	}
+	ctx.Close()
+	delete(w.streams, deviceID)
+}
+
 // Stop stops the device change watcher
 func (w *Watcher) Stop() {
 	w.mu.Lock()
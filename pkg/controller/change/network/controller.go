@@ -593,18 +593,6 @@ func (r *Reconciler) canTryRollback(change *networkchange.NetworkChange, deviceC
 	return true, nil
 }
 
-// isIntersectingChange indicates whether the changes from the two given NetworkChanges intersect
-func isIntersectingChange(config *networkchange.NetworkChange, history *networkchange.NetworkChange) bool {
-	for _, configChange := range config.Changes {
-		for _, historyChange := range history.Changes {
-			if configChange.DeviceID == historyChange.DeviceID {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 func getProtocolState(device *devicetopo.Device) topo.ChannelState {
 	// Find the gNMI protocol state for the device
 	var protocol *topo.ProtocolState
@@ -0,0 +1,99 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// isIntersectingChange indicates whether the changes from the two given NetworkChanges intersect, i.e.
+// whether they change the same device at paths where one path is equal to or contains the other
+func isIntersectingChange(config *networkchange.NetworkChange, history *networkchange.NetworkChange) bool {
+	for _, configChange := range config.Changes {
+		for _, historyChange := range history.Changes {
+			if configChange.DeviceID == historyChange.DeviceID && isIntersectingDeviceChange(configChange, historyChange) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIntersectingDeviceChange indicates whether the paths of the two given device changes intersect.
+// Changes with a path that cannot be parsed are assumed to intersect
+func isIntersectingDeviceChange(config *devicechange.Change, history *devicechange.Change) bool {
+	configPaths, err := parseChangePaths(config)
+	if err != nil {
+		log.Warnf("Failed to parse the paths of %s: %v", config.DeviceID, err)
+		return true
+	}
+	historyPaths, err := parseChangePaths(history)
+	if err != nil {
+		log.Warnf("Failed to parse the paths of %s: %v", history.DeviceID, err)
+		return true
+	}
+	for _, configPath := range configPaths {
+		for _, historyPath := range historyPaths {
+			if isIntersectingPath(configPath, historyPath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseChangePaths(change *devicechange.Change) ([]*gnmi.Path, error) {
+	paths := make([]*gnmi.Path, 0, len(change.Values))
+	for _, value := range change.Values {
+		path, err := utils.ParseGNMIElements(utils.SplitPath(value.Path))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// isIntersectingPath indicates whether one of the two given paths is equal to or contains the other
+func isIntersectingPath(path1 *gnmi.Path, path2 *gnmi.Path) bool {
+	for i := 0; i < len(path1.Elem) && i < len(path2.Elem); i++ {
+		elem1, elem2 := path1.Elem[i], path2.Elem[i]
+		if elem1.Name == "..." || elem2.Name == "..." {
+			return true
+		}
+		if !isIntersectingElem(elem1, elem2) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIntersectingElem indicates whether the two given path elements may name the same node. An
+// element without a key names every entry of the list, and a wildcard matches any name or key
+func isIntersectingElem(elem1 *gnmi.PathElem, elem2 *gnmi.PathElem) bool {
+	if elem1.Name != elem2.Name && elem1.Name != "*" && elem2.Name != "*" {
+		return false
+	}
+	for name, value1 := range elem1.Key {
+		value2, ok := elem2.Key[name]
+		if ok && value1 != value2 && value1 != "*" && value2 != "*" {
+			return false
+		}
+	}
+	return true
+}
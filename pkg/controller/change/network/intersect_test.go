@@ -0,0 +1,81 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"testing"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	"github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPathChange(deviceID device.ID, paths ...string) *networkchange.NetworkChange {
+	values := make([]*devicechange.ChangeValue, 0, len(paths))
+	for _, path := range paths {
+		values = append(values, &devicechange.ChangeValue{
+			Path:  path,
+			Value: devicechange.NewTypedValueString("value"),
+		})
+	}
+	return &networkchange.NetworkChange{
+		Changes: []*devicechange.Change{
+			{
+				DeviceID:      deviceID,
+				DeviceVersion: "1.0.0",
+				Values:        values,
+			},
+		},
+	}
+}
+
+func Test_isIntersectingChange(t *testing.T) {
+	tests := []struct {
+		path1     string
+		path2     string
+		intersect bool
+	}{
+		{"/a/b", "/a/b", true},
+		{"/a/b", "/a/b/c", true},
+		{"/a/b/c", "/a", true},
+		{"/a/b", "/a/c", false},
+		{"/a/b", "/a/bc", false},
+		{"/a/b", "/x/a/b", false},
+		{"/a[name=1]/b", "/a[name=1]/b", true},
+		{"/a[name=1]/b", "/a[name=2]/b", false},
+		{"/a[name=eth1:1]/b", "/a[name=eth1:2]/b", false},
+		{"/a[name=x/y]/b", "/a[name=x/z]/b", false},
+		{"/a/b", "/a[name=1]/b/c", true},
+		{"/a[name=1][type=x]/b", "/a[name=1]/b", true},
+		{"/a[name=1][type=x]/b", "/a[name=1][type=y]/b", false},
+		{"/a[name=*]/b", "/a[name=1]/b", true},
+		{"/a/*/c", "/a/b/c", true},
+		{"/a/*/c", "/a/b/d", false},
+		{"/a/...", "/a/b/c", true},
+	}
+	for _, test := range tests {
+		change1 := newPathChange("device-1", "/z", test.path1)
+		change2 := newPathChange("device-1", test.path2)
+		assert.Equal(t, test.intersect, isIntersectingChange(change1, change2), "%s %s", test.path1, test.path2)
+		assert.Equal(t, test.intersect, isIntersectingChange(change2, change1), "%s %s", test.path2, test.path1)
+	}
+
+	// Changes to different devices never intersect
+	assert.False(t, isIntersectingChange(newPathChange("device-1", "/a"), newPathChange("device-2", "/a")))
+
+	// Paths that cannot be parsed intersect
+	assert.True(t, isIntersectingChange(newPathChange("device-1", "/a[name]"), newPathChange("device-1", "/b")))
+}
@@ -111,6 +111,9 @@ func (w *DeviceWatcher) Start(ch chan<- controller.ID) error {
 	deviceCacheCh := make(chan stream.Event)
 	go func() {
 		for eventObj := range deviceCacheCh {
+			if eventObj.Type == stream.Deleted {
+				continue
+			}
 			event := eventObj.Object.(*cache.Info)
 			log.Infof("Received device event for device %v %v", event.DeviceID, event.Version)
 			device, err := w.DeviceStore.Get(devicetopo.ID(event.DeviceID))
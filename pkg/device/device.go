@@ -77,11 +77,11 @@ type Credentials struct {
 
 // TLSConfig contains information pertinent to establishing a secure connection
 type TLSConfig struct {
-	// name of the device's CA certificate
+	// name of the device's CA certificate file, or the PEM encoded certificate
 	CaCert string
-	// name of the device's certificate
+	// name of the device's certificate file, or the PEM encoded certificate
 	Cert string
-	// name of the device's TLS key
+	// name of the device's TLS key file, or the PEM encoded key
 	Key string
 	// indicates whether to connect to the device over plaintext
 	Plain bool
@@ -97,6 +97,14 @@ type ListResponse struct {
 	Device *Device
 }
 
+// ModelMismatchAttribute is the device attribute listing the modules of the model plugin
+// of a device that the device does not advertise in its capabilities
+const ModelMismatchAttribute = "onos-config.models.mismatch"
+
+// ActiveVersionAttribute is the device attribute naming the active configuration version of a
+// device with several versions. The type and version of the device itself are left unchanged
+const ActiveVersionAttribute = "onos-config.version.active"
+
 // ListResponseType is a device event type
 type ListResponseType int32
 
@@ -162,9 +170,11 @@ func ToObject(device *Device) *topo.Object {
 	setAttribute(o, topo.Version, device.Version)
 	setAttribute(o, topo.TLSInsecure, flag(device.TLS.Insecure))
 	setAttribute(o, topo.TLSPlain, flag(device.TLS.Plain))
-	setAttribute(o, topo.TLSInsecure, device.TLS.Key)
-	setAttribute(o, topo.TLSInsecure, device.TLS.CaCert)
-	setAttribute(o, topo.TLSInsecure, device.TLS.Cert)
+	setAttribute(o, topo.TLSKey, device.TLS.Key)
+	setAttribute(o, topo.TLSCaCert, device.TLS.CaCert)
+	setAttribute(o, topo.TLSCert, device.TLS.Cert)
+	// The credentials are only read from topo, so that updates of the device do not
+	// write a password in to its attributes
 
 	return o
 }
@@ -174,18 +184,28 @@ func ToDevice(object *topo.Object) (*Device, error) {
 	if object.Type != topo.Object_ENTITY {
 		return nil, fmt.Errorf("object is not a topo entity %v+", object)
 	}
-	version, ok := object.Attributes[topo.Version]
-	if !ok {
+	if _, ok := object.Attributes[topo.Version]; !ok {
 		return nil, fmt.Errorf("topo entity %s must have 'version' attribute to work with onos-config", object.ID)
 	}
+	if len(object.GetEntity().KindID) == 0 {
+		return nil, fmt.Errorf("topo entity %s must have a 'kindid' to work with onos-config", object.ID)
+	}
+	return ToUntypedDevice(object)
+}
+
+// ToUntypedDevice converts a topology object entity to a local device like ToDevice, but
+// the entity may have no version nor kind, for them to be detected from the capabilities
+// of the device
+func ToUntypedDevice(object *topo.Object) (*Device, error) {
+	if object.Type != topo.Object_ENTITY {
+		return nil, fmt.Errorf("object is not a topo entity %v+", object)
+	}
 	address, ok := object.Attributes[topo.Address]
 	if !ok {
 		return nil, fmt.Errorf("topo entity %s must have 'address' attribute to work with onos-config", object.ID)
 	}
+	version := object.Attributes[topo.Version]
 	typeKindID := Type(object.GetEntity().KindID)
-	if len(typeKindID) == 0 {
-		return nil, fmt.Errorf("topo entity %s must have a 'kindid' to work with onos-config", object.ID)
-	}
 	d := &Device{
 		ID:        ID(object.ID),
 		Revision:  object.Revision,
@@ -202,6 +222,10 @@ func ToDevice(object *topo.Object) (*Device, error) {
 			CaCert:   object.Attributes[topo.TLSCaCert],
 			Key:      object.Attributes[topo.TLSKey],
 		},
+		Credentials: Credentials{
+			User:     object.Attributes[topo.User],
+			Password: object.Attributes[topo.Password],
+		},
 		Attributes: object.Attributes,
 	}
 	return d, nil
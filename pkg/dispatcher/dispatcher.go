@@ -25,6 +25,7 @@ package dispatcher
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/onosproject/onos-config/pkg/events"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
@@ -32,16 +33,31 @@ import (
 
 var log = logging.GetLogger("dispatcher")
 
+// listenerBufferSize is the number of events buffered for each NBI listener. The events
+// for a listener whose buffer is full are dropped, so that a stalled listener holds up
+// neither the other listeners nor the device sessions
+const listenerBufferSize = 100
+
 // Dispatcher manages SB and NB configuration event listeners
 type Dispatcher struct {
-	nbiOpStateListenersLock sync.RWMutex
-	nbiOpStateListeners     map[string]chan events.OperationalStateEvent
+	// the drop counters are accessed atomically, and kept first for their alignment
+	droppedOpStateEvents        uint64
+	droppedConfigDriftEvents    uint64
+	droppedSouthboundErrors     uint64
+	nbiOpStateListenersLock     sync.RWMutex
+	nbiOpStateListeners         map[string]chan events.OperationalStateEvent
+	nbiConfigDriftListenersLock sync.RWMutex
+	nbiConfigDriftListeners     map[string]chan events.ConfigDriftEvent
+	nbiSouthboundErrorsLock     sync.RWMutex
+	nbiSouthboundErrorListeners map[string]chan events.DeviceResponse
 }
 
 // NewDispatcher creates and initializes a new event dispatcher
 func NewDispatcher() *Dispatcher {
 	return &Dispatcher{
-		nbiOpStateListeners: make(map[string]chan events.OperationalStateEvent),
+		nbiOpStateListeners:         make(map[string]chan events.OperationalStateEvent),
+		nbiConfigDriftListeners:     make(map[string]chan events.ConfigDriftEvent),
+		nbiSouthboundErrorListeners: make(map[string]chan events.DeviceResponse),
 	}
 }
 
@@ -55,8 +71,13 @@ func (d *Dispatcher) ListenOperationalState(operationalStateChannel <-chan event
 
 	for operationalStateEvent := range operationalStateChannel {
 		d.nbiOpStateListenersLock.RLock()
-		for _, nbiChan := range d.nbiOpStateListeners {
-			nbiChan <- operationalStateEvent
+		for subscriber, nbiChan := range d.nbiOpStateListeners {
+			select {
+			case nbiChan <- operationalStateEvent:
+			default:
+				atomic.AddUint64(&d.droppedOpStateEvents, 1)
+				log.Debugf("Dropped operational state event for listener %s", subscriber)
+			}
 		}
 		d.nbiOpStateListenersLock.RUnlock()
 	}
@@ -70,15 +91,15 @@ func (d *Dispatcher) RegisterOpState(subscriber string) (chan events.Operational
 	if _, ok := d.nbiOpStateListeners[subscriber]; ok {
 		return nil, fmt.Errorf("NBI operational state %s is already registered", subscriber)
 	}
-	channel := make(chan events.OperationalStateEvent)
+	channel := make(chan events.OperationalStateEvent, listenerBufferSize)
 	d.nbiOpStateListeners[subscriber] = channel
 	return channel, nil
 }
 
 // UnregisterOperationalState closes the device channel and removes it from the deviceListeners
 func (d *Dispatcher) UnregisterOperationalState(subscriber string) {
-	d.nbiOpStateListenersLock.RLock()
-	defer d.nbiOpStateListenersLock.RUnlock()
+	d.nbiOpStateListenersLock.Lock()
+	defer d.nbiOpStateListenersLock.Unlock()
 	channel, ok := d.nbiOpStateListeners[subscriber]
 	if !ok {
 		log.Infof("Subscriber %s had not been registered", subscriber)
@@ -88,6 +109,114 @@ func (d *Dispatcher) UnregisterOperationalState(subscriber string) {
 	close(channel)
 }
 
+// ListenConfigDrift is a go routine function that distributes the configuration
+// drift reports of the device sessions to the registered nbiListeners
+func (d *Dispatcher) ListenConfigDrift(configDriftChannel <-chan events.ConfigDriftEvent) {
+	log.Info("Configuration Drift Event listener initialized")
+
+	for configDriftEvent := range configDriftChannel {
+		d.nbiConfigDriftListenersLock.RLock()
+		for subscriber, nbiChan := range d.nbiConfigDriftListeners {
+			select {
+			case nbiChan <- configDriftEvent:
+			default:
+				atomic.AddUint64(&d.droppedConfigDriftEvents, 1)
+				log.Debugf("Dropped configuration drift event for listener %s", subscriber)
+			}
+		}
+		d.nbiConfigDriftListenersLock.RUnlock()
+	}
+}
+
+// RegisterConfigDrift is a way for nbi instances to register for
+// channel of configuration drift events
+func (d *Dispatcher) RegisterConfigDrift(subscriber string) (chan events.ConfigDriftEvent, error) {
+	d.nbiConfigDriftListenersLock.Lock()
+	defer d.nbiConfigDriftListenersLock.Unlock()
+	if _, ok := d.nbiConfigDriftListeners[subscriber]; ok {
+		return nil, fmt.Errorf("NBI configuration drift %s is already registered", subscriber)
+	}
+	channel := make(chan events.ConfigDriftEvent, listenerBufferSize)
+	d.nbiConfigDriftListeners[subscriber] = channel
+	return channel, nil
+}
+
+// UnregisterConfigDrift closes the drift channel and removes it from the listeners
+func (d *Dispatcher) UnregisterConfigDrift(subscriber string) {
+	d.nbiConfigDriftListenersLock.Lock()
+	defer d.nbiConfigDriftListenersLock.Unlock()
+	channel, ok := d.nbiConfigDriftListeners[subscriber]
+	if !ok {
+		log.Infof("Subscriber %s had not been registered", subscriber)
+		return
+	}
+	delete(d.nbiConfigDriftListeners, subscriber)
+	close(channel)
+}
+
+// ListenSouthboundErrors is a go routine function that distributes the errors of the
+// device sessions to the registered nbiListeners
+func (d *Dispatcher) ListenSouthboundErrors(southboundErrorChannel <-chan events.DeviceResponse) {
+	log.Info("Southbound Error Event listener initialized")
+
+	for southboundError := range southboundErrorChannel {
+		d.nbiSouthboundErrorsLock.RLock()
+		for subscriber, nbiChan := range d.nbiSouthboundErrorListeners {
+			select {
+			case nbiChan <- southboundError:
+			default:
+				atomic.AddUint64(&d.droppedSouthboundErrors, 1)
+				log.Debugf("Dropped southbound error event for listener %s", subscriber)
+			}
+		}
+		d.nbiSouthboundErrorsLock.RUnlock()
+	}
+}
+
+// RegisterSouthboundErrors is a way for nbi instances to register for
+// channel of southbound error events
+func (d *Dispatcher) RegisterSouthboundErrors(subscriber string) (chan events.DeviceResponse, error) {
+	d.nbiSouthboundErrorsLock.Lock()
+	defer d.nbiSouthboundErrorsLock.Unlock()
+	if _, ok := d.nbiSouthboundErrorListeners[subscriber]; ok {
+		return nil, fmt.Errorf("NBI southbound errors %s is already registered", subscriber)
+	}
+	channel := make(chan events.DeviceResponse, listenerBufferSize)
+	d.nbiSouthboundErrorListeners[subscriber] = channel
+	return channel, nil
+}
+
+// UnregisterSouthboundErrors closes the error channel and removes it from the listeners
+func (d *Dispatcher) UnregisterSouthboundErrors(subscriber string) {
+	d.nbiSouthboundErrorsLock.Lock()
+	defer d.nbiSouthboundErrorsLock.Unlock()
+	channel, ok := d.nbiSouthboundErrorListeners[subscriber]
+	if !ok {
+		log.Infof("Subscriber %s had not been registered", subscriber)
+		return
+	}
+	delete(d.nbiSouthboundErrorListeners, subscriber)
+	close(channel)
+}
+
+// DroppedOpStateEvents returns the number of operational state events dropped for
+// listeners that were not keeping up
+func (d *Dispatcher) DroppedOpStateEvents() uint64 {
+	return atomic.LoadUint64(&d.droppedOpStateEvents)
+}
+
+// DroppedConfigDriftEvents returns the number of configuration drift events dropped for
+// listeners that were not keeping up
+func (d *Dispatcher) DroppedConfigDriftEvents() uint64 {
+	return atomic.LoadUint64(&d.droppedConfigDriftEvents)
+}
+
+// DroppedSouthboundErrors returns the number of southbound error events dropped for
+// listeners that were not keeping up
+func (d *Dispatcher) DroppedSouthboundErrors() uint64 {
+	return atomic.LoadUint64(&d.droppedSouthboundErrors)
+}
+
 // GetListeners returns a list of registered listeners names
 func (d *Dispatcher) GetListeners() []string {
 	listenerKeys := make([]string, 0)
@@ -15,6 +15,7 @@
 package dispatcher
 
 import (
+	"fmt"
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	topodevice "github.com/onosproject/onos-config/pkg/device"
 	"github.com/onosproject/onos-config/pkg/events"
@@ -113,3 +114,87 @@ func testSyncOpState(testChan <-chan events.OperationalStateEvent, callback func
 		log.Info("OperationalState change for Test ", opStateChange)
 	}
 }
+
+func Test_listen_config_drift(t *testing.T) {
+	d := NewDispatcher()
+	ch, err := d.RegisterConfigDrift("nbiConfigDrift")
+	assert.NilError(t, err, "Unexpected error when registering nbi %s", err)
+	_, err = d.RegisterConfigDrift("nbiConfigDrift")
+	assert.ErrorContains(t, err, "already registered")
+
+	driftCh := make(chan events.ConfigDriftEvent, 10)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		d.ListenConfigDrift(driftCh)
+		wg.Done()
+	}()
+	driftCh <- events.NewConfigDriftEvent(string(device1.ID), nil,
+		[]*devicechange.PathValue{{Path: "testpath", Value: devicechange.NewTypedValueString("testValue")}}, nil)
+
+	event := <-ch
+	assert.Equal(t, event.Subject(), string(device1.ID))
+	assert.Equal(t, event.Extra()[0].Path, "testpath")
+
+	close(driftCh)
+	wg.Wait()
+
+	d.UnregisterConfigDrift("nbiConfigDrift")
+	_, ok := <-ch
+	assert.Assert(t, !ok)
+}
+
+func Test_listen_southbound_errors(t *testing.T) {
+	d := NewDispatcher()
+	ch, err := d.RegisterSouthboundErrors("nbiSouthboundErrors")
+	assert.NilError(t, err, "Unexpected error when registering nbi %s", err)
+	_, err = d.RegisterSouthboundErrors("nbiSouthboundErrors")
+	assert.ErrorContains(t, err, "already registered")
+
+	errorCh := make(chan events.DeviceResponse, 10)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		d.ListenSouthboundErrors(errorCh)
+		wg.Done()
+	}()
+	errorCh <- events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceCapabilities,
+		string(device1.ID), fmt.Errorf("capabilities failed"))
+
+	event := <-ch
+	assert.Equal(t, event.Subject(), string(device1.ID))
+	assert.Equal(t, event.EventType(), events.EventTypeErrorDeviceCapabilities)
+	assert.Error(t, event.Error(), "capabilities failed")
+
+	close(errorCh)
+	wg.Wait()
+
+	d.UnregisterSouthboundErrors("nbiSouthboundErrors")
+	_, ok := <-ch
+	assert.Assert(t, !ok)
+}
+
+func Test_listen_drops_for_stalled_listener(t *testing.T) {
+	d := NewDispatcher()
+	stalled, err := d.RegisterSouthboundErrors("stalled")
+	assert.NilError(t, err)
+
+	errorCh := make(chan events.DeviceResponse)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		d.ListenSouthboundErrors(errorCh)
+		wg.Done()
+	}()
+	for i := 0; i < listenerBufferSize+5; i++ {
+		errorCh <- events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceCapabilities,
+			string(device1.ID), fmt.Errorf("capabilities failed"))
+	}
+	close(errorCh)
+	wg.Wait()
+
+	// The listener that does not read gets its buffer full, and the rest is dropped
+	assert.Equal(t, len(stalled), listenerBufferSize)
+	assert.Equal(t, d.DroppedSouthboundErrors(), uint64(5))
+	d.UnregisterSouthboundErrors("stalled")
+}
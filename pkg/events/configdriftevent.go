@@ -0,0 +1,96 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"time"
+)
+
+// DriftedValue is a path whose value on the device differs from the intended value
+type DriftedValue struct {
+	Path     string
+	Intended *devicechange.TypedValue
+	Actual   *devicechange.TypedValue
+}
+
+// ConfigDriftEvent reports the differences between the intended configuration
+// of a device and the configuration it is actually running
+type ConfigDriftEvent interface {
+	Event
+	// Missing returns intended paths that are absent from the device
+	Missing() []*devicechange.PathValue
+	// Extra returns paths on the device that are not intended
+	Extra() []*devicechange.PathValue
+	// Different returns paths whose value on the device is not the intended one
+	Different() []*DriftedValue
+	// InSync indicates the device runs exactly the intended configuration
+	InSync() bool
+}
+
+type configDriftEventObj struct {
+	missing   []*devicechange.PathValue
+	extra     []*devicechange.PathValue
+	different []*DriftedValue
+}
+
+type configDriftEventImpl struct {
+	eventImpl
+}
+
+func (e configDriftEventImpl) Missing() []*devicechange.PathValue {
+	de, ok := e.object.(configDriftEventObj)
+	if ok {
+		return de.missing
+	}
+	return nil
+}
+
+func (e configDriftEventImpl) Extra() []*devicechange.PathValue {
+	de, ok := e.object.(configDriftEventObj)
+	if ok {
+		return de.extra
+	}
+	return nil
+}
+
+func (e configDriftEventImpl) Different() []*DriftedValue {
+	de, ok := e.object.(configDriftEventObj)
+	if ok {
+		return de.different
+	}
+	return nil
+}
+
+func (e configDriftEventImpl) InSync() bool {
+	return len(e.Missing()) == 0 && len(e.Extra()) == 0 && len(e.Different()) == 0
+}
+
+// NewConfigDriftEvent creates a new configuration drift event object
+func NewConfigDriftEvent(subject string, missing []*devicechange.PathValue,
+	extra []*devicechange.PathValue, different []*DriftedValue) ConfigDriftEvent {
+	return configDriftEventImpl{
+		eventImpl: eventImpl{
+			subject:   subject,
+			time:      time.Now(),
+			eventType: EventTypeConfigDrift,
+			object: configDriftEventObj{
+				missing:   missing,
+				extra:     extra,
+				different: different,
+			},
+		},
+	}
+}
@@ -39,6 +39,8 @@ const ( // For event types
 	EventTypeErrorTranslation
 	EventTypeErrorGetWithRoPaths
 	EventTypeTopoUpdate
+	EventTypeConfigDrift
+	EventTypeErrorModelMismatch
 )
 
 // EventAction is an enumerated type
@@ -57,8 +59,9 @@ func (et EventType) String() string {
 		"EventTypeErrorParseConfig", "EventTypeErrorDeviceConnect",
 		"EventTypeErrorDeviceCapabilities", "EventTypeErrorDeviceConnectInitialConfigSync",
 		"EventTypeErrorDeviceDisconnect",
-		"EventTypeErrorSubscribe, EventTypeErrorMissingModelPlugin, EventTypeErrorTranslation",
-		"EventTypeErrorGetWithRoPaths", "EventTypeTopoUpdate"}[et]
+		"EventTypeErrorSubscribe", "EventTypeErrorMissingModelPlugin", "EventTypeErrorTranslation",
+		"EventTypeErrorGetWithRoPaths", "EventTypeTopoUpdate", "EventTypeConfigDrift",
+		"EventTypeErrorModelMismatch"}[et]
 }
 
 // Event is a general purpose base type of event
@@ -57,6 +57,10 @@ func Test_operationalStateEventConstruction(t *testing.T) {
 
 	assert.Equal(t, event.Path(), path1)
 	assert.Equal(t, event.Value().ValueToString(), value1)
+	assert.Assert(t, event.Timestamp() <= time.Now().UnixNano())
+
+	event = NewOperationalStateEventWithTimestamp(eventSubject, path1, nil, EventItemDeleted, 1234)
+	assert.Equal(t, event.Timestamp(), int64(1234))
 }
 
 func Test_errorEventBoChangeIDConstruction(t *testing.T) {
@@ -70,3 +74,24 @@ func Test_errorEventBoChangeIDConstruction(t *testing.T) {
 	assert.Equal(t, event.Response(), "")
 	assert.Error(t, event.Error(), testResponse, "expected an error")
 }
+
+func Test_configDriftEventConstruction(t *testing.T) {
+	missing := []*devicechange.PathValue{{Path: path1, Value: devicechange.NewTypedValueString(value1)}}
+	different := []*DriftedValue{{
+		Path:     path1,
+		Intended: devicechange.NewTypedValueString(value1),
+		Actual:   devicechange.NewTypedValueString(testResponse),
+	}}
+	event := NewConfigDriftEvent(eventSubject, missing, nil, different)
+
+	assert.Equal(t, event.EventType(), EventTypeConfigDrift)
+	assert.Equal(t, event.Subject(), eventSubject)
+	assert.Assert(t, strings.Contains(EventTypeConfigDrift.String(), "ConfigDrift"))
+	assert.Equal(t, len(event.Missing()), 1)
+	assert.Equal(t, len(event.Extra()), 0)
+	assert.Equal(t, event.Different()[0].Actual.ValueToString(), testResponse)
+	assert.Assert(t, !event.InSync())
+
+	event = NewConfigDriftEvent(eventSubject, nil, nil, nil)
+	assert.Assert(t, event.InSync())
+}
@@ -25,12 +25,15 @@ type OperationalStateEvent interface {
 	ItemAction() EventAction
 	Path() string
 	Value() *devicechange.TypedValue
+	// Timestamp is when the device sampled the value, in nanoseconds since the epoch
+	Timestamp() int64
 }
 
 type operationalStateEventObj struct {
 	path       string
 	value      *devicechange.TypedValue
 	itemAction EventAction
+	timestamp  int64
 }
 
 type operationalStateEventImpl struct {
@@ -61,9 +64,24 @@ func (e operationalStateEventImpl) Value() *devicechange.TypedValue {
 	return nil
 }
 
+func (e operationalStateEventImpl) Timestamp() int64 {
+	oe, ok := e.object.(operationalStateEventObj)
+	if ok {
+		return oe.timestamp
+	}
+	return 0
+}
+
 // NewOperationalStateEvent creates a new operational state event object
 func NewOperationalStateEvent(subject string, path string, value *devicechange.TypedValue,
 	eventAction EventAction) OperationalStateEvent {
+	return NewOperationalStateEventWithTimestamp(subject, path, value, eventAction, time.Now().UnixNano())
+}
+
+// NewOperationalStateEventWithTimestamp creates a new operational state event object for
+// a value sampled by the device at the given time, in nanoseconds since the epoch
+func NewOperationalStateEventWithTimestamp(subject string, path string, value *devicechange.TypedValue,
+	eventAction EventAction, timestamp int64) OperationalStateEvent {
 	opStateEvent := operationalStateEventImpl{
 		eventImpl: eventImpl{
 			subject:   subject,
@@ -73,6 +91,7 @@ func NewOperationalStateEvent(subject string, path string, value *devicechange.T
 				itemAction: eventAction,
 				path:       path,
 				value:      value,
+				timestamp:  timestamp,
 			},
 		},
 	}
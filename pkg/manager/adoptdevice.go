@@ -0,0 +1,133 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	types "github.com/onosproject/onos-api/go/onos/config"
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// AdoptionChangePrefix prefixes the ID of the NetworkChanges created by device adoption
+const AdoptionChangePrefix = "adoption-"
+
+// AdoptDevice imports the configuration a device is running as its intended configuration.
+// The configuration is validated against the model of the device and stored as a
+// NetworkChange that is already complete, so nothing is pushed back to the device.
+// Only devices with no intended configuration can be adopted, and only by the
+// instance that is master for the device
+func (m *Manager) AdoptDevice(deviceID devicetype.ID) (*networkchange.NetworkChange, error) {
+	if m.sessionManager == nil {
+		return nil, errors.NewUnavailable("session manager is not started")
+	}
+	topoDevice, err := m.DeviceStore.Get(topodevice.ID(deviceID))
+	if err != nil {
+		return nil, err
+	}
+	version := devicetype.Version(topoDevice.Version)
+	deviceType := devicetype.Type(topoDevice.Type)
+
+	intended, err := m.DeviceStateStore.Get(devicetype.NewVersionedID(deviceID, version), 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(intended) > 0 {
+		return nil, errors.NewConflict("device %s %s already has %d configured paths", deviceID, version, len(intended))
+	}
+
+	running, err := m.sessionManager.GetRunningConfig(topoDevice.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(running) == 0 {
+		return nil, errors.NewInvalid("device %s is not running any configuration", deviceID)
+	}
+	updates := make(devicechange.TypedValueMap)
+	for _, pathValue := range running {
+		updates[pathValue.Path] = pathValue.Value
+	}
+	if err := m.ValidateNetworkConfig(deviceID, version, deviceType, updates, nil, 0); err != nil {
+		return nil, errors.NewInvalid("running configuration of %s is not valid: %v", deviceID, err)
+	}
+
+	change, err := computeDeviceChange(deviceID, version, deviceType, updates, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return m.createAdoptionChange(fmt.Sprintf("%s%s", AdoptionChangePrefix, uuid.New().String()), change)
+}
+
+// createAdoptionChange stores a NetworkChange, along with its DeviceChange, that is
+// already complete so that the controllers do not apply it to the device. The DeviceChange
+// takes the index the store assigns to the NetworkChange, so the NetworkChange is created
+// first and is deleted again along with the DeviceChange if the adoption cannot be completed
+func (m *Manager) createAdoptionChange(networkChangeID string, change *devicechange.Change) (*networkchange.NetworkChange, error) {
+	networkChange, err := networkchange.NewNetworkChange(networkChangeID, []*devicechange.Change{change})
+	if err != nil {
+		return nil, err
+	}
+	networkChange.Status = changetypes.Status{
+		Phase: changetypes.Phase_CHANGE,
+		State: changetypes.State_COMPLETE,
+	}
+	if err := m.NetworkChangesStore.Create(networkChange); err != nil {
+		return nil, err
+	}
+
+	// Following changes wait for the device changes of this change to be created
+	deviceChange := &devicechange.DeviceChange{
+		Index: devicechange.Index(networkChange.Index),
+		NetworkChange: devicechange.NetworkChangeRef{
+			ID:    types.ID(networkChange.ID),
+			Index: types.Index(networkChange.Index),
+		},
+		Change: change,
+		Status: changetypes.Status{
+			Phase: changetypes.Phase_CHANGE,
+			State: changetypes.State_COMPLETE,
+		},
+	}
+	if err := m.DeviceChangesStore.Create(deviceChange); err != nil {
+		m.deleteAdoptionChange(networkChange, nil)
+		return nil, err
+	}
+	networkChange.Refs = []*networkchange.DeviceChangeRef{
+		{DeviceChangeID: deviceChange.ID},
+	}
+	if err := m.NetworkChangesStore.Update(networkChange); err != nil {
+		m.deleteAdoptionChange(networkChange, deviceChange)
+		return nil, err
+	}
+	log.Infof("Adopted %d paths of %s %s as %s", len(change.Values), change.DeviceID, change.DeviceVersion, networkChange.ID)
+	return networkChange, nil
+}
+
+// deleteAdoptionChange deletes the changes of an adoption that failed part way
+func (m *Manager) deleteAdoptionChange(networkChange *networkchange.NetworkChange, deviceChange *devicechange.DeviceChange) {
+	if deviceChange != nil {
+		if err := m.DeviceChangesStore.Delete(deviceChange); err != nil && !errors.IsNotFound(err) {
+			log.Warnf("Failed to delete DeviceChange %s of failed adoption: %v", deviceChange.ID, err)
+		}
+	}
+	if err := m.NetworkChangesStore.Delete(networkChange); err != nil && !errors.IsNotFound(err) {
+		log.Warnf("Failed to delete NetworkChange %s of failed adoption: %v", networkChange.ID, err)
+	}
+}
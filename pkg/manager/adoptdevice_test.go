@@ -0,0 +1,148 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"github.com/golang/mock/gomock"
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/southbound/synchronizer"
+	mockstore "github.com/onosproject/onos-config/pkg/test/mocks/store"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_createAdoptionChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	networkChangesStore := mockstore.NewMockNetworkChangesStore(ctrl)
+	networkChangesStore.EXPECT().Create(gomock.Any()).DoAndReturn(
+		func(change *networkchange.NetworkChange) error {
+			assert.Equal(t, changetypes.State_COMPLETE, change.Status.State)
+			change.Index = 3
+			change.Revision = 1
+			return nil
+		}).Times(1)
+	networkChangesStore.EXPECT().Update(gomock.Any()).DoAndReturn(
+		func(change *networkchange.NetworkChange) error {
+			assert.Len(t, change.Refs, 1)
+			assert.Equal(t, devicechange.ID("adoption-1:Device1:1.0.0"), change.Refs[0].DeviceChangeID)
+			return nil
+		}).Times(1)
+
+	deviceChangesStore := mockstore.NewMockDeviceChangesStore(ctrl)
+	deviceChangesStore.EXPECT().Create(gomock.Any()).DoAndReturn(
+		func(change *devicechange.DeviceChange) error {
+			assert.Equal(t, devicechange.Index(3), change.Index)
+			assert.Equal(t, changetypes.State_COMPLETE, change.Status.State)
+			change.ID = devicechange.NewID(change.NetworkChange.ID, change.Change.DeviceID, change.Change.DeviceVersion)
+			return nil
+		}).Times(1)
+
+	mgrTest := &Manager{
+		NetworkChangesStore: networkChangesStore,
+		DeviceChangesStore:  deviceChangesStore,
+	}
+	updates := devicechange.TypedValueMap{
+		"/cont1a/cont2a/leaf2a": devicechange.NewTypedValueUint(12, 8),
+	}
+	change, err := computeDeviceChange(device1, deviceVersion1, deviceTypeTd, updates, nil, "")
+	assert.NoError(t, err)
+	networkChange, err := mgrTest.createAdoptionChange("adoption-1", change)
+	assert.NoError(t, err)
+	assert.Equal(t, networkchange.ID("adoption-1"), networkChange.ID)
+	assert.Equal(t, changetypes.Phase_CHANGE, networkChange.Status.Phase)
+	assert.Len(t, networkChange.Changes[0].Values, 1)
+}
+
+func Test_createAdoptionChangeFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	networkChangesStore := mockstore.NewMockNetworkChangesStore(ctrl)
+	networkChangesStore.EXPECT().Create(gomock.Any()).DoAndReturn(
+		func(change *networkchange.NetworkChange) error {
+			change.Index = 3
+			change.Revision = 1
+			return nil
+		}).Times(2)
+	networkChangesStore.EXPECT().Update(gomock.Any()).Return(errors.NewUnavailable("unavailable")).Times(1)
+	networkChangesStore.EXPECT().Delete(gomock.Any()).DoAndReturn(
+		func(change *networkchange.NetworkChange) error {
+			assert.Equal(t, networkchange.ID("adoption-1"), change.ID)
+			return nil
+		}).Times(2)
+
+	deviceChangesStore := mockstore.NewMockDeviceChangesStore(ctrl)
+	gomock.InOrder(
+		deviceChangesStore.EXPECT().Create(gomock.Any()).Return(errors.NewUnavailable("unavailable")),
+		deviceChangesStore.EXPECT().Create(gomock.Any()).DoAndReturn(
+			func(change *devicechange.DeviceChange) error {
+				change.ID = devicechange.NewID(change.NetworkChange.ID, change.Change.DeviceID, change.Change.DeviceVersion)
+				return nil
+			}),
+	)
+	deviceChangesStore.EXPECT().Delete(gomock.Any()).DoAndReturn(
+		func(change *devicechange.DeviceChange) error {
+			assert.Equal(t, devicechange.ID("adoption-1:Device1:1.0.0"), change.ID)
+			return nil
+		}).Times(1)
+
+	mgrTest := &Manager{
+		NetworkChangesStore: networkChangesStore,
+		DeviceChangesStore:  deviceChangesStore,
+	}
+	updates := devicechange.TypedValueMap{
+		"/cont1a/cont2a/leaf2a": devicechange.NewTypedValueUint(12, 8),
+	}
+	change, err := computeDeviceChange(device1, deviceVersion1, deviceTypeTd, updates, nil, "")
+	assert.NoError(t, err)
+
+	// The NetworkChange is deleted if its DeviceChange cannot be created
+	_, err = mgrTest.createAdoptionChange("adoption-1", change)
+	assert.True(t, errors.IsUnavailable(err))
+
+	// Both are deleted if the NetworkChange cannot refer to the DeviceChange
+	_, err = mgrTest.createAdoptionChange("adoption-1", change)
+	assert.True(t, errors.IsUnavailable(err))
+}
+
+func Test_AdoptDeviceWithConfig(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	deviceStore := mockstore.NewMockDeviceStore(ctrl)
+	deviceStore.EXPECT().Get(topodevice.ID(device1)).Return(&topodevice.Device{
+		ID:      device1,
+		Version: deviceVersion1,
+		Type:    deviceTypeTd,
+	}, nil)
+	deviceStateStore := mockstore.NewMockDeviceStateStore(ctrl)
+	deviceStateStore.EXPECT().Get(devicetype.NewVersionedID(device1, deviceVersion1), gomock.Any()).Return(
+		[]*devicechange.PathValue{
+			{Path: "/cont1a/cont2a/leaf2a", Value: devicechange.NewTypedValueUint(12, 8)},
+		}, nil)
+
+	mgrTest := &Manager{
+		DeviceStore:      deviceStore,
+		DeviceStateStore: deviceStateStore,
+		sessionManager:   &synchronizer.SessionManager{},
+	}
+	// Devices that already have an intended configuration cannot be adopted
+	_, err := mgrTest.AdoptDevice(device1)
+	assert.True(t, errors.IsConflict(err))
+}
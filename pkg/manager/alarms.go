@@ -0,0 +1,67 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"strings"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/onosproject/onos-config/pkg/alarms"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// GetActiveAlarms returns the alarms raised on the operational state of devices that
+// are not cleared yet, the oldest first
+func (m *Manager) GetActiveAlarms() ([]alarms.Alarm, error) {
+	if m.Alarms == nil {
+		return nil, errors.NewUnavailable("alarms are not enabled")
+	}
+	return m.Alarms.Active(), nil
+}
+
+// GetAlarmHistory returns the last raise and clear events of alarms, the oldest first
+func (m *Manager) GetAlarmHistory() ([]alarms.Event, error) {
+	if m.Alarms == nil {
+		return nil, errors.NewUnavailable("alarms are not enabled")
+	}
+	return m.Alarms.History(), nil
+}
+
+// validateAlarmRules validates the rules the alarms device is left with by the given updates
+// and deletes, in place of the model plugin the alarms device does not have
+func (m *Manager) validateAlarmRules(deviceID devicetype.VersionedID, updates devicechange.TypedValueMap,
+	deletes []string, lastWrite networkchange.Revision) error {
+	configValues, err := m.DeviceStateStore.Get(deviceID, lastWrite)
+	if err != nil {
+		return err
+	}
+	pathValues := make(devicechange.TypedValueMap)
+	for _, configValue := range configValues {
+		pathValues[configValue.Path] = configValue.Value
+	}
+	for changePath, changeValue := range updates {
+		pathValues[changePath] = changeValue
+	}
+	for _, deletePath := range deletes {
+		for path := range pathValues {
+			if path == deletePath || strings.HasPrefix(path, deletePath+"/") {
+				delete(pathValues, path)
+			}
+		}
+	}
+	return alarms.ValidateRules(pathValues)
+}
@@ -0,0 +1,32 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/southbound/synchronizer"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// GetDeviceCapabilities returns the capabilities a device advertised when it was last
+// connected, along with the modules of its model plugin it does not advertise. Only the
+// instance that is master for the device has them
+func (m *Manager) GetDeviceCapabilities(deviceID devicetype.ID) (*synchronizer.DeviceCapabilities, error) {
+	if m.sessionManager == nil {
+		return nil, errors.NewUnavailable("session manager is not started")
+	}
+	return m.sessionManager.GetDeviceCapabilities(topodevice.ID(deviceID))
+}
@@ -0,0 +1,39 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// CheckConfigDrift compares the configuration a device is running with the intended
+// configuration. Only the instance that is master for the device can check it
+func (m *Manager) CheckConfigDrift(deviceID devicetype.ID) (events.ConfigDriftEvent, error) {
+	if m.sessionManager == nil {
+		return nil, errors.NewUnavailable("session manager is not started")
+	}
+	return m.sessionManager.CheckConfigDrift(topodevice.ID(deviceID))
+}
+
+// GetConfigDrift returns the last configuration drift report of a device
+func (m *Manager) GetConfigDrift(deviceID devicetype.ID) (events.ConfigDriftEvent, error) {
+	if m.sessionManager == nil {
+		return nil, errors.NewUnavailable("session manager is not started")
+	}
+	return m.sessionManager.GetConfigDrift(topodevice.ID(deviceID))
+}
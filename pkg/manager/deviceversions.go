@@ -0,0 +1,233 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sort"
+
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	snaptype "github.com/onosproject/onos-api/go/onos/config/snapshot"
+	devicesnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/store/change/network"
+	"github.com/onosproject/onos-config/pkg/store/device/cache"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// ListDeviceVersions returns every configuration version of a device known to the device cache
+// along with the version that is active. The active version is empty if it cannot be determined
+func (m *Manager) ListDeviceVersions(deviceID devicetype.ID) ([]*cache.Info, devicetype.Version, error) {
+	deviceInfos := m.DeviceCache.GetDevicesByID(deviceID)
+	if len(deviceInfos) == 0 {
+		return nil, "", errors.NewNotFound("device %s has no configuration", deviceID)
+	}
+	sort.Slice(deviceInfos, func(i, j int) bool {
+		return deviceInfos[i].Version < deviceInfos[j].Version
+	})
+	topoDevice, err := m.DeviceStore.Get(topodevice.ID(deviceID))
+	if err != nil {
+		log.Infof("Device %s not found in topo store", deviceID)
+		topoDevice = nil
+	}
+	return deviceInfos, activeVersion(topoDevice, deviceInfos), nil
+}
+
+// SetActiveDeviceVersion marks a configuration version of a device as the active one. The version
+// is recorded in its own attribute of the device in the topo store so it is shared by every
+// instance in the cluster without changing the model of the device
+func (m *Manager) SetActiveDeviceVersion(deviceID devicetype.ID, version devicetype.Version) error {
+	info := findVersion(m.DeviceCache.GetDevicesByID(deviceID), version)
+	if info == nil {
+		return errors.NewNotFound("device %s has no configuration version %s", deviceID, version)
+	}
+	topoDevice, err := m.DeviceStore.Get(topodevice.ID(deviceID))
+	if err != nil {
+		return err
+	}
+	if topoDevice.Attributes[topodevice.ActiveVersionAttribute] == string(info.Version) {
+		return nil
+	}
+	log.Infof("Setting active version of %s to %s:%s", deviceID, info.Type, info.Version)
+	if topoDevice.Attributes == nil {
+		topoDevice.Attributes = make(map[string]string)
+	}
+	topoDevice.Attributes[topodevice.ActiveVersionAttribute] = string(info.Version)
+	_, err = m.DeviceStore.Update(topoDevice)
+	return err
+}
+
+// DeleteDeviceVersion deletes an inactive configuration version of a device. Completed
+// NetworkChanges are the history other versions and rollbacks depend on, so a version they refer
+// to cannot be deleted until a snapshot has compacted them. Failed NetworkChanges that only refer
+// to the version are deleted, then its DeviceChanges and its snapshot are purged. The device cache
+// and the device state store of every replica drop the version once no NetworkChange or snapshot
+// refers to it anymore
+func (m *Manager) DeleteDeviceVersion(deviceID devicetype.ID, version devicetype.Version) error {
+	deviceInfos := m.DeviceCache.GetDevicesByID(deviceID)
+	info := findVersion(deviceInfos, version)
+	if info == nil {
+		return errors.NewNotFound("device %s has no configuration version %s", deviceID, version)
+	}
+	topoDevice, err := m.DeviceStore.Get(topodevice.ID(deviceID))
+	if err != nil {
+		log.Infof("Device %s not found in topo store", deviceID)
+		topoDevice = nil
+	}
+	if activeVersion(topoDevice, deviceInfos) == version {
+		return errors.NewInvalid("version %s is the active version of device %s", version, deviceID)
+	}
+	versionedID := devicetype.NewVersionedID(deviceID, version)
+
+	// A snapshot of the version in progress would recreate what is about to be deleted
+	snapshots, err := m.listDeviceSnapshots(versionedID)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Status.State != snaptype.State_COMPLETE {
+			return errors.NewConflict("snapshot %s of device %s is in progress", snapshot.ID, versionedID)
+		}
+	}
+
+	changes, err := m.listDeviceChanges(versionedID)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Status.State == changetypes.State_PENDING {
+			return errors.NewConflict("change %s of device %s is pending", change.ID, versionedID)
+		}
+	}
+
+	result, err := m.NetworkChangesStore.Query(network.Query{DeviceID: deviceID})
+	if err != nil {
+		return err
+	}
+	networkChanges := make([]*networkchange.NetworkChange, 0, len(result.Changes))
+	for _, networkChange := range result.Changes {
+		if !refersToVersion(networkChange, versionedID) {
+			continue
+		}
+		switch {
+		case networkChange.Status.State == changetypes.State_PENDING:
+			return errors.NewConflict("change %s of device %s is pending", networkChange.ID, versionedID)
+		case networkChange.Status.State == changetypes.State_COMPLETE:
+			return errors.NewConflict("change %s of device %s is complete and must be snapshotted first", networkChange.ID, versionedID)
+		case len(networkChange.Changes) > 1:
+			return errors.NewConflict("change %s of device %s also changes other devices", networkChange.ID, versionedID)
+		}
+		networkChanges = append(networkChanges, networkChange)
+	}
+
+	for _, networkChange := range networkChanges {
+		if err := m.NetworkChangesStore.Delete(networkChange); err != nil {
+			return err
+		}
+	}
+	log.Infof("Deleted %d NetworkChanges for device %s", len(networkChanges), versionedID)
+
+	// Delete the changes and the snapshot in the same way as the snapshot DELETE phase
+	for _, change := range changes {
+		if err := m.DeviceChangesStore.Delete(change); err != nil {
+			return err
+		}
+	}
+	log.Infof("Deleted %d DeviceChanges for device %s", len(changes), versionedID)
+
+	if err := m.DeviceSnapshotStore.Purge(versionedID); err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if err := m.DeviceSnapshotStore.Delete(snapshot); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	log.Infof("Deleted snapshot for device %s", versionedID)
+	return nil
+}
+
+func refersToVersion(networkChange *networkchange.NetworkChange, versionedID devicetype.VersionedID) bool {
+	for _, deviceChange := range networkChange.Changes {
+		if deviceChange.GetVersionedDeviceID() == versionedID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) listDeviceSnapshots(versionedID devicetype.VersionedID) ([]*devicesnapshot.DeviceSnapshot, error) {
+	ch := make(chan *devicesnapshot.DeviceSnapshot)
+	ctx, err := m.DeviceSnapshotStore.List(ch)
+	if err != nil {
+		return nil, err
+	}
+	defer ctx.Close()
+
+	snapshots := make([]*devicesnapshot.DeviceSnapshot, 0)
+	for snapshot := range ch {
+		if snapshot.GetVersionedDeviceID() == versionedID {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+func (m *Manager) listDeviceChanges(versionedID devicetype.VersionedID) ([]*devicechange.DeviceChange, error) {
+	ch := make(chan *devicechange.DeviceChange)
+	ctx, err := m.DeviceChangesStore.List(versionedID, ch)
+	if err != nil {
+		return nil, err
+	}
+	defer ctx.Close()
+
+	changes := make([]*devicechange.DeviceChange, 0)
+	for change := range ch {
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// activeVersion returns the active version among the given versions of a device. A device
+// with a single version is always active on it, otherwise the active version recorded on the
+// device in topo decides, falling back to the version of the device itself
+func activeVersion(topoDevice *topodevice.Device, deviceInfos []*cache.Info) devicetype.Version {
+	if len(deviceInfos) == 1 {
+		return deviceInfos[0].Version
+	}
+	if topoDevice == nil {
+		return ""
+	}
+	if active, ok := topoDevice.Attributes[topodevice.ActiveVersionAttribute]; ok {
+		if info := findVersion(deviceInfos, devicetype.Version(active)); info != nil {
+			return info.Version
+		}
+	}
+	if info := findVersion(deviceInfos, devicetype.Version(topoDevice.Version)); info != nil {
+		return info.Version
+	}
+	return ""
+}
+
+func findVersion(deviceInfos []*cache.Info, version devicetype.Version) *cache.Info {
+	for _, info := range deviceInfos {
+		if info.Version == version {
+			return info
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"github.com/golang/mock/gomock"
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	snaptype "github.com/onosproject/onos-api/go/onos/config/snapshot"
+	devicesnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	networkstore "github.com/onosproject/onos-config/pkg/store/change/network"
+	"github.com/onosproject/onos-config/pkg/store/device/cache"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	mockstore "github.com/onosproject/onos-config/pkg/test/mocks/store"
+	mockcache "github.com/onosproject/onos-config/pkg/test/mocks/store/cache"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func setUpDeviceVersions(t *testing.T, changeState changetypes.State, networkChanges ...*networkchange.NetworkChange) (*Manager, *mockstore.MockDeviceStore) {
+	ctrl := gomock.NewController(t)
+
+	deviceCache := mockcache.NewMockCache(ctrl)
+	deviceCache.EXPECT().GetDevicesByID(devicetype.ID(device1)).Return([]*cache.Info{
+		{DeviceID: device1, Type: deviceTypeTd, Version: "2.0.0"},
+		{DeviceID: device1, Type: deviceTypeTd, Version: deviceVersion1},
+	}).AnyTimes()
+	deviceCache.EXPECT().GetDevicesByID(gomock.Any()).Return(make([]*cache.Info, 0)).AnyTimes()
+
+	deviceStore := mockstore.NewMockDeviceStore(ctrl)
+	deviceStore.EXPECT().Get(topodevice.ID(device1)).Return(&topodevice.Device{
+		ID:      device1,
+		Version: deviceVersion1,
+		Type:    deviceTypeTd,
+	}, nil).AnyTimes()
+
+	deviceChangesStore := mockstore.NewMockDeviceChangesStore(ctrl)
+	deviceChangesStore.EXPECT().List(devicetype.NewVersionedID(device1, "2.0.0"), gomock.Any()).DoAndReturn(
+		func(id devicetype.VersionedID, ch chan<- *devicechange.DeviceChange) (stream.Context, error) {
+			go func() {
+				ch <- &devicechange.DeviceChange{
+					ID:     "change-1:Device1:2.0.0",
+					Change: &devicechange.Change{DeviceID: device1, DeviceVersion: "2.0.0"},
+					Status: changetypes.Status{State: changeState},
+				}
+				close(ch)
+			}()
+			return stream.NewContext(func() {}), nil
+		}).AnyTimes()
+
+	deviceSnapshotStore := mockstore.NewMockDeviceSnapshotStore(ctrl)
+	deviceSnapshotStore.EXPECT().List(gomock.Any()).DoAndReturn(
+		func(ch chan<- *devicesnapshot.DeviceSnapshot) (stream.Context, error) {
+			go func() {
+				ch <- &devicesnapshot.DeviceSnapshot{
+					ID:            "snapshot-1:Device1:2.0.0",
+					DeviceID:      device1,
+					DeviceVersion: "2.0.0",
+					Status:        snaptype.Status{State: snaptype.State_COMPLETE},
+				}
+				ch <- &devicesnapshot.DeviceSnapshot{
+					ID:            "snapshot-1:Device1:1.0.0",
+					DeviceID:      device1,
+					DeviceVersion: deviceVersion1,
+					Status:        snaptype.Status{State: snaptype.State_RUNNING},
+				}
+				close(ch)
+			}()
+			return stream.NewContext(func() {}), nil
+		}).AnyTimes()
+
+	networkChangesStore := mockstore.NewMockNetworkChangesStore(ctrl)
+	networkChangesStore.EXPECT().Query(networkstore.Query{DeviceID: device1}).DoAndReturn(
+		func(query networkstore.Query) (*networkstore.QueryResult, error) {
+			return &networkstore.QueryResult{
+				Changes: append(networkChanges, &networkchange.NetworkChange{
+					ID: "change-3",
+					Changes: []*devicechange.Change{
+						{DeviceID: device1, DeviceVersion: deviceVersion1},
+					},
+					Status: changetypes.Status{State: changetypes.State_PENDING},
+				}),
+			}, nil
+		}).AnyTimes()
+
+	mgrTest := &Manager{
+		DeviceCache:         deviceCache,
+		DeviceStore:         deviceStore,
+		NetworkChangesStore: networkChangesStore,
+		DeviceChangesStore:  deviceChangesStore,
+		DeviceSnapshotStore: deviceSnapshotStore,
+	}
+	return mgrTest, deviceStore
+}
+
+func Test_ListDeviceVersions(t *testing.T) {
+	mgrTest, _ := setUpDeviceVersions(t, changetypes.State_COMPLETE)
+
+	versions, active, err := mgrTest.ListDeviceVersions(device1)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, devicetype.Version(deviceVersion1), versions[0].Version)
+	assert.Equal(t, devicetype.Version("2.0.0"), versions[1].Version)
+	assert.Equal(t, devicetype.Version(deviceVersion1), active)
+
+	_, _, err = mgrTest.ListDeviceVersions("unknown")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func Test_SetActiveDeviceVersion(t *testing.T) {
+	mgrTest, deviceStore := setUpDeviceVersions(t, changetypes.State_COMPLETE)
+
+	// The active version is recorded without changing the model of the device
+	deviceStore.EXPECT().Update(gomock.Any()).DoAndReturn(
+		func(device *topodevice.Device) (*topodevice.Device, error) {
+			assert.Equal(t, deviceVersion1, device.Version)
+			assert.Equal(t, topodevice.Type(deviceTypeTd), device.Type)
+			assert.Equal(t, "2.0.0", device.Attributes[topodevice.ActiveVersionAttribute])
+			assert.Equal(t, devicetype.Version("2.0.0"), activeVersion(device, mgrTest.DeviceCache.GetDevicesByID(device1)))
+			return device, nil
+		})
+	err := mgrTest.SetActiveDeviceVersion(device1, "2.0.0")
+	assert.NoError(t, err)
+
+	err = mgrTest.SetActiveDeviceVersion(device1, "3.0.0")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func Test_DeleteDeviceVersion(t *testing.T) {
+	mgrTest, _ := setUpDeviceVersions(t, changetypes.State_COMPLETE, &networkchange.NetworkChange{
+		ID: "change-2",
+		Changes: []*devicechange.Change{
+			{DeviceID: device1, DeviceVersion: "2.0.0"},
+		},
+		Status: changetypes.Status{State: changetypes.State_FAILED},
+	})
+	deviceChangesStore := mgrTest.DeviceChangesStore.(*mockstore.MockDeviceChangesStore)
+	deviceSnapshotStore := mgrTest.DeviceSnapshotStore.(*mockstore.MockDeviceSnapshotStore)
+
+	// The active version cannot be deleted
+	err := mgrTest.DeleteDeviceVersion(device1, deviceVersion1)
+	assert.True(t, errors.IsInvalid(err))
+
+	deviceChangesStore.EXPECT().Delete(gomock.Any()).Return(nil).Times(1)
+	deviceSnapshotStore.EXPECT().Purge(devicetype.NewVersionedID(device1, "2.0.0")).Return(nil).Times(1)
+	deviceSnapshotStore.EXPECT().Delete(gomock.Any()).DoAndReturn(
+		func(snapshot *devicesnapshot.DeviceSnapshot) error {
+			assert.Equal(t, devicesnapshot.ID("snapshot-1:Device1:2.0.0"), snapshot.ID)
+			return nil
+		}).Times(1)
+
+	// Failed network changes of the version alone are deleted
+	networkChangesStore := mgrTest.NetworkChangesStore.(*mockstore.MockNetworkChangesStore)
+	networkChangesStore.EXPECT().Delete(gomock.Any()).DoAndReturn(
+		func(change *networkchange.NetworkChange) error {
+			assert.Equal(t, networkchange.ID("change-2"), change.ID)
+			return nil
+		}).Times(1)
+	err = mgrTest.DeleteDeviceVersion(device1, "2.0.0")
+	assert.NoError(t, err)
+}
+
+func Test_DeleteDeviceVersionHistory(t *testing.T) {
+	// Completed network changes are never rewritten
+	mgrTest, _ := setUpDeviceVersions(t, changetypes.State_COMPLETE, &networkchange.NetworkChange{
+		ID: "change-1",
+		Changes: []*devicechange.Change{
+			{DeviceID: device1, DeviceVersion: "2.0.0"},
+		},
+		Status: changetypes.Status{State: changetypes.State_COMPLETE},
+	})
+	err := mgrTest.DeleteDeviceVersion(device1, "2.0.0")
+	assert.True(t, errors.IsConflict(err))
+
+	// Neither are network changes of other devices
+	mgrTest, _ = setUpDeviceVersions(t, changetypes.State_COMPLETE, &networkchange.NetworkChange{
+		ID: "change-1",
+		Changes: []*devicechange.Change{
+			{DeviceID: device1, DeviceVersion: "2.0.0"},
+			{DeviceID: "Device2", DeviceVersion: deviceVersion1},
+		},
+		Status: changetypes.Status{State: changetypes.State_FAILED},
+	})
+	err = mgrTest.DeleteDeviceVersion(device1, "2.0.0")
+	assert.True(t, errors.IsConflict(err))
+}
+
+func Test_DeleteDeviceVersionPending(t *testing.T) {
+	mgrTest, _ := setUpDeviceVersions(t, changetypes.State_PENDING)
+
+	err := mgrTest.DeleteDeviceVersion(device1, "2.0.0")
+	assert.True(t, errors.IsConflict(err))
+}
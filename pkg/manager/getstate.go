@@ -15,9 +15,13 @@
 package manager
 
 import (
+	"time"
+
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/southbound/synchronizer"
 	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
 )
 
 // GetTargetState returns a set of state values given a target and a path.
@@ -41,3 +45,28 @@ func (m *Manager) GetTargetState(target string, path string) []*devicechange.Pat
 	}
 	return configValues
 }
+
+// GetTargetStateInfo returns the timestamps and staleness of the state values of a target
+// matching a path, by path
+func (m *Manager) GetTargetStateInfo(target string, path string) map[string]synchronizer.OpStateInfo {
+	stateInfo := make(map[string]synchronizer.OpStateInfo)
+	pathRegexp := utils.MatchWildcardRegexp(path, false)
+	m.OperationalStateCacheLock.RLock()
+	defer m.OperationalStateCacheLock.RUnlock()
+	for pathCache, info := range m.OperationalStateInfo[topodevice.ID(target)] {
+		if pathRegexp.MatchString(pathCache) {
+			stateInfo[pathCache] = info
+		}
+	}
+	return stateInfo
+}
+
+// GetTargetStateHistory returns the recorded samples of the state values of a target
+// matching a path taken between from and to, oldest first
+func (m *Manager) GetTargetStateHistory(target string, path string, from time.Time, to time.Time) ([]synchronizer.OpStateSample, error) {
+	if m.OpStateHistory == nil {
+		return nil, errors.NewUnavailable("operational state history is not enabled")
+	}
+	pathRegexp := utils.MatchWildcardRegexp(path, false)
+	return m.OpStateHistory.Query(topodevice.ID(target), pathRegexp, from, to), nil
+}
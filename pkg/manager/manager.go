@@ -19,9 +19,11 @@ import (
 	"fmt"
 	"github.com/onosproject/onos-config/pkg/store/change/device/rbac"
 	"sync"
+	"time"
 
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/onosproject/onos-config/pkg/alarms"
 	devicechangectl "github.com/onosproject/onos-config/pkg/controller/change/device"
 	networkchangectl "github.com/onosproject/onos-config/pkg/controller/change/network"
 	devicesnapshotctl "github.com/onosproject/onos-config/pkg/controller/snapshot/device"
@@ -39,6 +41,7 @@ import (
 	"github.com/onosproject/onos-config/pkg/store/device/cache"
 	"github.com/onosproject/onos-config/pkg/store/leadership"
 	"github.com/onosproject/onos-config/pkg/store/mastership"
+	"github.com/onosproject/onos-config/pkg/store/opstate"
 	devicesnap "github.com/onosproject/onos-config/pkg/store/snapshot/device"
 	networksnap "github.com/onosproject/onos-config/pkg/store/snapshot/network"
 	"github.com/onosproject/onos-lib-go/pkg/controller"
@@ -53,30 +56,50 @@ var log = logging.GetLogger("manager")
 
 // Manager single point of entry for the config system.
 type Manager struct {
-	LeadershipStore           leadership.Store
-	MastershipStore           mastership.Store
-	DeviceChangesStore        device.Store
-	DeviceStateStore          state.Store
-	DeviceStore               devicestore.Store
-	DeviceCache               cache.Cache
-	NetworkChangesStore       network.Store
-	NetworkSnapshotStore      networksnap.Store
-	DeviceSnapshotStore       devicesnap.Store
-	networkChangeController   *controller.Controller
-	deviceChangeController    *controller.Controller
-	networkSnapshotController *controller.Controller
-	deviceSnapshotController  *controller.Controller
-	ModelRegistry             *modelregistry.ModelRegistry
-	TopoChannel               chan *topodevice.ListResponse
-	OperationalStateChannel   chan events.OperationalStateEvent
-	SouthboundErrorChan       chan events.DeviceResponse
-	Dispatcher                *dispatcher.Dispatcher
-	OperationalStateCache     map[topodevice.ID]devicechange.TypedValueMap
-	OperationalStateCacheLock *sync.RWMutex
-	RbacCache                 rbac.Cache
-	allowUnvalidatedConfig    bool
+	LeadershipStore            leadership.Store
+	MastershipStore            mastership.Store
+	DeviceChangesStore         device.Store
+	DeviceStateStore           state.Store
+	DeviceStore                devicestore.Store
+	DeviceCache                cache.Cache
+	NetworkChangesStore        network.Store
+	NetworkSnapshotStore       networksnap.Store
+	DeviceSnapshotStore        devicesnap.Store
+	networkChangeController    *controller.Controller
+	deviceChangeController     *controller.Controller
+	networkSnapshotController  *controller.Controller
+	deviceSnapshotController   *controller.Controller
+	ModelRegistry              *modelregistry.ModelRegistry
+	TopoChannel                chan *topodevice.ListResponse
+	OperationalStateChannel    chan events.OperationalStateEvent
+	ConfigDriftChannel         chan events.ConfigDriftEvent
+	ConfigDriftInterval        time.Duration
+	RemediationPolicy          synchronizer.RemediationPolicy
+	ModelRemediationPolicies   map[string]synchronizer.RemediationPolicy
+	CredentialsCheckInterval   time.Duration
+	OpStateSubscription        synchronizer.OpStateSubscription
+	ModelOpStateSubscriptions  map[string]synchronizer.OpStateSubscription
+	SouthboundErrorChan        chan events.DeviceResponse
+	SouthboundErrors           *synchronizer.SouthboundErrors
+	StrictModels               bool
+	AutoDetectModels           bool
+	Dispatcher                 *dispatcher.Dispatcher
+	OperationalStateCache      map[topodevice.ID]devicechange.TypedValueMap
+	OperationalStateCacheLock  *sync.RWMutex
+	OperationalStateInfo       map[topodevice.ID]synchronizer.OpStateInfoMap
+	OpStateStore               opstate.Store
+	OpStateReplicationInterval time.Duration
+	OpStateHistory             *synchronizer.OpStateHistory
+	Alarms                     alarms.Alarms
+	RbacCache                  rbac.Cache
+	allowUnvalidatedConfig     bool
+	sessionManager             *synchronizer.SessionManager
 }
 
+// southboundErrorChanSize is the number of southbound errors buffered for the dispatcher.
+// The sessions drop their errors when it is full
+const southboundErrorChanSize = 100
+
 // NewManager initializes the network config manager subsystem.
 func NewManager(leadershipStore leadership.Store, mastershipStore mastership.Store, deviceChangesStore device.Store,
 	deviceStateStore state.Store, deviceStore devicestore.Store, deviceCache cache.Cache,
@@ -100,10 +123,15 @@ func NewManager(leadershipStore leadership.Store, mastershipStore mastership.Sto
 		TopoChannel:               make(chan *topodevice.ListResponse, 10),
 		ModelRegistry:             modelRegistry,
 		OperationalStateChannel:   make(chan events.OperationalStateEvent),
-		SouthboundErrorChan:       make(chan events.DeviceResponse),
+		ConfigDriftChannel:        make(chan events.ConfigDriftEvent),
+		CredentialsCheckInterval:  synchronizer.DefaultCredentialsCheckInterval,
+		OpStateSubscription:       synchronizer.DefaultOpStateSubscription(),
+		SouthboundErrorChan:       make(chan events.DeviceResponse, southboundErrorChanSize),
+		SouthboundErrors:          synchronizer.NewSouthboundErrors(synchronizer.DefaultSouthboundErrorsSize),
 		Dispatcher:                dispatcher.NewDispatcher(),
 		OperationalStateCache:     make(map[topodevice.ID]devicechange.TypedValueMap),
 		OperationalStateCacheLock: &sync.RWMutex{},
+		OperationalStateInfo:      make(map[topodevice.ID]synchronizer.OpStateInfoMap),
 		allowUnvalidatedConfig:    allowUnvalidatedConfig,
 		RbacCache:                 rbacCache,
 	}
@@ -142,6 +170,8 @@ func (m *Manager) Run() {
 
 	// Start the main dispatcher system
 	go m.Dispatcher.ListenOperationalState(m.OperationalStateChannel)
+	go m.Dispatcher.ListenConfigDrift(m.ConfigDriftChannel)
+	go m.Dispatcher.ListenSouthboundErrors(m.SouthboundErrorChan)
 
 	sessionManager, err := synchronizer.NewSessionManager(
 		synchronizer.WithTopoChannel(m.TopoChannel),
@@ -151,7 +181,24 @@ func (m *Manager) Run() {
 		synchronizer.WithOperationalStateCache(m.OperationalStateCache),
 		synchronizer.WithNewTargetFn(southbound.TargetGenerator),
 		synchronizer.WithOperationalStateCacheLock(m.OperationalStateCacheLock),
+		synchronizer.WithOperationalStateInfo(m.OperationalStateInfo),
+		synchronizer.WithOpStateStore(m.OpStateStore),
+		synchronizer.WithOpStateReplicationInterval(m.OpStateReplicationInterval),
+		synchronizer.WithOpStateHistory(m.OpStateHistory),
+		synchronizer.WithSouthboundErrorChannel(m.SouthboundErrorChan),
+		synchronizer.WithSouthboundErrors(m.SouthboundErrors),
+		synchronizer.WithStrictModels(m.StrictModels),
+		synchronizer.WithAutoDetectModels(m.AutoDetectModels),
 		synchronizer.WithDeviceChangeStore(m.DeviceChangesStore),
+		synchronizer.WithDeviceStateStore(m.DeviceStateStore),
+		synchronizer.WithConfigDriftChannel(m.ConfigDriftChannel),
+		synchronizer.WithConfigDriftInterval(m.ConfigDriftInterval),
+		synchronizer.WithNetworkChangeStore(m.NetworkChangesStore),
+		synchronizer.WithRemediationPolicy(m.RemediationPolicy),
+		synchronizer.WithModelRemediationPolicies(m.ModelRemediationPolicies),
+		synchronizer.WithCredentialsCheckInterval(m.CredentialsCheckInterval),
+		synchronizer.WithOpStateSubscription(m.OpStateSubscription),
+		synchronizer.WithModelOpStateSubscriptions(m.ModelOpStateSubscriptions),
 		synchronizer.WithMastershipStore(m.MastershipStore),
 		synchronizer.WithDeviceStore(m.DeviceStore),
 		synchronizer.WithSessions(make(map[topodevice.ID]*synchronizer.Session)),
@@ -165,6 +212,7 @@ func (m *Manager) Run() {
 	if err != nil {
 		log.Errorf("Error in starting session manager", err)
 	}
+	m.sessionManager = sessionManager
 
 	log.Info("Manager Started")
 }
@@ -172,8 +220,17 @@ func (m *Manager) Run() {
 //Close kills the channels and manager related objects
 func (m *Manager) Close() {
 	log.Info("Closing Manager")
+	if m.Alarms != nil {
+		_ = m.Alarms.Close()
+	}
+	// The sessions are stopped first, as they publish on the channels
+	if m.sessionManager != nil {
+		m.sessionManager.Close()
+	}
 	close(m.TopoChannel)
 	close(m.OperationalStateChannel)
+	close(m.ConfigDriftChannel)
+	close(m.SouthboundErrorChan)
 }
 
 // GetManager returns the initialized and running instance of manager.
@@ -218,6 +275,14 @@ func (m *Manager) CheckCacheForDevice(target devicetype.ID, deviceType devicetyp
 				return di.Type, di.Version, nil
 			}
 		}
+		// Without an explicit version fall back to the active one
+		if version == "" && errTopoDevice == nil {
+			if active := activeVersion(topoDevice, deviceInfos); active != "" {
+				info := findVersion(deviceInfos, active)
+				log.Infof("Handling target %s as active %s:%s", target, info.Type, info.Version)
+				return info.Type, info.Version, nil
+			}
+		}
 		// Else allow it as a new version
 		if deviceType == deviceInfos[0].Type && version != "" {
 			log.Infof("Handling target %s as %s:%s", target, deviceType, version)
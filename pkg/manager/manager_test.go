@@ -22,8 +22,11 @@ import (
 	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
 	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
 	configmodel "github.com/onosproject/onos-config-model/pkg/model"
+	"github.com/onosproject/onos-config/pkg/alarms"
 	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
 	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-config/pkg/southbound/synchronizer"
 	networkstore "github.com/onosproject/onos-config/pkg/store/change/network"
 	"github.com/onosproject/onos-config/pkg/store/device/cache"
 	"github.com/onosproject/onos-config/pkg/store/stream"
@@ -643,6 +646,153 @@ func TestManager_GetTargetState(t *testing.T) {
 	assert.Len(t, stateBad, 0, "Bad path entry has incorrect length %d", len(stateBad))
 }
 
+func TestManager_GetTargetStateInfo(t *testing.T) {
+	const (
+		device1 = "device1"
+		path1   = "/a/b/c"
+		path2   = "/x/y/z"
+	)
+	mgrTest, _ := setUp(t)
+
+	mgrTest.OperationalStateCacheLock.Lock()
+	mgrTest.OperationalStateInfo[device1] = synchronizer.OpStateInfoMap{
+		path1: {Timestamp: 1000},
+		path2: {Timestamp: 2000, Stale: true},
+	}
+	mgrTest.OperationalStateCacheLock.Unlock()
+
+	info := mgrTest.GetTargetStateInfo(device1, "/a/*/c")
+	assert.Len(t, info, 1)
+	assert.Equal(t, int64(1000), info[path1].Timestamp)
+	assert.False(t, info[path1].Stale)
+
+	info = mgrTest.GetTargetStateInfo(device1, path2)
+	assert.True(t, info[path2].Stale)
+}
+
+func TestManager_GetTargetStateHistory(t *testing.T) {
+	const (
+		device1 = "device1"
+		path1   = "/a/b/c"
+	)
+	mgrTest, _ := setUp(t)
+
+	_, err := mgrTest.GetTargetStateHistory(device1, path1, time.Time{}, time.Time{})
+	assert.EqualError(t, err, "operational state history is not enabled")
+
+	mgrTest.OpStateHistory = synchronizer.NewOpStateHistory(10, 0)
+	now := time.Now()
+	mgrTest.OpStateHistory.Record(device1, synchronizer.OpStateSample{
+		Path:      path1,
+		Value:     devicechange.NewTypedValueString("DOWN"),
+		Timestamp: now.Add(-time.Minute).UnixNano(),
+	})
+	mgrTest.OpStateHistory.Record(device1, synchronizer.OpStateSample{
+		Path:      path1,
+		Value:     devicechange.NewTypedValueString("UP"),
+		Timestamp: now.UnixNano(),
+	})
+
+	samples, err := mgrTest.GetTargetStateHistory(device1, "/a/*/c", now.Add(-time.Second), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 1)
+	assert.Equal(t, "UP", samples[0].Value.ValueToString())
+}
+
+type stubAlarms struct {
+	id      devicetype.VersionedID
+	active  []alarms.Alarm
+	history []alarms.Event
+}
+
+func (s *stubAlarms) Close() error                     { return nil }
+func (s *stubAlarms) DeviceID() devicetype.VersionedID { return s.id }
+func (s *stubAlarms) Rules() map[string]*alarms.Rule   { return nil }
+func (s *stubAlarms) Active() []alarms.Alarm           { return s.active }
+func (s *stubAlarms) History() []alarms.Event          { return s.history }
+
+func TestManager_GetAlarms(t *testing.T) {
+	mgrTest, _ := setUp(t)
+
+	_, err := mgrTest.GetActiveAlarms()
+	assert.EqualError(t, err, "alarms are not enabled")
+	_, err = mgrTest.GetAlarmHistory()
+	assert.EqualError(t, err, "alarms are not enabled")
+
+	alarm := alarms.Alarm{ID: "cpu-1", RuleID: "cpu", DeviceID: "device1", Severity: alarms.SeverityMajor}
+	mgrTest.Alarms = &stubAlarms{
+		active:  []alarms.Alarm{alarm},
+		history: []alarms.Event{{Type: alarms.EventRaised, Alarm: alarm}},
+	}
+	active, err := mgrTest.GetActiveAlarms()
+	assert.NoError(t, err)
+	assert.Equal(t, []alarms.Alarm{alarm}, active)
+	history, err := mgrTest.GetAlarmHistory()
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, alarms.EventRaised, history[0].Type)
+}
+
+func TestManager_ValidateAlarmRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	alarmsID := devicetype.NewVersionedID("alarms", "1.0.0")
+	deviceStateStore := mockstore.NewMockDeviceStateStore(ctrl)
+	deviceStateStore.EXPECT().Get(alarmsID, gomock.Any()).Return([]*devicechange.PathValue{
+		{Path: "/alarms/rule[id=cpu]/path", Value: devicechange.NewTypedValueString("/system/cpus/cpu[index=*]/state/total/instant")},
+		{Path: "/alarms/rule[id=cpu]/condition", Value: devicechange.NewTypedValueString("> 90")},
+	}, nil).AnyTimes()
+	mgrTest := &Manager{
+		DeviceStateStore: deviceStateStore,
+		Alarms:           &stubAlarms{id: alarmsID},
+	}
+
+	// The alarms device is validated without a model plugin
+	err := mgrTest.ValidateNetworkConfig("alarms", "1.0.0", "Alarms", devicechange.TypedValueMap{
+		"/alarms/rule[id=cpu]/severity": devicechange.NewTypedValueString("major"),
+	}, nil, 0)
+	assert.NoError(t, err)
+
+	err = mgrTest.ValidateNetworkConfig("alarms", "1.0.0", "Alarms", devicechange.TypedValueMap{
+		"/alarms/rule[id=cpu]/condition": devicechange.NewTypedValueString("about 90"),
+	}, nil, 0)
+	assert.Error(t, err)
+
+	err = mgrTest.ValidateNetworkConfig("alarms", "1.0.0", "Alarms", devicechange.TypedValueMap{
+		"/alarms/rule[id=mem]/path": devicechange.NewTypedValueString("/system/memory/state/used"),
+	}, nil, 0)
+	assert.Error(t, err)
+
+	err = mgrTest.ValidateNetworkConfig("alarms", "1.0.0", "Alarms", nil,
+		[]string{"/alarms/rule[id=cpu]/condition"}, 0)
+	assert.Error(t, err)
+
+	err = mgrTest.ValidateNetworkConfig("alarms", "1.0.0", "Alarms", nil,
+		[]string{"/alarms/rule[id=cpu]"}, 0)
+	assert.NoError(t, err)
+}
+
+func TestManager_GetSouthboundErrors(t *testing.T) {
+	mgrTest, _ := setUp(t)
+
+	mgrTest.SouthboundErrors.Record(events.NewErrorEventNoChangeID(events.EventTypeErrorSubscribe,
+		"device1", errors.New("subscribe failed")))
+	mgrTest.SouthboundErrors.Record(events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceConnect,
+		"device2", errors.New("connect failed")))
+
+	errs, err := mgrTest.GetSouthboundErrors("device1")
+	assert.NoError(t, err)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0].Error(), "subscribe failed")
+
+	errs, err = mgrTest.GetSouthboundErrors("")
+	assert.NoError(t, err)
+	assert.Len(t, errs, 2)
+
+	mgrTest.SouthboundErrors = nil
+	_, err = mgrTest.GetSouthboundErrors("device1")
+	assert.EqualError(t, err, "southbound errors are not kept")
+}
+
 type MockModelPlugin struct{}
 
 func (m MockModelPlugin) ModelData() (string, string, []*gnmi.ModelData, string) {
@@ -781,10 +931,11 @@ func TestManager_CheckCacheForDevice(t *testing.T) {
 	assert.Equal(t, tdType, string(ty))
 	assert.Equal(t, v1, string(v))
 
-	// Fully ambiguous - no version or type given
-	_, _, err = mgrTest.CheckCacheForDevice(deviceTest1, "", "")
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "DeviceTest1 has 2 versions. Specify 1 version with extension 102")
+	// No version or type given - the active version from topo is used
+	ty, v, err = mgrTest.CheckCacheForDevice(deviceTest1, "", "")
+	assert.NoError(t, err, "testing cache access")
+	assert.Equal(t, tdType, string(ty))
+	assert.Equal(t, v1, string(v))
 
 	/********************************************************************
 	 * deviceTest1 v2.0.0
@@ -801,10 +952,11 @@ func TestManager_CheckCacheForDevice(t *testing.T) {
 	assert.Equal(t, tdType, string(ty))
 	assert.Equal(t, v2, string(v))
 
-	// Fully ambiguous - no version or type given
-	_, _, err = mgrTest.CheckCacheForDevice(deviceTest1, "", "")
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "DeviceTest1 has 2 versions. Specify 1 version with extension 102")
+	// No version or type given - the active version from topo is used
+	ty, v, err = mgrTest.CheckCacheForDevice(deviceTest1, "", "")
+	assert.NoError(t, err, "testing cache access")
+	assert.Equal(t, tdType, string(ty))
+	assert.Equal(t, v1, string(v))
 
 	// Wrong device type given - ignored
 	ty, v, err = mgrTest.CheckCacheForDevice(deviceTest1, dsType, v1)
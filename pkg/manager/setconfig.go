@@ -36,6 +36,11 @@ const SetConfigAlreadyApplied = "Already applied:"
 func (m *Manager) ValidateNetworkConfig(deviceName devicetype.ID, version devicetype.Version,
 	deviceType devicetype.Type, updates devicechange.TypedValueMap, deletes []string, lastWrite networkchange.Revision) error {
 
+	// The internal alarms device has no model plugin, its rules are validated instead
+	if m.Alarms != nil && devicetype.NewVersionedID(deviceName, version) == m.Alarms.DeviceID() {
+		return m.validateAlarmRules(m.Alarms.DeviceID(), updates, deletes, lastWrite)
+	}
+
 	modelName := utils.ToModelName(deviceType, version)
 	deviceModelYgotPlugin, err := m.ModelRegistry.GetPlugin(modelName)
 	if err != nil {
@@ -0,0 +1,32 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// GetSouthboundErrors returns the recent errors of the session of a device, or of all
+// devices if deviceID is empty, oldest first. New errors are published through the
+// dispatcher, see Dispatcher.RegisterSouthboundErrors
+func (m *Manager) GetSouthboundErrors(deviceID devicetype.ID) ([]events.DeviceResponse, error) {
+	if m.SouthboundErrors == nil {
+		return nil, errors.NewUnavailable("southbound errors are not kept")
+	}
+	return m.SouthboundErrors.List(topodevice.ID(deviceID)), nil
+}
@@ -50,12 +50,12 @@ func (s *Server) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetRespon
 	}
 
 	for _, path := range req.GetPath() {
-		updates, err := s.getUpdate(version, prefix, path, req.GetEncoding())
+		updates, timestamp, err := s.getUpdate(version, prefix, path, req.GetEncoding())
 		if err != nil {
 			return nil, status.Error(codes.Internal, err.Error())
 		}
 		notification := &gnmi.Notification{
-			Timestamp: time.Now().Unix(),
+			Timestamp: timestamp,
 			Update:    updates,
 			Prefix:    prefix,
 		}
@@ -64,12 +64,12 @@ func (s *Server) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetRespon
 	}
 	// Alternatively - if there's only the prefix
 	if len(req.GetPath()) == 0 {
-		updates, err := s.getUpdate(version, prefix, nil, req.GetEncoding())
+		updates, timestamp, err := s.getUpdate(version, prefix, nil, req.GetEncoding())
 		if err != nil {
 			return nil, status.Error(codes.Internal, err.Error())
 		}
 		notification := &gnmi.Notification{
-			Timestamp: time.Now().Unix(),
+			Timestamp: timestamp,
 			Update:    updates,
 			Prefix:    prefix,
 		}
@@ -83,10 +83,12 @@ func (s *Server) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetRespon
 	return &response, nil
 }
 
-// getUpdate utility method for getting an Update for a given path
-func (s *Server) getUpdate(version devicetype.Version, prefix *gnmi.Path, path *gnmi.Path, encoding gnmi.Encoding) ([]*gnmi.Update, error) {
+// getUpdate utility method for getting an Update for a given path. The timestamp of the
+// update is the one of its oldest state value, so that it tells how old the values are
+func (s *Server) getUpdate(version devicetype.Version, prefix *gnmi.Path, path *gnmi.Path, encoding gnmi.Encoding) ([]*gnmi.Update, int64, error) {
+	timestamp := time.Now().UnixNano()
 	if (path == nil || path.Target == "") && (prefix == nil || prefix.Target == "") {
-		return nil, fmt.Errorf("invalid request - Path %s has no target", utils.StrPath(path))
+		return nil, 0, fmt.Errorf("invalid request - Path %s has no target", utils.StrPath(path))
 	}
 
 	// If a target exists on the path, use it. If not use target of Prefix
@@ -120,7 +122,7 @@ func (s *Server) getUpdate(version devicetype.Version, prefix *gnmi.Path, path *
 			typedVal = gnmi.TypedValue{
 				Value: &gnmi.TypedValue_LeaflistVal{LeaflistVal: &gnmi.ScalarArray{Element: deviceIDStrs}}}
 		default:
-			return nil, fmt.Errorf("get targets - unhandled encoding format %v", encoding)
+			return nil, 0, fmt.Errorf("get targets - unhandled encoding format %v", encoding)
 		}
 		update := gnmi.Update{
 			Path: &allDevicesPath,
@@ -129,13 +131,13 @@ func (s *Server) getUpdate(version devicetype.Version, prefix *gnmi.Path, path *
 		updates := []*gnmi.Update{
 			&update,
 		}
-		return updates, nil
+		return updates, timestamp, nil
 	}
 
 	_, version, errTypeVersion := manager.GetManager().CheckCacheForDevice(devicetype.ID(target), "", version)
 	if errTypeVersion != nil {
 		log.Errorf("Error while extracting type and version for target %s with err %v", target, errTypeVersion)
-		return nil, status.Error(codes.InvalidArgument, errTypeVersion.Error())
+		return nil, 0, status.Error(codes.InvalidArgument, errTypeVersion.Error())
 	}
 
 	pathAsString := utils.StrPath(path)
@@ -151,14 +153,20 @@ func (s *Server) getUpdate(version devicetype.Version, prefix *gnmi.Path, path *
 		devicetype.ID(target), version, pathAsString, revision)
 	if errGetTargetCfg != nil {
 		log.Error("Error while extracting config", errGetTargetCfg)
-		return nil, errGetTargetCfg
+		return nil, 0, errGetTargetCfg
 	}
 
 	stateValues := manager.GetManager().GetTargetState(target, pathAsString)
 	//Merging the two results
 	configValues = append(configValues, stateValues...)
+	for _, info := range manager.GetManager().GetTargetStateInfo(target, pathAsString) {
+		if info.Timestamp < timestamp {
+			timestamp = info.Timestamp
+		}
+	}
 
-	return buildUpdate(prefix, path, configValues, encoding)
+	updates, err := buildUpdate(prefix, path, configValues, encoding)
+	return updates, timestamp, err
 }
 
 func buildUpdate(prefix *gnmi.Path, path *gnmi.Path, configValues []*devicechange.PathValue, encoding gnmi.Encoding) ([]*gnmi.Update, error) {
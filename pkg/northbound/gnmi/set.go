@@ -24,6 +24,7 @@ import (
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
 	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/onosproject/onos-config/pkg/alarms"
 	"github.com/onosproject/onos-config/pkg/manager"
 	"github.com/onosproject/onos-config/pkg/modelregistry"
 	"github.com/onosproject/onos-config/pkg/modelregistry/jsonvalues"
@@ -382,6 +383,10 @@ func extractModelForTarget(target devicetype.ID,
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	// The internal alarms device has no model plugin
+	if a := manager.GetManager().Alarms; a != nil && devicetype.NewVersionedID(target, actualVersion) == a.DeviceID() {
+		return alarms.ReadWritePaths, nil
+	}
 	modelName := utils.ToModelName(actualType, actualVersion)
 	plugin, err := manager.GetManager().ModelRegistry.GetPlugin(modelName)
 	if err != nil {
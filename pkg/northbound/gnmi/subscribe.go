@@ -163,12 +163,12 @@ func (s *Server) collector(mgr *manager.Manager, version devicetype.Version, str
 			resChan <- result{success: false, err: err}
 		}
 		//We get the stated of the device, for each path we build an update and send it out.
-		updates, err := s.getUpdate(version, request.Prefix, sub.Path, gnmi.Encoding_PROTO)
+		updates, timestamp, err := s.getUpdate(version, request.Prefix, sub.Path, gnmi.Encoding_PROTO)
 		if err != nil {
 			log.Error("Error while collecting data for subscribe once or poll ", err)
 			resChan <- result{success: false, err: err}
 		}
-		response, errGet := buildUpdateResponse(updates)
+		response, errGet := buildUpdateResponse(updates, timestamp)
 		if errGet != nil {
 			log.Error("Error Retrieving Device", err)
 			resChan <- result{success: false, err: err}
@@ -230,7 +230,7 @@ func listenForDeviceUpdates(stream gnmi.GNMI_SubscribeServer, mgr *manager.Manag
 						continue
 					}
 					log.Infof("Subscribe notification for %s on %s with value %s", pathGnmi, target, value.Value)
-					err = buildAndSendUpdate(pathGnmi, string(target), value.Value, value.Removed, stream)
+					err = buildAndSendUpdate(pathGnmi, string(target), value.Value, value.Removed, time.Now().UnixNano(), stream)
 					if err != nil {
 						log.Error("Error in sending update path ", err)
 						resChan <- result{success: false, err: err}
@@ -256,7 +256,8 @@ func listenForOpStateUpdates(opStateChan chan events.OperationalStateEvent, stre
 				continue
 			}
 
-			err = buildAndSendUpdate(pathGnmi, target, opStateChange.Value(), len(opStateChange.Value().Bytes) == 0, stream)
+			err = buildAndSendUpdate(pathGnmi, target, opStateChange.Value(), opStateChange.ItemAction() == events.EventItemDeleted,
+				opStateChange.Timestamp(), stream)
 			if err != nil {
 				log.Error("Error in sending update path ", err)
 				resChan <- result{success: false, err: err}
@@ -275,13 +276,13 @@ func matchRegex(path string, subs []*regexp.Regexp) bool {
 }
 
 func buildAndSendUpdate(pathGnmi *gnmi.Path, target string, value *devicechange.TypedValue, removed bool,
-	stream gnmi.GNMI_SubscribeServer) error {
+	timestamp int64, stream gnmi.GNMI_SubscribeServer) error {
 	pathGnmi.Target = target
 	var response *gnmi.SubscribeResponse
 	var errGet error
 	//if removed we issue a delete notification
 	if removed {
-		response, errGet = buildDeleteResponse(pathGnmi)
+		response, errGet = buildDeleteResponse(pathGnmi, timestamp)
 	} else {
 		valueGnmi, err := values.NativeTypeToGnmiTypedValue(value)
 		if err != nil {
@@ -295,7 +296,7 @@ func buildAndSendUpdate(pathGnmi *gnmi.Path, target string, value *devicechange.
 		}
 		updates := make([]*gnmi.Update, 1)
 		updates[0] = update
-		response, errGet = buildUpdateResponse(updates)
+		response, errGet = buildUpdateResponse(updates, timestamp)
 	}
 	if errGet != nil {
 		return errGet
@@ -318,18 +319,18 @@ func buildSyncResponse() *gnmi.SubscribeResponse {
 	}
 }
 
-func buildUpdateResponse(updates []*gnmi.Update) (*gnmi.SubscribeResponse, error) {
+func buildUpdateResponse(updates []*gnmi.Update, timestamp int64) (*gnmi.SubscribeResponse, error) {
 	notification := &gnmi.Notification{
-		Timestamp: time.Now().Unix(),
+		Timestamp: timestamp,
 		Update:    updates,
 	}
 	return buildSubscribeResponse(notification)
 }
 
-func buildDeleteResponse(delete *gnmi.Path) (*gnmi.SubscribeResponse, error) {
+func buildDeleteResponse(delete *gnmi.Path, timestamp int64) (*gnmi.SubscribeResponse, error) {
 	deleteArray := []*gnmi.Path{delete}
 	notification := &gnmi.Notification{
-		Timestamp: time.Now().Unix(),
+		Timestamp: timestamp,
 		Delete:    deleteArray,
 	}
 	return buildSubscribeResponse(notification)
@@ -41,52 +41,90 @@ var log = logging.GetLogger("southbound")
 var Targets = make(map[topodevice.ID]TargetIf)
 var targetMu = &sync.RWMutex{}
 
-func createDestination(device topodevice.Device) (*client.Destination, topodevice.ID) {
+// pemPrefix identifies TLS attributes that hold PEM encoded material rather than a file name
+const pemPrefix = "-----BEGIN"
+
+// createDestination builds the destination of a device. The TLS mode of the connection,
+// plain, insecure, server verification or mutual TLS, and the credentials sent as gNMI
+// metadata are configured independently of each other
+func createDestination(device topodevice.Device) (*client.Destination, topodevice.ID, error) {
 	d := &client.Destination{}
 	d.Addrs = []string{device.Address}
 	d.Target = device.Target
 	if device.Timeout != nil {
 		d.Timeout = *device.Timeout
 	}
+
+	if device.Credentials.Password != "" && device.Credentials.User == "" {
+		return nil, device.ID, fmt.Errorf("device %s has a password but no user", device.ID)
+	}
+	if device.Credentials.User != "" {
+		d.Credentials = &client.Credentials{
+			Username: device.Credentials.User,
+			Password: device.Credentials.Password,
+		}
+	}
+
+	tlsConfig, err := createTLSConfig(device)
+	if err != nil {
+		return nil, device.ID, err
+	}
+	d.TLS = tlsConfig
+	return d, device.ID, nil
+}
+
+// createTLSConfig returns the TLS configuration of a device, nil if it is reached over plaintext
+func createTLSConfig(device topodevice.Device) (*tls.Config, error) {
+	hasClientCert := device.TLS.Cert != "" || device.TLS.Key != ""
 	if device.TLS.Plain {
+		if device.TLS.Insecure || device.TLS.CaCert != "" || hasClientCert {
+			return nil, fmt.Errorf("device %s uses a plaintext connection but has TLS settings", device.ID)
+		}
+		if device.Credentials.User != "" {
+			log.Warnf("Credentials of %s are sent over a plaintext connection", device.ID)
+		}
 		log.Info("Plain (non TLS) connection connection to ", device.Address)
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if device.TLS.Insecure {
+		if device.TLS.CaCert != "" {
+			log.Warnf("CA certificate of %s is not used as the server is not verified", device.ID)
+		}
+		log.Info("Insecure TLS connection to ", device.Address)
+		tlsConfig.InsecureSkipVerify = true
+	} else if device.TLS.CaCert == "" {
+		log.Info("Secure TLS connection to ", device.Address, " verified with default CA onfca")
+		tlsConfig.RootCAs = getCertPoolDefault()
 	} else {
-		d.TLS = &tls.Config{}
-		if device.TLS.Insecure {
-			log.Info("Insecure TLS connection to ", device.Address)
-			d.TLS = &tls.Config{InsecureSkipVerify: true}
-		} else {
-			log.Info("Secure TLS connection to ", device.Address)
+		log.Info("Secure TLS connection to ", device.Address)
+		certPool, err := getCertPool(device.TLS.CaCert)
+		if err != nil {
+			return nil, fmt.Errorf("could not load CA certificate of %s: %v", device.ID, err)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if hasClientCert {
+		if device.TLS.Cert == "" || device.TLS.Key == "" {
+			return nil, fmt.Errorf("device %s needs both a client certificate and a key", device.ID)
 		}
-		if device.TLS.CaCert == "" {
-			log.Info("Loading default CA onfca")
-			d.TLS.RootCAs = getCertPoolDefault()
-		} else {
-			d.TLS.RootCAs = getCertPool(device.TLS.CaCert)
+		certificate, err := getCertificate(device.TLS.Cert, device.TLS.Key)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate of %s: %v", device.ID, err)
 		}
-		if device.TLS.Cert == "" && device.TLS.Key == "" {
-			// Load default Certificates
-			log.Info("Loading default certificates")
-			clientCerts, err := tls.X509KeyPair([]byte(certs.DefaultClientCrt), []byte(certs.DefaultClientKey))
-			if err != nil {
-				log.Error("Error loading default certs")
-			}
-			d.TLS.Certificates = []tls.Certificate{clientCerts}
-		} else if device.TLS.Cert != "" && device.TLS.Key != "" {
-			// Load certs given for device
-			d.TLS.Certificates = []tls.Certificate{setCertificate(device.TLS.Cert, device.TLS.Key)}
-		} else if device.Credentials.User != "" && device.Credentials.Password != "" {
-			cred := &client.Credentials{}
-			cred.Username = device.Credentials.User
-			cred.Password = device.Credentials.Password
-			d.Credentials = cred
-		} else {
-			log.Errorf("Can't load Ca=%s , Cert=%s , key=%s for %v, trying with insecure connection",
-				device.TLS.CaCert, device.TLS.Cert, device.TLS.Key, device.Address)
-			d.TLS = &tls.Config{InsecureSkipVerify: true}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	} else if device.Credentials.User == "" {
+		// Devices with neither a client certificate nor credentials are authenticated with the default one
+		log.Info("Loading default certificates")
+		certificate, err := tls.X509KeyPair([]byte(certs.DefaultClientCrt), []byte(certs.DefaultClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("could not load default client certificate: %v", err)
 		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
 	}
-	return d, device.ID
+	return tlsConfig, nil
 }
 
 // GetTarget attempts to get a specific target from the targets cache
@@ -102,9 +140,11 @@ func GetTarget(key topodevice.ID) (TargetIf, error) {
 
 // ConnectTarget connects to a given Device according to the passed information establishing a channel to it.
 //TODO make asyc
-//TODO lock channel to allow one request to device at each time
 func (target *Target) ConnectTarget(ctx context.Context, device topodevice.Device) (topodevice.ID, error) {
-	dest, key := createDestination(device)
+	dest, key, err := createDestination(device)
+	if err != nil {
+		return "", err
+	}
 	c, err := GnmiClientFactory(ctx, *dest)
 
 	//c.handler := client.NotificationHandler{}
@@ -112,12 +152,10 @@ func (target *Target) ConnectTarget(ctx context.Context, device topodevice.Devic
 		return "", fmt.Errorf("could not create a gNMI client: %v", err)
 	}
 
+	// Swap the new client in before closing the old one so that a target
+	// connected again, e.g. with new credentials, is never without a client
 	target.mu.Lock()
-	if target.clt != nil {
-		log.Infof("Closing connection to %v", key)
-		target.clt.Close()
-	}
-
+	oldClt := target.clt
 	target.dest = *dest
 	target.clt = c
 	target.ctx = ctx
@@ -126,27 +164,58 @@ func (target *Target) ConnectTarget(ctx context.Context, device topodevice.Devic
 	targetMu.Lock()
 	Targets[key] = target
 	targetMu.Unlock()
-	return key, err
+
+	if oldClt != nil {
+		log.Infof("Closing previous connection to %v", key)
+		if err := oldClt.Close(); err != nil {
+			log.Warnf("Closing previous connection to %v failed: %v", key, err)
+		}
+	}
+	return key, nil
+}
+
+// CertificateFiles returns the files the TLS material of a device is read from
+func CertificateFiles(device topodevice.Device) []string {
+	files := make([]string, 0, 3)
+	for _, attribute := range []string{device.TLS.CaCert, device.TLS.Cert, device.TLS.Key} {
+		if attribute != "" && !strings.HasPrefix(strings.TrimSpace(attribute), pemPrefix) {
+			files = append(files, attribute)
+		}
+	}
+	return files
+}
+
+// readPEM returns the PEM encoded material of a TLS attribute, reading it from a file
+// unless the attribute holds the material itself
+func readPEM(attribute string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(attribute), pemPrefix) {
+		return []byte(attribute), nil
+	}
+	return ioutil.ReadFile(attribute)
 }
 
-func setCertificate(pathCert string, pathKey string) tls.Certificate {
-	certificate, err := tls.LoadX509KeyPair(pathCert, pathKey)
+func getCertificate(cert string, key string) (tls.Certificate, error) {
+	certPEM, err := readPEM(cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := readPEM(key)
 	if err != nil {
-		log.Error("could not load client key pair ", err)
+		return tls.Certificate{}, err
 	}
-	return certificate
+	return tls.X509KeyPair(certPEM, keyPEM)
 }
 
-func getCertPool(CaPath string) *x509.CertPool {
-	certPool := x509.NewCertPool()
-	ca, err := ioutil.ReadFile(CaPath)
+func getCertPool(caCert string) (*x509.CertPool, error) {
+	ca, err := readPEM(caCert)
 	if err != nil {
-		log.Error("could not read ", CaPath, err)
+		return nil, err
 	}
+	certPool := x509.NewCertPool()
 	if ok := certPool.AppendCertsFromPEM(ca); !ok {
-		log.Error("failed to append CA certificates")
+		return nil, errors.New("failed to append CA certificates")
 	}
-	return certPool
+	return certPool, nil
 }
 
 func getCertPoolDefault() *x509.CertPool {
@@ -211,11 +280,20 @@ func (target *Target) SetWithString(ctx context.Context, request string) (*gpb.S
 	return target.Set(ctx, r)
 }
 
-// Set can make a set request according to a formatted request
+// Set can make a set request according to a formatted request. Set requests to a target
+// are sent one at a time, and each is bounded by the timeout of the device
 func (target *Target) Set(ctx context.Context, request *gpb.SetRequest) (*gpb.SetResponse, error) {
+	done, err := target.enqueue(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("waiting to send Set(%q) : %w", request.String(), err)
+	}
+	defer done()
+
+	ctx, cancel := target.withTimeout(ctx)
+	defer cancel()
 	response, err := target.Client().Set(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("target returned RPC error for Set(%q) : %v", request.String(), err)
+		return nil, fmt.Errorf("target returned RPC error for Set(%q) : %w", request.String(), err)
 	}
 	return response, nil
 }
@@ -230,11 +308,15 @@ func (target *Target) Subscribe(ctx context.Context, request *gpb.SubscribeReque
 	if err != nil {
 		return err
 	}
-	q.Addrs = target.Destination().Addrs
-	q.Timeout = target.Destination().Timeout
-	q.Target = target.Destination().Target
-	q.Credentials = target.Destination().Credentials
-	q.TLS = target.Destination().TLS
+	// The destination is replaced when the target is connected again
+	target.mu.RLock()
+	dest := target.dest
+	target.mu.RUnlock()
+	q.Addrs = dest.Addrs
+	q.Timeout = dest.Timeout
+	q.Target = dest.Target
+	q.Credentials = dest.Credentials
+	q.TLS = dest.TLS
 	q.ProtoHandler = handler
 	c := GnmiBaseClientFactory()
 	err = c.Subscribe(ctx, q, "gnmi")
@@ -244,9 +326,13 @@ func (target *Target) Subscribe(ctx context.Context, request *gpb.SubscribeReque
 	return err
 }
 
-// Context allows retrieval of the context for the target
+// Context allows retrieval of the context for the target. The context is copied as
+// connecting the target again replaces it
 func (target *Target) Context() *context.Context {
-	return &target.ctx
+	target.mu.RLock()
+	defer target.mu.RUnlock()
+	ctx := target.ctx
+	return &ctx
 }
 
 // Destination allows retrieval of the context for the target
@@ -317,6 +403,7 @@ func NewSubscribeRequest(subscribeOptions *SubscribeOptions) (*gpb.SubscribeRequ
 			Mode:              streamMode,
 			SampleInterval:    subscribeOptions.SampleInterval,
 			HeartbeatInterval: subscribeOptions.HeartbeatInterval,
+			SuppressRedundant: subscribeOptions.SuppressRedundant,
 		}
 	}
 	return &gpb.SubscribeRequest{Request: &gpb.SubscribeRequest_Subscribe{
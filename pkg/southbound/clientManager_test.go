@@ -16,12 +16,17 @@ package southbound
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/golang/protobuf/proto"
 	topodevice "github.com/onosproject/onos-config/pkg/device"
 	"github.com/onosproject/onos-config/pkg/utils"
 	"github.com/openconfig/gnmi/client"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
 	"strconv"
 	"testing"
 	"time"
@@ -174,8 +179,6 @@ func Test_BadTarget(t *testing.T) {
 func Test_ConnectTargetUserPassword(t *testing.T) {
 	setUp(t)
 
-	device.TLS.Cert = "cert path"
-	device.TLS.Key = ""
 	device.Credentials.User = "User"
 	device.Credentials.Password = "Password"
 	target, key, _ := getDevice1Target(t)
@@ -184,22 +187,67 @@ func Test_ConnectTargetUserPassword(t *testing.T) {
 	assert.NoError(t, fetchError)
 	assert.Equal(t, target.Destination().Credentials.Username, "User")
 	assert.Equal(t, target.Destination().Credentials.Password, "Password")
+	// Server only TLS, the default client certificate is not sent along with credentials
+	assert.Equal(t, targetFetch.Destination().TLS.InsecureSkipVerify, false)
+	assert.Len(t, targetFetch.Destination().TLS.Certificates, 0)
 	assert.Equal(t, target.clt, targetFetch.Client())
 
 	tearDown()
 }
 
-func Test_ConnectTargetInsecurePaths(t *testing.T) {
+func Test_ConnectTargetCertAndUserPassword(t *testing.T) {
+	setUp(t)
+
+	device.TLS.Cert = "testdata/client1.crt"
+	device.TLS.Key = "testdata/client1.key"
+	device.TLS.CaCert = "testdata/onfca.crt"
+	target, _, _ := getDevice1Target(t)
+
+	assert.Equal(t, target.Destination().Credentials.Username, "devicesim")
+	assert.Len(t, target.Destination().TLS.Certificates, 1)
+
+	tearDown()
+}
+
+func Test_ConnectTargetInvalidTLS(t *testing.T) {
 	setUp(t)
 
+	// A certificate needs a key
+	device.TLS.Cert = "testdata/client1.crt"
+	_, err := (&Target{}).ConnectTarget(context.Background(), device)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "both a client certificate and a key")
+
+	// Plaintext connections have no TLS settings
+	device.TLS.Key = "testdata/client1.key"
+	device.TLS.Plain = true
+	_, err = (&Target{}).ConnectTarget(context.Background(), device)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "plaintext")
+
+	// Certificates that cannot be loaded are not replaced by an insecure connection
+	device.TLS.Plain = false
 	device.TLS.Cert = "cert path"
-	device.TLS.Key = ""
-	target, key, _ := getDevice1Target(t)
+	_, err = (&Target{}).ConnectTarget(context.Background(), device)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "could not load client certificate")
 
-	targetFetch, fetchError := GetTarget(key)
-	assert.NoError(t, fetchError)
-	assert.Equal(t, targetFetch.Destination().TLS.InsecureSkipVerify, false)
-	assert.Equal(t, target.clt, targetFetch.Client())
+	// A password needs a user
+	device.TLS = topodevice.TLSConfig{}
+	device.Credentials.User = ""
+	_, err = (&Target{}).ConnectTarget(context.Background(), device)
+	assert.Error(t, err)
+
+	tearDown()
+}
+
+func Test_ConnectTargetPlain(t *testing.T) {
+	setUp(t)
+
+	device.TLS.Plain = true
+	target, _, _ := getDevice1Target(t)
+	assert.Nil(t, target.Destination().TLS)
+	assert.Equal(t, target.Destination().Credentials.Username, "devicesim")
 
 	tearDown()
 }
@@ -228,15 +276,38 @@ func Test_ConnectTargetWithCert(t *testing.T) {
 
 	targetFetch, fetchError := GetTarget(key)
 	assert.NoError(t, fetchError)
-	ca := getCertPool("testdata/onfca.crt")
+	ca, err := getCertPool("testdata/onfca.crt")
+	assert.NoError(t, err)
 	assert.Equal(t, targetFetch.Destination().TLS.RootCAs.Subjects()[0], ca.Subjects()[0])
-	cert := setCertificate("testdata/client1.crt", "testdata/client1.key")
+	cert, err := getCertificate("testdata/client1.crt", "testdata/client1.key")
+	assert.NoError(t, err)
 	assert.Equal(t, targetFetch.Destination().TLS.Certificates[0].Certificate, cert.Certificate)
 	assert.Equal(t, target.clt, targetFetch.Client())
 
 	tearDown()
 }
 
+func Test_ConnectTargetWithPEM(t *testing.T) {
+	setUp(t)
+
+	certPEM, err := ioutil.ReadFile("testdata/client1.crt")
+	assert.NoError(t, err)
+	keyPEM, err := ioutil.ReadFile("testdata/client1.key")
+	assert.NoError(t, err)
+	caPEM, err := ioutil.ReadFile("testdata/onfca.crt")
+	assert.NoError(t, err)
+	device.TLS.Cert = string(certPEM)
+	device.TLS.Key = string(keyPEM)
+	device.TLS.CaCert = string(caPEM)
+	target, _, _ := getDevice1Target(t)
+
+	cert, err := getCertificate("testdata/client1.crt", "testdata/client1.key")
+	assert.NoError(t, err)
+	assert.Equal(t, target.Destination().TLS.Certificates[0].Certificate, cert.Certificate)
+
+	tearDown()
+}
+
 func Test_Get(t *testing.T) {
 	setUp(t)
 
@@ -426,3 +497,34 @@ func Test_SetWithString(t *testing.T) {
 
 	tearDown()
 }
+
+func Test_SetQueued(t *testing.T) {
+	setUp(t)
+
+	target, _, ctx := getDevice1Target(t)
+
+	// A Set waiting behind another request gives up when its context is done
+	done, err := target.enqueue(ctx)
+	assert.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, setErr := target.Set(waitCtx, &gnmi.SetRequest{})
+	assert.Error(t, setErr)
+	assert.True(t, IsRetryable(setErr))
+
+	done()
+	setResponse, setErr := target.Set(ctx, &gnmi.SetRequest{})
+	assert.NoError(t, setErr)
+	assert.NotNil(t, setResponse)
+
+	tearDown()
+}
+
+func Test_IsRetryable(t *testing.T) {
+	assert.False(t, IsRetryable(nil))
+	assert.False(t, IsRetryable(errors.New("unknown")))
+	assert.True(t, IsRetryable(fmt.Errorf("wrapped: %w", status.Error(codes.Unavailable, "unavailable"))))
+	assert.False(t, IsRetryable(context.DeadlineExceeded))
+	assert.False(t, IsRetryable(status.Error(codes.DeadlineExceeded, "deadline exceeded")))
+	assert.False(t, IsRetryable(status.Error(codes.InvalidArgument, "invalid path")))
+}
@@ -44,10 +44,11 @@ type TargetIf interface {
 
 // Target struct for connecting to gNMI
 type Target struct {
-	dest client.Destination
-	clt  GnmiClient
-	ctx  context.Context
-	mu   sync.RWMutex
+	dest     client.Destination
+	clt      GnmiClient
+	ctx      context.Context
+	requests chan struct{}
+	mu       sync.RWMutex
 }
 
 // NewTarget is a method for constructing a target
@@ -63,6 +64,7 @@ type SubscribeOptions struct {
 	StreamMode        string
 	SampleInterval    uint64
 	HeartbeatInterval uint64
+	SuppressRedundant bool
 	Paths             [][]string
 	Origin            string
 }
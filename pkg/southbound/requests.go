@@ -0,0 +1,76 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package southbound
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requestQueue returns the queue that lets one Set request at a time through to the target
+func (target *Target) requestQueue() chan struct{} {
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if target.requests == nil {
+		target.requests = make(chan struct{}, 1)
+	}
+	return target.requests
+}
+
+// enqueue waits for the previous requests to the target to complete. The returned
+// function must be called to let the next request through. A request that gives up
+// waiting was never sent, so the target is reported as unavailable
+func (target *Target) enqueue(ctx context.Context) (func(), error) {
+	queue := target.requestQueue()
+	select {
+	case queue <- struct{}{}:
+		return func() { <-queue }, nil
+	case <-ctx.Done():
+		return nil, status.Error(codes.Unavailable, ctx.Err().Error())
+	}
+}
+
+// withTimeout bounds the context of a request to the target by the timeout of the device
+func (target *Target) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	target.mu.RLock()
+	timeout := target.dest.Timeout
+	target.mu.RUnlock()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// IsRetryable indicates whether a request to a target that failed with the given error
+// may succeed if it is sent again. Errors the device returned for the request itself,
+// such as an invalid path or value, are permanent. A request that timed out may have been
+// applied by the device, and Set requests are not idempotent, so timeouts are permanent too
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"strings"
+
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+)
+
+const (
+	// addressesKey is the device attribute listing, in order of preference and separated
+	// by commas, the addresses the device can be reached at
+	addressesKey = "onos-config.addresses"
+	// activeAddressKey is the device attribute reporting the address the device is connected at
+	activeAddressKey = "onos-config.address.active"
+)
+
+// getAddresses returns the addresses of a device in the order they are tried. The
+// address of the device comes first unless the addresses attribute lists it
+func getAddresses(device *topodevice.Device) []string {
+	addresses := make([]string, 0)
+	for _, address := range strings.Split(device.Attributes[addressesKey], ",") {
+		address = strings.TrimSpace(address)
+		if address != "" && !containsAddress(addresses, address) {
+			addresses = append(addresses, address)
+		}
+	}
+	if device.Address != "" && !containsAddress(addresses, device.Address) {
+		addresses = append([]string{device.Address}, addresses...)
+	}
+	return addresses
+}
+
+func containsAddress(addresses []string, address string) bool {
+	return indexOfAddress(addresses, address) >= 0
+}
+
+func indexOfAddress(addresses []string, address string) int {
+	for i, a := range addresses {
+		if a == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// addressesChanged indicates whether the addresses of a device changed
+func addressesChanged(device *topodevice.Device, updated *topodevice.Device) bool {
+	return device.Address != updated.Address || device.Attributes[addressesKey] != updated.Attributes[addressesKey]
+}
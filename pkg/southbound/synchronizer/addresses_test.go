@@ -0,0 +1,53 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"gotest.tools/assert"
+	"testing"
+)
+
+func Test_getAddresses(t *testing.T) {
+	device := &topodevice.Device{ID: device1, Address: "10.0.0.1:9339"}
+	assert.DeepEqual(t, getAddresses(device), []string{"10.0.0.1:9339"})
+
+	device.Attributes = map[string]string{addressesKey: "10.0.1.1:9339, 10.0.0.1:9339,,10.0.1.1:9339"}
+	assert.DeepEqual(t, getAddresses(device), []string{"10.0.1.1:9339", "10.0.0.1:9339"})
+
+	device.Attributes[addressesKey] = "10.0.1.1:9339,10.0.2.1:9339"
+	assert.DeepEqual(t, getAddresses(device), []string{"10.0.0.1:9339", "10.0.1.1:9339", "10.0.2.1:9339"})
+
+	updated := &topodevice.Device{ID: device1, Address: "10.0.0.1:9339", Attributes: map[string]string{
+		addressesKey:     "10.0.1.1:9339,10.0.2.1:9339",
+		activeAddressKey: "10.0.1.1:9339",
+	}}
+	assert.Assert(t, !addressesChanged(device, updated))
+	updated.Attributes[addressesKey] = "10.0.2.1:9339"
+	assert.Assert(t, addressesChanged(device, updated))
+}
+
+func Test_getAddress(t *testing.T) {
+	device := &topodevice.Device{ID: device1, Address: "10.0.0.1:9339"}
+	session := &Session{device: device}
+	assert.Equal(t, session.getAddress(), "10.0.0.1:9339")
+
+	// Failed attempts move on to the next address and wrap around
+	session.addresses = []string{"10.0.0.1:9339", "10.0.1.1:9339"}
+	session.addressIndex = 1
+	assert.Equal(t, session.getAddress(), "10.0.1.1:9339")
+	session.addressIndex++
+	assert.Equal(t, session.getAddress(), "10.0.0.1:9339")
+}
@@ -0,0 +1,136 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"strconv"
+	"strings"
+
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// selectPlugin chooses the model plugin matching the models a device advertises in its
+// capabilities. A plugin matches if the device advertises all of its modules at the same
+// versions. Among the matching plugins, one whose modules are exactly those the device
+// advertises comes first, then the one covering the most modules, then the one with the
+// highest version. It returns nil if no plugin matches
+func selectPlugin(response *gnmi.CapabilityResponse, plugins []*modelregistry.ModelPlugin) *modelregistry.ModelPlugin {
+	if response == nil || len(response.SupportedModels) == 0 {
+		return nil
+	}
+	advertised := make(map[string]string)
+	for _, model := range response.SupportedModels {
+		advertised[model.Name] = model.Version
+	}
+
+	var selected *modelregistry.ModelPlugin
+	var selectedExact bool
+	var selectedModules int
+	for _, plugin := range plugins {
+		models := plugin.Model.Data()
+		if len(models) == 0 || !newDeviceCapabilities(response, models).Compatible() {
+			continue
+		}
+		exact := len(models) == len(advertised)
+		better := selected == nil
+		if !better && exact != selectedExact {
+			better = exact
+		} else if !better && len(models) != selectedModules {
+			better = len(models) > selectedModules
+		} else if !better {
+			if c := compareVersions(string(plugin.Info.Version), string(selected.Info.Version)); c != 0 {
+				better = c > 0
+			} else {
+				better = plugin.Info.Name < selected.Info.Name
+			}
+		}
+		if better {
+			selected, selectedExact, selectedModules = plugin, exact, len(models)
+		}
+	}
+	return selected
+}
+
+// compareVersions compares two dotted versions numerically where their parts are numbers
+func compareVersions(a string, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart string
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		aNumber, aErr := strconv.Atoi(aPart)
+		bNumber, bErr := strconv.Atoi(bPart)
+		switch {
+		case aErr == nil && bErr == nil && aNumber != bNumber:
+			if aNumber > bNumber {
+				return 1
+			}
+			return -1
+		case (aErr != nil || bErr != nil) && aPart != bPart:
+			if aPart > bPart {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// needsModelDetection indicates whether the model of a device is to be detected from
+// its capabilities, as its type or version is not set
+func needsModelDetection(device *topodevice.Device) bool {
+	return device.Type == "" || device.Version == ""
+}
+
+// modelChanged indicates whether the type or version of a device is changed
+func modelChanged(device *topodevice.Device, updated *topodevice.Device) bool {
+	return device.Type != updated.Type || device.Version != updated.Version
+}
+
+// detectModel chooses the model plugin of the device from its capabilities and sets its
+// type and version in topo. The update of the device restarts the session with the plugin
+func (s *Session) detectModel(capabilities *gnmi.CapabilityResponse) {
+	plugins, err := s.modelRegistry.GetPlugins()
+	if err != nil {
+		log.Warnf("Detecting the model of %s failed: %v", s.device.ID, err)
+		return
+	}
+	plugin := selectPlugin(capabilities, plugins)
+	if plugin == nil {
+		log.Warnf("No model plugin matches the capabilities of %s", s.device.ID)
+		return
+	}
+	log.Infof("Detected model %s %s for device %s", plugin.Info.Name, plugin.Info.Version, s.device.ID)
+
+	device, err := s.deviceStore.Get(s.device.ID)
+	if err != nil {
+		log.Warnf("Setting the model of %s failed: %v", s.device.ID, err)
+		return
+	}
+	if !needsModelDetection(device) {
+		return
+	}
+	device.Type = topodevice.Type(plugin.Info.Name)
+	device.Version = string(plugin.Info.Version)
+	if _, err := s.deviceStore.Update(device); err != nil {
+		log.Warnf("Setting the model of %s failed: %v", s.device.ID, err)
+	}
+}
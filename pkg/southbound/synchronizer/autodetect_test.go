@@ -0,0 +1,89 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"testing"
+
+	configmodel "github.com/onosproject/onos-config-model/pkg/model"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"gotest.tools/assert"
+)
+
+// testModel is a config model with only its model data
+type testModel struct {
+	configmodel.ConfigModel
+	data []*gnmi.ModelData
+}
+
+func (m testModel) Data() []*gnmi.ModelData {
+	return m.data
+}
+
+func newTestPlugin(name string, version string, modules ...string) *modelregistry.ModelPlugin {
+	data := make([]*gnmi.ModelData, 0, len(modules)/2)
+	for i := 0; i+1 < len(modules); i += 2 {
+		data = append(data, &gnmi.ModelData{Name: modules[i], Version: modules[i+1]})
+	}
+	return &modelregistry.ModelPlugin{
+		Info: configmodel.ModelInfo{
+			Name:    configmodel.Name(name),
+			Version: configmodel.Version(version),
+		},
+		Model: testModel{data: data},
+	}
+}
+
+func Test_selectPlugin(t *testing.T) {
+	response := &gnmi.CapabilityResponse{
+		SupportedModels: []*gnmi.ModelData{
+			{Name: "openconfig-interfaces", Version: "2.4.3"},
+			{Name: "openconfig-system", Version: "0.9.1"},
+		},
+	}
+	interfaces := newTestPlugin("Interfaces", "1.0.0", "openconfig-interfaces", "2.4.3")
+	devicesim1 := newTestPlugin("Devicesim", "1.0.0", "openconfig-interfaces", "2.4.3", "openconfig-system", "0.9.1")
+	devicesim2 := newTestPlugin("Devicesim", "2.0.0", "openconfig-interfaces", "2.4.3", "openconfig-system", "0.9.1")
+	devicesim10 := newTestPlugin("Devicesim", "10.0.0", "openconfig-interfaces", "2.4.3", "openconfig-system", "0.9.1")
+	stratum := newTestPlugin("Stratum", "1.0.0", "openconfig-interfaces", "2.4.3", "openconfig-platform", "0.12.2")
+	oldSystem := newTestPlugin("Devicesim", "0.1.0", "openconfig-interfaces", "2.4.3", "openconfig-system", "0.7.0")
+
+	// An exact module set comes before a partial one
+	assert.Equal(t, selectPlugin(response, []*modelregistry.ModelPlugin{interfaces, devicesim1}), devicesim1)
+	// The highest version of the exact matches is chosen
+	assert.Equal(t, selectPlugin(response, []*modelregistry.ModelPlugin{devicesim2, devicesim10, devicesim1}), devicesim10)
+	// Plugins with modules the device does not advertise, or at other versions, don't match
+	assert.Equal(t, selectPlugin(response, []*modelregistry.ModelPlugin{stratum, oldSystem, interfaces}), interfaces)
+	assert.Assert(t, selectPlugin(response, []*modelregistry.ModelPlugin{stratum, oldSystem}) == nil)
+	assert.Assert(t, selectPlugin(nil, []*modelregistry.ModelPlugin{devicesim1}) == nil)
+}
+
+func Test_compareVersions(t *testing.T) {
+	assert.Equal(t, compareVersions("1.0.0", "1.0.0"), 0)
+	assert.Equal(t, compareVersions("10.0.0", "2.0.0"), 1)
+	assert.Equal(t, compareVersions("1.0", "1.0.1"), -1)
+	assert.Equal(t, compareVersions("1.0.0-beta", "1.0.0-alpha"), 1)
+}
+
+func Test_modelChanged(t *testing.T) {
+	device := &topodevice.Device{ID: device1}
+	assert.Assert(t, needsModelDetection(device))
+	updated := &topodevice.Device{ID: device1, Type: "Devicesim", Version: "1.0.0"}
+	assert.Assert(t, !needsModelDetection(updated))
+	assert.Assert(t, modelChanged(device, updated))
+	assert.Assert(t, !modelChanged(updated, updated))
+}
@@ -0,0 +1,97 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// ModelMismatch is a module of the model plugin of a device that the device does not
+// advertise in its capabilities, or advertises at another version
+type ModelMismatch struct {
+	// Name is the name of the module
+	Name string
+	// Version is the version of the module in the model plugin
+	Version string
+	// Advertised is the version the device advertises, empty if it does not advertise the module
+	Advertised string
+}
+
+func (m ModelMismatch) String() string {
+	if m.Advertised == "" {
+		return fmt.Sprintf("%s %s not advertised", m.Name, m.Version)
+	}
+	return fmt.Sprintf("%s %s advertised as %s", m.Name, m.Version, m.Advertised)
+}
+
+// DeviceCapabilities are the capabilities a device advertised when it was last connected
+type DeviceCapabilities struct {
+	// Response is the capabilities response of the device
+	Response *gnmi.CapabilityResponse
+	// Mismatches are the modules of the model plugin the device does not advertise as such
+	Mismatches []ModelMismatch
+	// Timestamp is when the capabilities were fetched
+	Timestamp time.Time
+}
+
+// Compatible indicates whether the device advertises all the modules of its model plugin
+func (c *DeviceCapabilities) Compatible() bool {
+	return len(c.Mismatches) == 0
+}
+
+// mismatchSummary returns the mismatches in the form of a device attribute value
+func (c *DeviceCapabilities) mismatchSummary() string {
+	mismatches := make([]string, 0, len(c.Mismatches))
+	for _, mismatch := range c.Mismatches {
+		mismatches = append(mismatches, mismatch.String())
+	}
+	return strings.Join(mismatches, ", ")
+}
+
+// newDeviceCapabilities compares the models advertised in a capabilities response with
+// the modules of the model plugin of the device. If there is no plugin, nothing is compared
+func newDeviceCapabilities(response *gnmi.CapabilityResponse, pluginModels []*gnmi.ModelData) *DeviceCapabilities {
+	capabilities := &DeviceCapabilities{
+		Response:   response,
+		Mismatches: make([]ModelMismatch, 0),
+		Timestamp:  time.Now(),
+	}
+	advertised := make(map[string]string)
+	if response != nil {
+		for _, model := range response.SupportedModels {
+			advertised[model.Name] = model.Version
+		}
+	}
+	for _, model := range pluginModels {
+		version, ok := advertised[model.Name]
+		if !ok {
+			capabilities.Mismatches = append(capabilities.Mismatches, ModelMismatch{
+				Name:    model.Name,
+				Version: model.Version,
+			})
+		} else if version != "" && model.Version != "" && version != model.Version {
+			capabilities.Mismatches = append(capabilities.Mismatches, ModelMismatch{
+				Name:       model.Name,
+				Version:    model.Version,
+				Advertised: version,
+			})
+		}
+	}
+	return capabilities
+}
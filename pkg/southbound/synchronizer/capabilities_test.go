@@ -0,0 +1,56 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"gotest.tools/assert"
+)
+
+func Test_newDeviceCapabilities(t *testing.T) {
+	pluginModels := []*gnmi.ModelData{
+		{Name: "openconfig-interfaces", Organization: "OpenConfig working group", Version: "2.4.3"},
+		{Name: "openconfig-system", Organization: "OpenConfig working group", Version: "0.9.1"},
+		{Name: "openconfig-platform", Organization: "OpenConfig working group", Version: "0.12.2"},
+	}
+	response := &gnmi.CapabilityResponse{
+		SupportedModels: []*gnmi.ModelData{
+			{Name: "openconfig-interfaces", Organization: "OpenConfig working group", Version: "2.4.3"},
+			{Name: "openconfig-system", Organization: "OpenConfig working group", Version: "0.7.0"},
+			{Name: "openconfig-lldp", Organization: "OpenConfig working group", Version: "0.2.1"},
+		},
+		SupportedEncodings: []gnmi.Encoding{gnmi.Encoding_JSON},
+		GNMIVersion:        "0.7.0",
+	}
+
+	capabilities := newDeviceCapabilities(response, pluginModels)
+	assert.Assert(t, !capabilities.Compatible())
+	assert.Equal(t, len(capabilities.Mismatches), 2)
+	assert.Equal(t, capabilities.Mismatches[0].String(), "openconfig-system 0.9.1 advertised as 0.7.0")
+	assert.Equal(t, capabilities.Mismatches[1].String(), "openconfig-platform 0.12.2 not advertised")
+	assert.Equal(t, capabilities.mismatchSummary(),
+		"openconfig-system 0.9.1 advertised as 0.7.0, openconfig-platform 0.12.2 not advertised")
+
+	// Models the device advertises besides those of the plugin are compatible
+	capabilities = newDeviceCapabilities(response, pluginModels[:1])
+	assert.Assert(t, capabilities.Compatible())
+
+	// Without a plugin there is nothing to compare
+	capabilities = newDeviceCapabilities(response, nil)
+	assert.Assert(t, capabilities.Compatible())
+	assert.Equal(t, capabilities.Response.GNMIVersion, "0.7.0")
+}
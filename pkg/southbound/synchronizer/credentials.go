@@ -0,0 +1,103 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"time"
+
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+)
+
+// DefaultCredentialsCheckInterval is how often the certificate files of devices are checked for changes
+const DefaultCredentialsCheckInterval = time.Minute
+
+// credentialsWatcher detects changes to the certificate files a device connection is built from
+type credentialsWatcher struct {
+	files    func() []string
+	hashes   map[string][sha256.Size]byte
+	interval time.Duration
+	onChange func()
+}
+
+func newCredentialsWatcher(files func() []string, interval time.Duration, onChange func()) *credentialsWatcher {
+	w := &credentialsWatcher{
+		files:    files,
+		interval: interval,
+		onChange: onChange,
+	}
+	w.hashes = w.hashFiles()
+	return w
+}
+
+// run checks the files at every interval until the context is done
+func (w *credentialsWatcher) run(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check calls onChange if the content of any file changed since the last check.
+// Files that were not watched before are only recorded
+func (w *credentialsWatcher) check() bool {
+	hashes := w.hashFiles()
+	changed := false
+	for file, hash := range hashes {
+		if prev, ok := w.hashes[file]; ok && prev != hash {
+			log.Infof("Certificate file %s changed", file)
+			changed = true
+		}
+	}
+	w.hashes = hashes
+	if changed {
+		w.onChange()
+	}
+	return changed
+}
+
+// hashFiles hashes the content of the files. A file that cannot be read, e.g. while
+// it is being replaced, keeps its previous hash so it is picked up once it is complete
+func (w *credentialsWatcher) hashFiles() map[string][sha256.Size]byte {
+	hashes := make(map[string][sha256.Size]byte)
+	for _, file := range w.files() {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Warnf("Could not read certificate file %s: %v", file, err)
+			if prev, ok := w.hashes[file]; ok {
+				hashes[file] = prev
+			}
+			continue
+		}
+		hashes[file] = sha256.Sum256(content)
+	}
+	return hashes
+}
+
+// credentialsChanged indicates whether the TLS settings or credentials of a device changed
+func credentialsChanged(device *topodevice.Device, updated *topodevice.Device) bool {
+	return device.TLS != updated.TLS || device.Credentials != updated.Credentials
+}
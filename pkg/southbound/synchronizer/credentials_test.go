@@ -0,0 +1,88 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/test/mocks/southbound"
+	"gotest.tools/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_credentialsWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	assert.NilError(t, ioutil.WriteFile(certFile, []byte("cert-1"), 0600))
+
+	files := []string{certFile}
+	changes := 0
+	watcher := newCredentialsWatcher(func() []string { return files }, time.Minute, func() { changes++ })
+
+	assert.Assert(t, !watcher.check())
+
+	assert.NilError(t, ioutil.WriteFile(certFile, []byte("cert-2"), 0600))
+	assert.Assert(t, watcher.check())
+	assert.Equal(t, changes, 1)
+
+	// A file that is newly referenced is only recorded
+	assert.NilError(t, ioutil.WriteFile(keyFile, []byte("key-1"), 0600))
+	files = append(files, keyFile)
+	assert.Assert(t, !watcher.check())
+
+	// A file that cannot be read does not count as a change
+	assert.NilError(t, os.Remove(keyFile))
+	assert.Assert(t, !watcher.check())
+	assert.NilError(t, ioutil.WriteFile(keyFile, []byte("key-2"), 0600))
+	assert.Assert(t, watcher.check())
+	assert.Equal(t, changes, 2)
+}
+
+func Test_updateCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+
+	device := &topodevice.Device{ID: device1, Address: "device1:11161", Version: "1.0.0"}
+	updated := *device
+	updated.Credentials = topodevice.Credentials{User: "admin", Password: "rotated"}
+	assert.Assert(t, credentialsChanged(device, &updated))
+
+	mockTarget := southbound.NewMockTargetIf(ctrl)
+	mockTarget.EXPECT().Context().Return(&ctx).AnyTimes()
+	mockTarget.EXPECT().ConnectTarget(gomock.Any(), updated).Return(topodevice.ID(device1), nil).Times(1)
+
+	session := &Session{
+		device: device,
+		target: mockTarget,
+	}
+	// A session that is not synchronized picks the credentials up when it connects
+	assert.NilError(t, session.updateCredentials(&updated))
+
+	session.cancel = func() {}
+	session.resubscribe = make(chan struct{}, 1)
+	assert.NilError(t, session.updateCredentials(&updated))
+	assert.Equal(t, session.device.Credentials.Password, "rotated")
+	// The operational state subscriptions are made again with the new client
+	assert.Equal(t, len(session.resubscribe), 1)
+	ctrl.Finish()
+}
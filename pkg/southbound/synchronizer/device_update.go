@@ -85,6 +85,16 @@ func (s *Session) updateDevice(connectivity topo.ConnectivityState, channel topo
 
 	topoDevice.Attributes[mastershipTermKey] = strconv.FormatUint(uint64(s.mastershipState.Term), 10)
 	topoDevice.Attributes[mastershipMasterKey] = string(s.mastershipState.Master)
+	if activeAddress := s.getActiveAddress(); activeAddress != "" {
+		topoDevice.Attributes[activeAddressKey] = activeAddress
+	}
+	if capabilities, err := s.getCapabilities(); err == nil {
+		if capabilities.Compatible() {
+			delete(topoDevice.Attributes, topodevice.ModelMismatchAttribute)
+		} else {
+			topoDevice.Attributes[topodevice.ModelMismatchAttribute] = capabilities.mismatchSummary()
+		}
+	}
 	_, err = s.deviceStore.Update(topoDevice)
 	if err != nil {
 		log.Errorf("Device %s is not updated %s", id, err.Error())
@@ -109,8 +119,13 @@ func remove(s []*topo.ProtocolState, i int) []*topo.ProtocolState {
 }
 
 func (s *Session) updateConnectedDevice() error {
-	err := s.updateDevice(topo.ConnectivityState_REACHABLE, topo.ChannelState_CONNECTED,
-		topo.ServiceState_AVAILABLE)
+	service := topo.ServiceState_AVAILABLE
+	// In strict mode, a device that does not advertise the models of its plugin is not
+	// available for configuration
+	if capabilities, err := s.getCapabilities(); err == nil && s.strictModels && !capabilities.Compatible() {
+		service = topo.ServiceState_UNAVAILABLE
+	}
+	err := s.updateDevice(topo.ConnectivityState_REACHABLE, topo.ChannelState_CONNECTED, service)
 	return err
 }
 
@@ -124,11 +139,15 @@ func (s *Session) updateDisconnectedDevice() error {
 // updateDeviceState updates device state based on a device response event
 func (s *Session) updateDeviceState() error {
 	for event := range s.deviceResponseChan {
+		if event.Error() != nil {
+			s.reportError(event)
+		}
 		switch event.EventType() {
 		case events.EventTypeDeviceConnected:
 			// TODO: Retry only on write conflicts
 			_ = backoff.Retry(s.updateConnectedDevice, backoff.NewExponentialBackOff())
 		case events.EventTypeErrorDeviceConnect:
+			s.setOpStateStale(true)
 			// TODO: Retry only on write conflicts
 			_ = backoff.Retry(s.updateDisconnectedDevice, backoff.NewExponentialBackOff())
 
@@ -139,3 +158,29 @@ func (s *Session) updateDeviceState() error {
 
 	return nil
 }
+
+// reportError keeps an error of the session and publishes it to the northbound. The error
+// is dropped rather than published if the southbound error channel is full, so that the
+// session is not held up by a slow northbound, and it is not published once the session
+// is closed, as the channel may be closed then
+func (s *Session) reportError(event events.DeviceResponse) {
+	if s.southboundErrors != nil {
+		s.southboundErrors.Record(event)
+	}
+	if s.southboundErrorChan == nil {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.southboundErrorChan <- event:
+	default:
+		if s.southboundErrors != nil {
+			s.southboundErrors.drop()
+		}
+		log.Warnf("Dropped southbound error of %s: %v", event.Subject(), event.Error())
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-config/pkg/modelregistry/jsonvalues"
+	"github.com/onosproject/onos-config/pkg/southbound"
+	"github.com/onosproject/onos-config/pkg/store/change/device/state"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/onosproject/onos-config/pkg/utils/values"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// configDriftChecker compares the configuration a device is running with the
+// configuration the device state store holds for it
+type configDriftChecker struct {
+	ctx            context.Context
+	device         *topodevice.Device
+	target         southbound.TargetIf
+	stateStore     state.Store
+	encoding       gnmi.Encoding
+	readOnlyPaths  modelregistry.ReadOnlyPathMap
+	readWritePaths modelregistry.ReadWritePathMap
+	driftChan      chan<- events.ConfigDriftEvent
+	interval       time.Duration
+	onDrift        func(events.ConfigDriftEvent)
+	last           events.ConfigDriftEvent
+	mu             sync.RWMutex
+}
+
+// run checks for drift once and then at every interval until the context is done
+func (c *configDriftChecker) run() {
+	if _, err := c.check(); err != nil {
+		log.Warnf("Checking configuration drift of %s failed: %v", c.device.ID, err)
+	}
+	if c.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.check(); err != nil {
+				log.Warnf("Checking configuration drift of %s failed: %v", c.device.ID, err)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// check gets the configuration of the device and reports how it differs from the intended one
+func (c *configDriftChecker) check() (events.ConfigDriftEvent, error) {
+	versionedID := devicetype.NewVersionedID(devicetype.ID(c.device.ID), devicetype.Version(c.device.Version))
+	intended, err := c.stateStore.Get(versionedID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, err := c.getRunningConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	report := diffConfig(string(c.device.ID), intended, actual)
+	log.Infof("Configuration drift of %s: %d missing, %d extra, %d different", c.device.ID,
+		len(report.Missing()), len(report.Extra()), len(report.Different()))
+	c.mu.Lock()
+	c.last = report
+	c.mu.Unlock()
+
+	// Extra paths alone are not remediated as pushing the intended configuration does not remove them
+	if c.onDrift != nil && (len(report.Missing()) > 0 || len(report.Different()) > 0) {
+		c.onDrift(report)
+	}
+
+	if c.driftChan != nil {
+		select {
+		case c.driftChan <- report:
+		case <-c.ctx.Done():
+		}
+	}
+	return report, nil
+}
+
+// lastReport returns the report of the most recent check if any
+func (c *configDriftChecker) lastReport() events.ConfigDriftEvent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.last
+}
+
+// getRunningConfig gets the configuration the device is running
+func (c *configDriftChecker) getRunningConfig() ([]*devicechange.PathValue, error) {
+	request := &gnmi.GetRequest{
+		Type:     gnmi.GetRequest_CONFIG,
+		Encoding: c.encoding,
+	}
+	response, err := c.target.Get(c.ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return c.getValues(response.Notification)
+}
+
+// getValues flattens the notifications of a Get response in to path values
+func (c *configDriftChecker) getValues(notifications []*gnmi.Notification) ([]*devicechange.PathValue, error) {
+	configValues := make([]*devicechange.PathValue, 0)
+	for _, notification := range notifications {
+		for _, update := range notification.Update {
+			path := updatePath(notification.Prefix, update.Path)
+			jsonVal := update.GetVal().GetJsonVal()
+			if jsonVal == nil {
+				jsonVal = update.GetVal().GetJsonIetfVal()
+			}
+			if jsonVal != nil {
+				prefix := utils.StrPath(path)
+				if prefix == "/" {
+					prefix = ""
+				}
+				jsonValues, err := jsonvalues.DecomposeJSONWithPaths(prefix, jsonVal, c.readOnlyPaths, c.readWritePaths)
+				if err != nil {
+					return nil, err
+				}
+				configValues = append(configValues, jsonValues...)
+				continue
+			}
+			strPath := utils.StrPath(path)
+			typedVal, err := values.GnmiTypedValueToNativeType(update.Val, c.modelPathElem(strPath))
+			if err != nil {
+				return nil, err
+			}
+			configValues = append(configValues, &devicechange.PathValue{
+				Path:  strPath,
+				Value: typedVal,
+			})
+		}
+	}
+	return configValues, nil
+}
+
+// modelPathElem looks up the read write path of the model an instance path is of, so that values
+// are decoded with the width, precision and leaf-list types of the model
+func (c *configDriftChecker) modelPathElem(path string) *modelregistry.ReadWritePathElem {
+	if elem, ok := c.readWritePaths[modelregistry.AnonymizePathIndices(path)]; ok {
+		return &elem
+	}
+	return nil
+}
+
+// diffConfig compares the intended and the actual values of a device by path
+func diffConfig(subject string, intended []*devicechange.PathValue, actual []*devicechange.PathValue) events.ConfigDriftEvent {
+	actualValues := make(map[string]*devicechange.TypedValue)
+	for _, pv := range actual {
+		actualValues[pv.Path] = pv.Value
+	}
+
+	missing := make([]*devicechange.PathValue, 0)
+	different := make([]*events.DriftedValue, 0)
+	intendedPaths := make(map[string]struct{})
+	for _, pv := range intended {
+		intendedPaths[pv.Path] = struct{}{}
+		actualValue, ok := actualValues[pv.Path]
+		if !ok {
+			missing = append(missing, pv)
+		} else if !equalValues(pv.Value, actualValue) {
+			different = append(different, &events.DriftedValue{
+				Path:     pv.Path,
+				Intended: pv.Value,
+				Actual:   actualValue,
+			})
+		}
+	}
+
+	extra := make([]*devicechange.PathValue, 0)
+	for _, pv := range actual {
+		if _, ok := intendedPaths[pv.Path]; !ok {
+			extra = append(extra, pv)
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Path < missing[j].Path })
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Path < extra[j].Path })
+	sort.Slice(different, func(i, j int) bool { return different[i].Path < different[j].Path })
+	return events.NewConfigDriftEvent(subject, missing, extra, different)
+}
+
+// equalValues compares an intended and an actual value by what they represent rather than by
+// how they are encoded: numbers are compared whatever their type, width or precision, and empty
+// values and empty leaf-lists are equal
+func equalValues(intended *devicechange.TypedValue, actual *devicechange.TypedValue) bool {
+	if isEmptyValue(intended) || isEmptyValue(actual) {
+		return isEmptyValue(intended) && isEmptyValue(actual)
+	}
+	if intendedNumbers, ok := numericValues(intended); ok {
+		if actualNumbers, ok := numericValues(actual); ok {
+			if len(intendedNumbers) != len(actualNumbers) {
+				return false
+			}
+			for i := range intendedNumbers {
+				if !equalNumbers(intendedNumbers[i], actualNumbers[i]) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return intended.ValueToString() == actual.ValueToString()
+}
+
+func isEmptyValue(value *devicechange.TypedValue) bool {
+	if value == nil || value.Type == devicechange.ValueType_EMPTY {
+		return true
+	}
+	switch value.Type {
+	case devicechange.ValueType_LEAFLIST_STRING, devicechange.ValueType_LEAFLIST_INT,
+		devicechange.ValueType_LEAFLIST_UINT, devicechange.ValueType_LEAFLIST_BOOL,
+		devicechange.ValueType_LEAFLIST_DECIMAL, devicechange.ValueType_LEAFLIST_FLOAT,
+		devicechange.ValueType_LEAFLIST_BYTES:
+		return len(value.Bytes) == 0
+	}
+	return false
+}
+
+// number is a numeric value, exact unless it comes from a float
+type number struct {
+	value *big.Rat
+	float bool
+}
+
+// numericValues returns the numbers of a numeric value or leaf-list
+func numericValues(value *devicechange.TypedValue) ([]number, bool) {
+	switch value.Type {
+	case devicechange.ValueType_INT:
+		return []number{{value: new(big.Rat).SetInt64(int64((*devicechange.TypedInt)(value).Int()))}}, true
+	case devicechange.ValueType_UINT:
+		return []number{{value: new(big.Rat).SetInt(new(big.Int).SetUint64(uint64((*devicechange.TypedUint)(value).Uint())))}}, true
+	case devicechange.ValueType_DECIMAL:
+		return []number{decimalNumber((*devicechange.TypedDecimal)(value).Decimal64())}, true
+	case devicechange.ValueType_FLOAT:
+		return []number{floatNumber((*devicechange.TypedFloat)(value).Float32())}, true
+	case devicechange.ValueType_LEAFLIST_INT:
+		list, _ := (*devicechange.TypedLeafListInt)(value).List()
+		numbers := make([]number, 0, len(list))
+		for _, v := range list {
+			numbers = append(numbers, number{value: new(big.Rat).SetInt64(v)})
+		}
+		return numbers, true
+	case devicechange.ValueType_LEAFLIST_UINT:
+		list, _ := (*devicechange.TypedLeafListUint)(value).List()
+		numbers := make([]number, 0, len(list))
+		for _, v := range list {
+			numbers = append(numbers, number{value: new(big.Rat).SetInt(new(big.Int).SetUint64(v))})
+		}
+		return numbers, true
+	case devicechange.ValueType_LEAFLIST_DECIMAL:
+		list, precision := (*devicechange.TypedLeafListDecimal)(value).List()
+		numbers := make([]number, 0, len(list))
+		for _, digits := range list {
+			numbers = append(numbers, decimalNumber(digits, precision))
+		}
+		return numbers, true
+	case devicechange.ValueType_LEAFLIST_FLOAT:
+		list := (*devicechange.TypedLeafListFloat)(value).List()
+		numbers := make([]number, 0, len(list))
+		for _, v := range list {
+			numbers = append(numbers, floatNumber(v))
+		}
+		return numbers, true
+	}
+	return nil, false
+}
+
+func decimalNumber(digits int64, precision uint8) number {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	return number{value: new(big.Rat).SetFrac(big.NewInt(digits), scale)}
+}
+
+func floatNumber(value float32) number {
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(float64(value), 'g', -1, 32))
+	if !ok {
+		rat = new(big.Rat)
+	}
+	return number{value: rat, float: true}
+}
+
+// equalNumbers compares two numbers exactly, or within the precision of a float32 when either
+// comes from a float
+func equalNumbers(n1 number, n2 number) bool {
+	if !n1.float && !n2.float {
+		return n1.value.Cmp(n2.value) == 0
+	}
+	f1, _ := n1.value.Float64()
+	f2, _ := n2.value.Float64()
+	return float32(f1) == float32(f2)
+}
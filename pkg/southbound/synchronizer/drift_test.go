@@ -0,0 +1,189 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-config/pkg/test/mocks/southbound"
+	storemock "github.com/onosproject/onos-config/pkg/test/mocks/store"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"gotest.tools/assert"
+	"testing"
+)
+
+func Test_diffConfig(t *testing.T) {
+	intended := []*devicechange.PathValue{
+		{Path: cont1aCont2aLeaf2a, Value: devicechange.NewTypedValueUint(13, 8)},
+		{Path: cont1aCont2aLeaf2b, Value: devicechange.NewTypedValueString("intended")},
+		{Path: cont1aLeaf1a, Value: devicechange.NewTypedValueString("leaf1a")},
+	}
+	actual := []*devicechange.PathValue{
+		{Path: cont1aCont2aLeaf2a, Value: devicechange.NewTypedValueUint(13, 64)},
+		{Path: cont1aCont2aLeaf2b, Value: devicechange.NewTypedValueString("edited")},
+		{Path: cont1aCont2aLeaf2d, Value: devicechange.NewTypedValueString("extra")},
+	}
+
+	report := diffConfig(device1, intended, actual)
+	assert.Equal(t, report.Subject(), device1)
+	assert.Equal(t, report.EventType(), events.EventTypeConfigDrift)
+	assert.Equal(t, len(report.Missing()), 1)
+	assert.Equal(t, report.Missing()[0].Path, cont1aLeaf1a)
+	assert.Equal(t, len(report.Extra()), 1)
+	assert.Equal(t, report.Extra()[0].Path, cont1aCont2aLeaf2d)
+	assert.Equal(t, len(report.Different()), 1)
+	assert.Equal(t, report.Different()[0].Path, cont1aCont2aLeaf2b)
+	assert.Equal(t, report.Different()[0].Actual.ValueToString(), "edited")
+	assert.Assert(t, !report.InSync())
+
+	report = diffConfig(device1, intended, intended)
+	assert.Assert(t, report.InSync())
+}
+
+func Test_configDriftCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	device := &topodevice.Device{ID: device1, Version: "1.0.0"}
+
+	stateStore := storemock.NewMockDeviceStateStore(ctrl)
+	stateStore.EXPECT().Get(devicetype.NewVersionedID(device1, "1.0.0"), gomock.Any()).Return([]*devicechange.PathValue{
+		{Path: cont1aCont2aLeaf2a, Value: devicechange.NewTypedValueString("value2a")},
+		{Path: cont1aCont2aLeaf2b, Value: devicechange.NewTypedValueString("value2b")},
+	}, nil)
+
+	prefix, err := utils.ParseGNMIElements([]string{"cont1a", "cont2a"})
+	assert.NilError(t, err)
+	leaf2a, err := utils.ParseGNMIElements([]string{"leaf2a"})
+	assert.NilError(t, err)
+	mockTarget := southbound.NewMockTargetIf(ctrl)
+	mockTarget.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, request *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+			assert.Equal(t, request.Type, gnmi.GetRequest_CONFIG)
+			return &gnmi.GetResponse{
+				Notification: []*gnmi.Notification{
+					{
+						Prefix: prefix,
+						Update: []*gnmi.Update{
+							{
+								Path: leaf2a,
+								Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "value2a"}},
+							},
+						},
+					},
+				},
+			}, nil
+		})
+
+	driftChan := make(chan events.ConfigDriftEvent, 1)
+	checker := &configDriftChecker{
+		ctx:        context.Background(),
+		device:     device,
+		target:     mockTarget,
+		stateStore: stateStore,
+		encoding:   gnmi.Encoding_PROTO,
+		driftChan:  driftChan,
+	}
+	assert.Assert(t, checker.lastReport() == nil)
+
+	report, err := checker.check()
+	assert.NilError(t, err)
+	assert.Equal(t, len(report.Missing()), 1)
+	assert.Equal(t, report.Missing()[0].Path, cont1aCont2aLeaf2b)
+	assert.Equal(t, len(report.Extra()), 0)
+	assert.Equal(t, len(report.Different()), 0)
+	assert.Equal(t, len(checker.lastReport().Missing()), 1)
+
+	published := <-driftChan
+	assert.Equal(t, published.Subject(), device1)
+	assert.Equal(t, published.Missing()[0].Path, cont1aCont2aLeaf2b)
+}
+
+func Test_equalValues(t *testing.T) {
+	assert.Assert(t, equalValues(devicechange.NewTypedValueUint(13, 8), devicechange.NewTypedValueInt(13, 64)))
+	assert.Assert(t, !equalValues(devicechange.NewTypedValueUint(13, 8), devicechange.NewTypedValueInt(-13, 8)))
+	assert.Assert(t, equalValues(devicechange.NewTypedValueDecimal(15, 1), devicechange.NewTypedValueDecimal(150, 2)))
+	assert.Assert(t, !equalValues(devicechange.NewTypedValueDecimal(15, 1), devicechange.NewTypedValueDecimal(151, 2)))
+	assert.Assert(t, equalValues(devicechange.NewTypedValueDecimal(15, 1), devicechange.NewTypedValueFloat(1.5)))
+	assert.Assert(t, equalValues(devicechange.NewLeafListUintTv([]uint64{1, 2}, 8), devicechange.NewLeafListIntTv([]int64{1, 2}, 32)))
+	assert.Assert(t, !equalValues(devicechange.NewLeafListUintTv([]uint64{1, 2}, 8), devicechange.NewLeafListIntTv([]int64{2, 1}, 32)))
+	assert.Assert(t, equalValues(devicechange.NewTypedValueEmpty(), devicechange.NewLeafListStringTv([]string{})))
+	assert.Assert(t, !equalValues(devicechange.NewTypedValueEmpty(), devicechange.NewTypedValueString("value")))
+	assert.Assert(t, equalValues(devicechange.NewTypedValueString("value"), devicechange.NewTypedValueString("value")))
+	assert.Assert(t, !equalValues(devicechange.NewTypedValueString("value"), devicechange.NewTypedValueString("other")))
+}
+
+func Test_configDriftCheckModelTypes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	device := &topodevice.Device{ID: device1, Version: "1.0.0"}
+
+	stateStore := storemock.NewMockDeviceStateStore(ctrl)
+	stateStore.EXPECT().Get(devicetype.NewVersionedID(device1, "1.0.0"), gomock.Any()).Return([]*devicechange.PathValue{
+		{Path: cont1aCont2aLeaf2a, Value: devicechange.NewTypedValueUint(13, 8)},
+		{Path: cont1aCont2aLeaf2c, Value: devicechange.NewLeafListUintTv([]uint64{1, 2}, 16)},
+	}, nil)
+
+	leaf2a, err := utils.ParseGNMIElements(utils.SplitPath(cont1aCont2aLeaf2a))
+	assert.NilError(t, err)
+	leaf2c, err := utils.ParseGNMIElements(utils.SplitPath(cont1aCont2aLeaf2c))
+	assert.NilError(t, err)
+	mockTarget := southbound.NewMockTargetIf(ctrl)
+	mockTarget.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&gnmi.GetResponse{
+		Notification: []*gnmi.Notification{
+			{
+				Update: []*gnmi.Update{
+					{
+						Path: leaf2a,
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 13}},
+					},
+					{
+						Path: leaf2c,
+						Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_LeaflistVal{LeaflistVal: &gnmi.ScalarArray{
+							Element: []*gnmi.TypedValue{
+								{Value: &gnmi.TypedValue_UintVal{UintVal: 1}},
+								{Value: &gnmi.TypedValue_UintVal{UintVal: 2}},
+							},
+						}}},
+					},
+				},
+			},
+		},
+	}, nil)
+
+	checker := &configDriftChecker{
+		ctx:        context.Background(),
+		device:     device,
+		target:     mockTarget,
+		stateStore: stateStore,
+		encoding:   gnmi.Encoding_PROTO,
+		readWritePaths: modelregistry.ReadWritePathMap{
+			cont1aCont2aLeaf2a: modelregistry.ReadWritePathElem{
+				ReadOnlyAttrib: modelregistry.ReadOnlyAttrib{ValueType: devicechange.ValueType_UINT, TypeOpts: []uint8{8}},
+			},
+			cont1aCont2aLeaf2c: modelregistry.ReadWritePathElem{
+				ReadOnlyAttrib: modelregistry.ReadOnlyAttrib{ValueType: devicechange.ValueType_LEAFLIST_UINT, TypeOpts: []uint8{16}},
+			},
+		},
+	}
+
+	// Values that differ only in their encoding are not drift
+	report, err := checker.check()
+	assert.NilError(t, err)
+	assert.Assert(t, report.InSync())
+}
@@ -0,0 +1,347 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// SubscriptionMode is how a device sends updates of its operational state
+type SubscriptionMode string
+
+const (
+	// SubscriptionTargetDefined lets the device choose how to send updates of each path
+	SubscriptionTargetDefined SubscriptionMode = "target_defined"
+	// SubscriptionSample has the device send updates at every sample interval
+	SubscriptionSample SubscriptionMode = "sample"
+	// SubscriptionOnChange has the device send updates when values change
+	SubscriptionOnChange SubscriptionMode = "on_change"
+)
+
+// PathGrouping is how the subscribed paths are split into subscriptions
+type PathGrouping string
+
+const (
+	// GroupingNone subscribes to all paths in a single subscription
+	GroupingNone PathGrouping = "none"
+	// GroupingRoot opens a subscription for each top level container
+	GroupingRoot PathGrouping = "root"
+)
+
+const (
+	// DefaultSampleInterval is the default interval updates are sampled or polled at
+	DefaultSampleInterval = 15 * time.Second
+	// DefaultHeartbeatInterval is the default interval values are sent at even if suppressed
+	DefaultHeartbeatInterval = 15 * time.Second
+)
+
+// opStateSubscriptionKey is the device attribute that overrides the operational state
+// subscription options of its model
+const opStateSubscriptionKey = "onos-config.opstate.subscription"
+
+// OpStateSubscription configures how the operational state of a device is kept up to date
+type OpStateSubscription struct {
+	Mode              SubscriptionMode
+	SampleInterval    time.Duration
+	HeartbeatInterval time.Duration
+	SuppressRedundant bool
+	Grouping          PathGrouping
+	// Poll does not subscribe, for devices that do not support Subscribe at all. The state is
+	// polled with Get requests of the read only paths of the model, or of the STATE and
+	// OPERATIONAL partitions if it has none, at every sample interval
+	Poll bool
+	// IgnoredValues are values devices send that are not actual state
+	IgnoredValues []string
+	// TTL is how long cached values are kept without being received again; 0 keeps them
+	TTL time.Duration
+}
+
+// DefaultOpStateSubscription returns the subscription options used when none are configured
+func DefaultOpStateSubscription() OpStateSubscription {
+	return OpStateSubscription{
+		Mode:              SubscriptionTargetDefined,
+		SampleInterval:    DefaultSampleInterval,
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		Grouping:          GroupingNone,
+		// Stratum sends phantom notifications with this value
+		IgnoredValues: []string{"unsupported yet"},
+	}
+}
+
+// ParseOpStateSubscription parses subscription options in the form
+// mode=<mode>,sample=<duration>,heartbeat=<duration>,suppress=<bool>,grouping=<grouping>,poll=<bool>,ignore=<value>|<value>,ttl=<duration>
+// Options that are not given keep their default value
+func ParseOpStateSubscription(options string) (OpStateSubscription, error) {
+	return parseOpStateSubscription(options, DefaultOpStateSubscription())
+}
+
+// parseOpStateSubscription parses subscription options over the given ones
+func parseOpStateSubscription(options string, subscription OpStateSubscription) (OpStateSubscription, error) {
+	if options == "" {
+		return subscription, nil
+	}
+	for _, option := range strings.Split(options, ",") {
+		parts := strings.SplitN(strings.TrimSpace(option), "=", 2)
+		if len(parts) != 2 {
+			return subscription, errors.NewInvalid("invalid subscription option %s", option)
+		}
+		var err error
+		switch value := strings.TrimSpace(parts[1]); strings.TrimSpace(parts[0]) {
+		case "mode":
+			switch mode := SubscriptionMode(strings.ToLower(value)); mode {
+			case SubscriptionTargetDefined, SubscriptionSample, SubscriptionOnChange:
+				subscription.Mode = mode
+			default:
+				return subscription, errors.NewInvalid("unknown subscription mode %s", value)
+			}
+		case "sample":
+			subscription.SampleInterval, err = parseInterval(value)
+		case "heartbeat":
+			subscription.HeartbeatInterval, err = parseInterval(value)
+		case "suppress":
+			subscription.SuppressRedundant, err = strconv.ParseBool(value)
+		case "grouping":
+			switch grouping := PathGrouping(strings.ToLower(value)); grouping {
+			case GroupingNone, GroupingRoot:
+				subscription.Grouping = grouping
+			default:
+				return subscription, errors.NewInvalid("unknown path grouping %s", value)
+			}
+		case "poll":
+			subscription.Poll, err = strconv.ParseBool(value)
+		case "ttl":
+			subscription.TTL, err = parseInterval(value)
+		case "ignore":
+			subscription.IgnoredValues = nil
+			for _, ignored := range strings.Split(value, "|") {
+				if ignored != "" {
+					subscription.IgnoredValues = append(subscription.IgnoredValues, ignored)
+				}
+			}
+		default:
+			return subscription, errors.NewInvalid("unknown subscription option %s", parts[0])
+		}
+		if err != nil {
+			return subscription, errors.NewInvalid("invalid subscription option %s: %v", option, err)
+		}
+	}
+	if subscription.Poll && subscription.SampleInterval == 0 {
+		return subscription, errors.NewInvalid("polling needs a sample interval")
+	}
+	return subscription, nil
+}
+
+func parseInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval < 0 {
+		return 0, errors.NewInvalid("negative interval %s", value)
+	}
+	return interval, nil
+}
+
+// ParseModelOpStateSubscriptions parses a list of model subscription options in the form
+// <type>-<version>:<options>;<type>-<version>:<options>
+func ParseModelOpStateSubscriptions(subscriptions string) (map[string]OpStateSubscription, error) {
+	modelSubscriptions := make(map[string]OpStateSubscription)
+	if subscriptions == "" {
+		return modelSubscriptions, nil
+	}
+	for _, modelSubscription := range strings.Split(subscriptions, ";") {
+		parts := strings.SplitN(modelSubscription, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.NewInvalid("invalid model subscription %s", modelSubscription)
+		}
+		subscription, err := ParseOpStateSubscription(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		modelSubscriptions[strings.TrimSpace(parts[0])] = subscription
+	}
+	return modelSubscriptions, nil
+}
+
+// ignoresValue indicates whether a value sent by the device is dropped
+func (s OpStateSubscription) ignoresValue(value *devicechange.TypedValue) bool {
+	if len(s.IgnoredValues) == 0 || value == nil {
+		return false
+	}
+	valueStr := value.ValueToString()
+	for _, ignored := range s.IgnoredValues {
+		if valueStr == ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// opStateSubscriptionChanged indicates whether the subscription options of a device changed
+func opStateSubscriptionChanged(device *topodevice.Device, updated *topodevice.Device) bool {
+	return device.Attributes[opStateSubscriptionKey] != updated.Attributes[opStateSubscriptionKey]
+}
+
+// groupPaths splits the paths to subscribe to according to the grouping
+func groupPaths(paths [][]string, grouping PathGrouping) [][][]string {
+	if grouping != GroupingRoot {
+		return [][][]string{paths}
+	}
+	groups := make([][][]string, 0)
+	roots := make(map[string]int)
+	for _, path := range paths {
+		root := ""
+		if len(path) > 0 {
+			root = path[0]
+		}
+		index, ok := roots[root]
+		if !ok {
+			index = len(groups)
+			roots[root] = index
+			groups = append(groups, make([][]string, 0))
+		}
+		groups[index] = append(groups[index], path)
+	}
+	return groups
+}
+
+// syncOperationalStateByPolling keeps the operational state cache up to date with a Get
+// of the state of the device at every sample interval. For use with OpStateSubscription.Poll
+func (sync *Synchronizer) syncOperationalStateByPolling(ctx context.Context,
+	errChan chan<- events.DeviceResponse) {
+
+	interval := sync.subscription.SampleInterval
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+	log.Infof("Polling Op & State of %s every %v", string(sync.key), interval)
+	stale := false
+	poll := func() {
+		// The cached values are stale until a poll succeeds again
+		if err := sync.pollOpState(ctx, errChan); (err != nil) != stale {
+			stale = !stale
+			sync.markStale(stale)
+		}
+	}
+	poll()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			poll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pollOpState gets the state of the device and updates the cache with what changed
+func (sync *Synchronizer) pollOpState(ctx context.Context, errChan chan<- events.DeviceResponse) error {
+	notifications, err := sync.getPolledState(ctx, errChan)
+	if err != nil {
+		log.Warn("Error polling state of ", sync.key, err)
+		errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorGetWithRoPaths,
+			string(sync.key), err)
+		return err
+	}
+
+	type polledValue struct {
+		value     *devicechange.TypedValue
+		timestamp int64
+	}
+	polled := make(map[string]polledValue)
+	for _, notification := range notifications {
+		for _, update := range notification.Update {
+			pathValues, err := sync.getUpdateValues(notification, update)
+			if err != nil {
+				errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorTranslation,
+					string(sync.key), err)
+				continue
+			}
+			for _, pathValue := range pathValues {
+				polled[pathValue.Path] = polledValue{value: pathValue.GetValue(), timestamp: notification.Timestamp}
+			}
+		}
+	}
+
+	stateEvents := make([]events.OperationalStateEvent, 0)
+	sync.operationalCacheLock.Lock()
+	for path, polledValue := range polled {
+		cached, ok := sync.operationalCache[path]
+		// Unchanged values are only refreshed
+		sync.cacheValue(path, polledValue.value, polledValue.timestamp)
+		if ok && cached.ValueToString() == polledValue.value.ValueToString() {
+			continue
+		}
+		stateEvents = append(stateEvents, events.NewOperationalStateEventWithTimestamp(string(sync.Device.ID),
+			path, polledValue.value, events.EventItemUpdated, deviceTimestamp(polledValue.timestamp, time.Now())))
+	}
+	for path := range sync.operationalCache {
+		if _, ok := polled[path]; !ok {
+			sync.uncacheValue(path)
+			stateEvents = append(stateEvents, events.NewOperationalStateEvent(string(sync.Device.ID), path, nil, events.EventItemDeleted))
+		}
+	}
+	sync.operationalCacheLock.Unlock()
+
+	for _, event := range stateEvents {
+		sync.operationalStateChan <- event
+	}
+	return nil
+}
+
+// getPolledState gets the read only paths of the model, or the STATE and OPERATIONAL
+// partitions if the model has none
+func (sync *Synchronizer) getPolledState(ctx context.Context, errChan chan<- events.DeviceResponse) ([]*gnmi.Notification, error) {
+	if len(sync.modelReadOnlyPaths) == 0 {
+		stateNotif, err := sync.getOpStatePathsByType(ctx, gnmi.GetRequest_STATE, errChan)
+		if err != nil {
+			return nil, err
+		}
+		operNotif, err := sync.getOpStatePathsByType(ctx, gnmi.GetRequest_OPERATIONAL, errChan)
+		if err != nil {
+			return nil, err
+		}
+		return append(stateNotif, operNotif...), nil
+	}
+
+	getPaths := make([]*gnmi.Path, 0, len(sync.modelReadOnlyPaths))
+	for _, path := range sync.modelReadOnlyPaths.JustPaths() {
+		gnmiPath, err := utils.ParseGNMIElements(utils.SplitPath(path))
+		if err != nil {
+			return nil, err
+		}
+		getPaths = append(getPaths, gnmiPath)
+	}
+	response, err := sync.target.Get(ctx, &gnmi.GetRequest{
+		Encoding: sync.encoding,
+		Path:     getPaths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Notification, nil
+}
@@ -0,0 +1,323 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-config/pkg/test/mocks/southbound"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"gotest.tools/assert"
+)
+
+func Test_ParseOpStateSubscription(t *testing.T) {
+	subscription, err := ParseOpStateSubscription("")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, subscription, DefaultOpStateSubscription())
+
+	subscription, err = ParseOpStateSubscription("mode=sample, sample=10s,heartbeat=1m,suppress=true,grouping=root")
+	assert.NilError(t, err)
+	assert.Equal(t, subscription.Mode, SubscriptionSample)
+	assert.Equal(t, subscription.SampleInterval, 10*time.Second)
+	assert.Equal(t, subscription.HeartbeatInterval, time.Minute)
+	assert.Assert(t, subscription.SuppressRedundant)
+	assert.Equal(t, subscription.Grouping, GroupingRoot)
+	assert.DeepEqual(t, subscription.IgnoredValues, []string{"unsupported yet"})
+
+	subscription, err = ParseOpStateSubscription("ignore=n/a|unknown")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, subscription.IgnoredValues, []string{"n/a", "unknown"})
+	subscription, err = ParseOpStateSubscription("ignore=")
+	assert.NilError(t, err)
+	assert.Equal(t, len(subscription.IgnoredValues), 0)
+	subscription, err = ParseOpStateSubscription("ttl=5m")
+	assert.NilError(t, err)
+	assert.Equal(t, subscription.TTL, 5*time.Minute)
+
+	_, err = ParseOpStateSubscription("mode=sometimes")
+	assert.ErrorContains(t, err, "unknown subscription mode")
+	_, err = ParseOpStateSubscription("sample=-1s")
+	assert.ErrorContains(t, err, "invalid subscription option")
+	_, err = ParseOpStateSubscription("mode=poll")
+	assert.ErrorContains(t, err, "unknown subscription mode")
+	_, err = ParseOpStateSubscription("poll=true,sample=0s")
+	assert.ErrorContains(t, err, "polling needs a sample interval")
+	_, err = ParseOpStateSubscription("poll=sometimes")
+	assert.ErrorContains(t, err, "invalid subscription option")
+	_, err = ParseOpStateSubscription("priority=high")
+	assert.ErrorContains(t, err, "unknown subscription option")
+
+	subscriptions, err := ParseModelOpStateSubscriptions("Devicesim-1.0.0:mode=on_change;Stratum-1.0.0:poll=true,sample=30s")
+	assert.NilError(t, err)
+	assert.Equal(t, len(subscriptions), 2)
+	assert.Assert(t, subscriptions["Stratum-1.0.0"].Poll)
+	assert.Equal(t, subscriptions["Stratum-1.0.0"].Mode, SubscriptionTargetDefined)
+	assert.Equal(t, subscriptions["Stratum-1.0.0"].SampleInterval, 30*time.Second)
+	_, err = ParseModelOpStateSubscriptions("Devicesim-1.0.0")
+	assert.ErrorContains(t, err, "invalid model subscription")
+}
+
+func Test_getOpStateSubscription(t *testing.T) {
+	sm := &SessionManager{
+		opStateSubscription: DefaultOpStateSubscription(),
+		modelOpStateSubscriptions: map[string]OpStateSubscription{
+			"Devicesim-1.0.0": {Mode: SubscriptionOnChange, SampleInterval: time.Second},
+		},
+	}
+	device := &topodevice.Device{ID: device1, Type: "Stratum", Version: "1.0.0"}
+	assert.DeepEqual(t, sm.getOpStateSubscription(device), DefaultOpStateSubscription())
+
+	device.Type = "Devicesim"
+	assert.Equal(t, sm.getOpStateSubscription(device).Mode, SubscriptionOnChange)
+
+	// The device attribute only overrides the options it lists
+	device.Attributes = map[string]string{opStateSubscriptionKey: "suppress=true"}
+	subscription := sm.getOpStateSubscription(device)
+	assert.Equal(t, subscription.Mode, SubscriptionOnChange)
+	assert.Equal(t, subscription.SampleInterval, time.Second)
+	assert.Assert(t, subscription.SuppressRedundant)
+
+	device.Attributes[opStateSubscriptionKey] = "mode=sometimes"
+	assert.Equal(t, sm.getOpStateSubscription(device).Mode, SubscriptionOnChange)
+}
+
+func Test_groupPaths(t *testing.T) {
+	paths := [][]string{
+		utils.SplitPath("/interfaces/interface[name=eth1]/state/mtu"),
+		utils.SplitPath("/system/state/hostname"),
+		utils.SplitPath("/interfaces/interface[name=eth2]/state/mtu"),
+	}
+	groups := groupPaths(paths, GroupingNone)
+	assert.Equal(t, len(groups), 1)
+	assert.Equal(t, len(groups[0]), 3)
+
+	groups = groupPaths(paths, GroupingRoot)
+	assert.Equal(t, len(groups), 2)
+	assert.Equal(t, len(groups[0]), 2)
+	assert.Equal(t, len(groups[1]), 1)
+	assert.Equal(t, groups[1][0][0], "system")
+}
+
+func Test_pollOpState(t *testing.T) {
+	const (
+		mtuPath      = "/interfaces/interface[name=eth1]/state/mtu"
+		hostnamePath = "/system/state/hostname"
+	)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockTarget := southbound.NewMockTargetIf(ctrl)
+
+	gnmiMtuPath, err := utils.ParseGNMIElements(utils.SplitPath(mtuPath))
+	assert.NilError(t, err)
+	mtu := func(value uint64) *gnmi.Notification {
+		return &gnmi.Notification{
+			Update: []*gnmi.Update{{
+				Path: gnmiMtuPath,
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: value}},
+			}},
+		}
+	}
+	gomock.InOrder(
+		mockTarget.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&gnmi.GetResponse{Notification: []*gnmi.Notification{mtu(1500)}}, nil),
+		mockTarget.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&gnmi.GetResponse{Notification: []*gnmi.Notification{mtu(1500)}}, nil),
+		mockTarget.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&gnmi.GetResponse{Notification: []*gnmi.Notification{mtu(9000)}}, nil),
+	)
+
+	opStateChan := make(chan events.OperationalStateEvent, 10)
+	errChan := make(chan events.DeviceResponse, 10)
+	cache := devicechange.TypedValueMap{
+		hostnamePath: devicechange.NewTypedValueString("switch1"),
+	}
+	s := &Synchronizer{
+		Device:               &topodevice.Device{ID: device1},
+		operationalStateChan: opStateChan,
+		operationalCache:     cache,
+		operationalCacheLock: &sync.RWMutex{},
+		modelReadOnlyPaths: modelregistry.ReadOnlyPathMap{
+			"/interfaces/interface[name=*]/state": modelregistry.ReadOnlySubPathMap{
+				"/mtu": modelregistry.ReadOnlyAttrib{ValueType: devicechange.ValueType_UINT},
+			},
+		},
+		encoding: gnmi.Encoding_PROTO,
+		target:   mockTarget,
+	}
+
+	// Paths that are no longer returned are deleted
+	assert.NilError(t, s.pollOpState(context.Background(), errChan))
+	assert.Equal(t, len(opStateChan), 2)
+	assert.Equal(t, len(cache), 1)
+	assert.Equal(t, cache[mtuPath].ValueToString(), "1500")
+	<-opStateChan
+	<-opStateChan
+
+	// Unchanged values are not sent again
+	assert.NilError(t, s.pollOpState(context.Background(), errChan))
+	assert.Equal(t, len(opStateChan), 0)
+
+	assert.NilError(t, s.pollOpState(context.Background(), errChan))
+	assert.Equal(t, len(opStateChan), 1)
+	event := <-opStateChan
+	assert.Equal(t, event.Path(), mtuPath)
+	assert.Equal(t, event.ItemAction(), events.EventItemUpdated)
+	assert.Equal(t, cache[mtuPath].ValueToString(), "9000")
+	assert.Equal(t, len(errChan), 0)
+}
+
+func Test_opStateSubHandlerValues(t *testing.T) {
+	opStateChan := make(chan events.OperationalStateEvent, 10)
+	cache := make(devicechange.TypedValueMap)
+	s := &Synchronizer{
+		Device:               &topodevice.Device{ID: device1},
+		operationalStateChan: opStateChan,
+		operationalCache:     cache,
+		operationalCacheLock: &sync.RWMutex{},
+		modelReadOnlyPaths: modelregistry.ReadOnlyPathMap{
+			"/interfaces/interface[name=*]/state": modelregistry.ReadOnlySubPathMap{
+				"/mtu":         modelregistry.ReadOnlyAttrib{ValueType: devicechange.ValueType_UINT, TypeOpts: []uint8{16}},
+				"/description": modelregistry.ReadOnlyAttrib{ValueType: devicechange.ValueType_STRING},
+			},
+		},
+		subscription: DefaultOpStateSubscription(),
+	}
+	prefix, err := utils.ParseGNMIElements(utils.SplitPath("/interfaces/interface[name=eth1]/state"))
+	assert.NilError(t, err)
+	mtu, err := utils.ParseGNMIElements(utils.SplitPath("/mtu"))
+	assert.NilError(t, err)
+	description, err := utils.ParseGNMIElements(utils.SplitPath("/description"))
+	assert.NilError(t, err)
+
+	// The prefix of the notification is applied and the width of the value taken from the model
+	err = s.opStateSubHandler(&gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+			Prefix: prefix,
+			Update: []*gnmi.Update{
+				{Path: mtu, Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 1500}}},
+				{Path: description, Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "unsupported yet"}}},
+			},
+		}},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(opStateChan), 1)
+	<-opStateChan
+	mtuValue, ok := cache["/interfaces/interface[name=eth1]/state/mtu"]
+	assert.Assert(t, ok)
+	assert.Equal(t, mtuValue.ValueToString(), "1500")
+	assert.Equal(t, mtuValue.TypeOpts[0], int32(16))
+
+	// JSON trees are decomposed in to leaves
+	err = s.opStateSubHandler(&gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+			Update: []*gnmi.Update{{
+				Path: prefix,
+				Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{
+					JsonVal: []byte(`{"mtu": 9000, "description": "uplink"}`),
+				}},
+			}},
+		}},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(opStateChan), 2)
+	<-opStateChan
+	<-opStateChan
+	assert.Equal(t, cache["/interfaces/interface[name=eth1]/state/mtu"].ValueToString(), "9000")
+	assert.Equal(t, cache["/interfaces/interface[name=eth1]/state/description"].ValueToString(), "uplink")
+
+	// Deleting the container deletes its leaves
+	err = s.opStateSubHandler(&gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+			Delete: []*gnmi.Path{prefix},
+		}},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(opStateChan), 2)
+	assert.Equal(t, len(cache), 0)
+}
+
+func Test_expireValues(t *testing.T) {
+	const mtuPath = "/interfaces/interface[name=eth1]/state/mtu"
+	opStateChan := make(chan events.OperationalStateEvent, 10)
+	cache := make(devicechange.TypedValueMap)
+	s := &Synchronizer{
+		Device:               &topodevice.Device{ID: device1},
+		operationalStateChan: opStateChan,
+		operationalCache:     cache,
+		operationalInfo:      make(OpStateInfoMap),
+		operationalCacheLock: &sync.RWMutex{},
+	}
+
+	// Timestamps in seconds are converted to nanoseconds
+	s.cacheValue(mtuPath, devicechange.NewTypedValueUint(1500, 16), 1600000000)
+	assert.Equal(t, s.operationalInfo[mtuPath].Timestamp, int64(1600000000)*int64(time.Second))
+
+	s.expireValues(time.Now().Add(-time.Minute))
+	assert.Equal(t, len(cache), 1)
+	assert.Equal(t, len(opStateChan), 0)
+
+	markOpStateStale(s.operationalInfo)
+	assert.Assert(t, s.operationalInfo[mtuPath].Stale)
+
+	s.expireValues(time.Now().Add(time.Minute))
+	assert.Equal(t, len(cache), 0)
+	assert.Equal(t, len(s.operationalInfo), 0)
+	assert.Equal(t, len(opStateChan), 1)
+	event := <-opStateChan
+	assert.Equal(t, event.Path(), mtuPath)
+	assert.Equal(t, event.ItemAction(), events.EventItemDeleted)
+}
+
+func Test_disconnectKeepsOpState(t *testing.T) {
+	received := time.Now().Add(-time.Minute)
+	cache := map[topodevice.ID]devicechange.TypedValueMap{
+		device1: {cont1aLeaf1a: devicechange.NewTypedValueString("a")},
+	}
+	info := map[topodevice.ID]OpStateInfoMap{
+		device1: {cont1aLeaf1a: OpStateInfo{Timestamp: received.UnixNano(), Received: received}},
+	}
+	lock := &sync.RWMutex{}
+	session := &Session{
+		device:                    &topodevice.Device{ID: device1},
+		operationalStateCache:     cache,
+		operationalStateInfo:      info,
+		operationalStateCacheLock: lock,
+	}
+
+	// The values are kept with their timestamps, but are stale
+	assert.NilError(t, session.disconnect())
+	assert.Assert(t, session.isOpStateStale())
+	assert.Equal(t, cache[device1][cont1aLeaf1a].ValueToString(), "a")
+	assert.Assert(t, info[device1][cont1aLeaf1a].Stale)
+	assert.Equal(t, info[device1][cont1aLeaf1a].Timestamp, received.UnixNano())
+
+	// They are only removed with the device
+	sm := &SessionManager{
+		operationalStateCache:     cache,
+		operationalStateInfo:      info,
+		operationalStateCacheLock: lock,
+	}
+	sm.deleteOpState(device1)
+	_, ok := cache[device1]
+	assert.Assert(t, !ok)
+	_, ok = info[device1]
+	assert.Assert(t, !ok)
+}
@@ -0,0 +1,132 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-config/pkg/events"
+)
+
+// OpStateInfo is what is known about a value in the operational state cache
+type OpStateInfo struct {
+	// Timestamp is when the device sampled the value, in nanoseconds since the epoch
+	Timestamp int64
+	// Received is when the value was last received from the device
+	Received time.Time
+	// Stale indicates the value is no longer kept up to date by the device
+	Stale bool
+}
+
+// OpStateInfoMap holds the OpStateInfo of the cached values of a device by path
+type OpStateInfoMap map[string]OpStateInfo
+
+// minSecondsTimestamp is the smallest timestamp taken to be in nanoseconds. Some devices
+// send timestamps in seconds, which are much smaller than any recent time in nanoseconds
+const minSecondsTimestamp = int64(1) << 40
+
+// deviceTimestamp converts the timestamp of a notification to nanoseconds since the epoch.
+// Notifications with no timestamp are taken to be sampled when they are received
+func deviceTimestamp(timestamp int64, received time.Time) int64 {
+	if timestamp <= 0 {
+		return received.UnixNano()
+	} else if timestamp < minSecondsTimestamp {
+		return timestamp * int64(time.Second)
+	}
+	return timestamp
+}
+
+// cacheValue stores a value in the operational state cache. It must be called
+// with the cache lock held
+func (sync *Synchronizer) cacheValue(path string, value *devicechange.TypedValue, timestamp int64) {
+	sync.operationalCache[path] = value
+	received := time.Now()
+	timestamp = deviceTimestamp(timestamp, received)
+	if sync.operationalInfo != nil {
+		sync.operationalInfo[path] = OpStateInfo{
+			Timestamp: timestamp,
+			Received:  received,
+		}
+	}
+	if sync.history != nil {
+		sync.history.Record(sync.Device.ID, OpStateSample{Path: path, Value: value, Timestamp: timestamp})
+	}
+}
+
+// uncacheValue removes a value from the operational state cache. It must be called
+// with the cache lock held
+func (sync *Synchronizer) uncacheValue(path string) {
+	delete(sync.operationalCache, path)
+	if sync.operationalInfo != nil {
+		delete(sync.operationalInfo, path)
+	}
+	if sync.history != nil {
+		sync.history.Record(sync.Device.ID, OpStateSample{Path: path, Timestamp: time.Now().UnixNano()})
+	}
+}
+
+// expireOpState removes the cached values that were not received again within the TTL
+// of the subscription until the context is done
+func (sync *Synchronizer) expireOpState(ctx context.Context) {
+	ttl := sync.subscription.TTL
+	if ttl <= 0 || sync.operationalInfo == nil {
+		return
+	}
+	interval := ttl / 2
+	if interval < time.Second {
+		interval = ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sync.expireValues(time.Now().Add(-ttl))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// expireValues removes the cached values last received before the given time
+func (sync *Synchronizer) expireValues(before time.Time) {
+	expired := make([]string, 0)
+	sync.operationalCacheLock.Lock()
+	for path, info := range sync.operationalInfo {
+		if info.Received.Before(before) {
+			expired = append(expired, path)
+			sync.uncacheValue(path)
+		}
+	}
+	sync.operationalCacheLock.Unlock()
+
+	if len(expired) > 0 {
+		log.Infof("Expired %d operational state values of %s", len(expired), string(sync.key))
+	}
+	for _, path := range expired {
+		sync.operationalStateChan <- events.NewOperationalStateEvent(string(sync.Device.ID), path, nil, events.EventItemDeleted)
+	}
+}
+
+// markOpStateStale marks the cached values of a device as no longer kept up to date.
+// Values received afterwards are fresh again
+func markOpStateStale(info OpStateInfoMap) {
+	for path, pathInfo := range info {
+		pathInfo.Stale = true
+		info[path] = pathInfo
+	}
+}
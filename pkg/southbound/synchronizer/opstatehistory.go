@@ -0,0 +1,163 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+)
+
+// OpStateSample is a value of the operational state of a device at a point in time
+type OpStateSample struct {
+	// Path is the path of the value
+	Path string
+	// Value is the value, nil if the path was deleted
+	Value *devicechange.TypedValue
+	// Timestamp is when the device sampled the value, in nanoseconds since the epoch
+	Timestamp int64
+}
+
+// OpStateHistory keeps the recent values of the operational state of devices, up to a
+// number of samples and an age per path
+type OpStateHistory struct {
+	maxSamples int
+	maxAge     time.Duration
+	devices    map[topodevice.ID]map[string]*sampleRing
+	mu         sync.RWMutex
+}
+
+// NewOpStateHistory returns a history keeping at most maxSamples samples per path, none
+// older than maxAge. Zero leaves either bound unset, but one of them has to be set
+func NewOpStateHistory(maxSamples int, maxAge time.Duration) *OpStateHistory {
+	if maxSamples <= 0 && maxAge <= 0 {
+		return nil
+	}
+	return &OpStateHistory{
+		maxSamples: maxSamples,
+		maxAge:     maxAge,
+		devices:    make(map[topodevice.ID]map[string]*sampleRing),
+	}
+}
+
+// Record adds a sample of a path of a device
+func (h *OpStateHistory) Record(deviceID topodevice.ID, sample OpStateSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	paths, ok := h.devices[deviceID]
+	if !ok {
+		paths = make(map[string]*sampleRing)
+		h.devices[deviceID] = paths
+	}
+	ring, ok := paths[sample.Path]
+	if !ok {
+		ring = &sampleRing{}
+		paths[sample.Path] = ring
+	}
+	ring.add(sample, h.maxSamples)
+	if h.maxAge > 0 {
+		ring.expire(time.Now().Add(-h.maxAge).UnixNano())
+	}
+}
+
+// Query returns the samples of the paths of a device matching a pattern taken between
+// from and to, oldest first. A zero from or to leaves that end of the range open
+func (h *OpStateHistory) Query(deviceID topodevice.ID, pathRegexp *regexp.Regexp, from time.Time, to time.Time) []OpStateSample {
+	var oldest int64
+	if h.maxAge > 0 {
+		oldest = time.Now().Add(-h.maxAge).UnixNano()
+	}
+	if !from.IsZero() && from.UnixNano() > oldest {
+		oldest = from.UnixNano()
+	}
+	h.mu.RLock()
+	samples := make([]OpStateSample, 0)
+	for path, ring := range h.devices[deviceID] {
+		if !pathRegexp.MatchString(path) {
+			continue
+		}
+		for _, sample := range ring.ordered() {
+			if sample.Timestamp >= oldest && (to.IsZero() || sample.Timestamp <= to.UnixNano()) {
+				samples = append(samples, sample)
+			}
+		}
+	}
+	h.mu.RUnlock()
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp < samples[j].Timestamp
+	})
+	return samples
+}
+
+// Purge removes the history of a device
+func (h *OpStateHistory) Purge(deviceID topodevice.ID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.devices, deviceID)
+}
+
+// sampleRing is a ring buffer of the samples of a path
+type sampleRing struct {
+	samples []OpStateSample
+	// next is where the next sample is written once the ring is full
+	next int
+}
+
+// add adds a sample, overwriting the oldest one if there are already max samples. A sample
+// repeating the last one, e.g. replayed by a mirror, is not added again
+func (r *sampleRing) add(sample OpStateSample, max int) {
+	if last := r.last(); last != nil && last.Timestamp == sample.Timestamp && typedValuesEqual(last.Value, sample.Value) {
+		return
+	}
+	if max <= 0 || len(r.samples) < max {
+		r.samples = append(r.samples, sample)
+		return
+	}
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % max
+}
+
+// last returns the newest sample, if any
+func (r *sampleRing) last() *OpStateSample {
+	if len(r.samples) == 0 {
+		return nil
+	}
+	return &r.samples[(r.next+len(r.samples)-1)%len(r.samples)]
+}
+
+// ordered returns the samples oldest first
+func (r *sampleRing) ordered() []OpStateSample {
+	samples := make([]OpStateSample, 0, len(r.samples))
+	samples = append(samples, r.samples[r.next:]...)
+	return append(samples, r.samples[:r.next]...)
+}
+
+// expire drops the samples taken before the given time
+func (r *sampleRing) expire(before int64) {
+	samples := r.ordered()
+	i := 0
+	for i < len(samples) && samples[i].Timestamp < before {
+		i++
+	}
+	if i == 0 {
+		return
+	}
+	r.samples = samples[i:]
+	r.next = 0
+}
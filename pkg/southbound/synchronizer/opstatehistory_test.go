@@ -0,0 +1,106 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"gotest.tools/assert"
+)
+
+const (
+	operStatusPath = "/interfaces/interface[name=eth1]/state/oper-status"
+	inOctetsPath   = "/interfaces/interface[name=eth1]/state/counters/in-octets"
+)
+
+func Test_OpStateHistory(t *testing.T) {
+	assert.Assert(t, NewOpStateHistory(0, 0) == nil)
+
+	history := NewOpStateHistory(3, 0)
+	start := time.Now().Add(-time.Minute)
+	for i := 0; i < 5; i++ {
+		history.Record(device1, OpStateSample{
+			Path:      inOctetsPath,
+			Value:     devicechange.NewTypedValueUint(uint(i*100), 64),
+			Timestamp: start.Add(time.Duration(i) * time.Second).UnixNano(),
+		})
+	}
+	history.Record(device1, OpStateSample{
+		Path:      operStatusPath,
+		Value:     devicechange.NewTypedValueString("UP"),
+		Timestamp: start.Add(1500 * time.Millisecond).UnixNano(),
+	})
+
+	// Only the last samples of each path are kept, oldest first across paths
+	samples := history.Query(device1, utils.MatchWildcardRegexp("/interfaces/interface[name=*]/state", false), time.Time{}, time.Time{})
+	assert.Equal(t, len(samples), 4)
+	assert.Equal(t, samples[0].Path, operStatusPath)
+	assert.Equal(t, samples[1].Value.ValueToString(), "200")
+	assert.Equal(t, samples[3].Value.ValueToString(), "400")
+
+	// Repeated samples are not recorded twice
+	history.Record(device1, samples[3])
+	samples = history.Query(device1, utils.MatchWildcardRegexp(inOctetsPath, false), time.Time{}, time.Time{})
+	assert.Equal(t, len(samples), 3)
+
+	// Time ranges are inclusive
+	samples = history.Query(device1, utils.MatchWildcardRegexp(inOctetsPath, false),
+		start.Add(3*time.Second), start.Add(3*time.Second))
+	assert.Equal(t, len(samples), 1)
+	assert.Equal(t, samples[0].Value.ValueToString(), "300")
+
+	history.Purge(device1)
+	assert.Equal(t, len(history.Query(device1, utils.MatchWildcardRegexp("/", false), time.Time{}, time.Time{})), 0)
+}
+
+func Test_OpStateHistoryAge(t *testing.T) {
+	history := NewOpStateHistory(0, time.Minute)
+	now := time.Now()
+	history.Record(device1, OpStateSample{
+		Path:      operStatusPath,
+		Value:     devicechange.NewTypedValueString("DOWN"),
+		Timestamp: now.Add(-2 * time.Minute).UnixNano(),
+	})
+	history.Record(device1, OpStateSample{
+		Path:      operStatusPath,
+		Value:     devicechange.NewTypedValueString("UP"),
+		Timestamp: now.UnixNano(),
+	})
+	samples := history.Query(device1, utils.MatchWildcardRegexp(operStatusPath, false), time.Time{}, time.Time{})
+	assert.Equal(t, len(samples), 1)
+	assert.Equal(t, samples[0].Value.ValueToString(), "UP")
+}
+
+func Test_cacheValueHistory(t *testing.T) {
+	s := &Synchronizer{
+		Device:               &topodevice.Device{ID: device1},
+		operationalCache:     make(devicechange.TypedValueMap),
+		operationalCacheLock: &sync.RWMutex{},
+		history:              NewOpStateHistory(10, 0),
+	}
+	s.cacheValue(operStatusPath, devicechange.NewTypedValueString("UP"), 0)
+	s.uncacheValue(operStatusPath)
+
+	samples := s.history.Query(device1, utils.MatchWildcardRegexp(operStatusPath, false), time.Time{}, time.Time{})
+	assert.Equal(t, len(samples), 2)
+	assert.Equal(t, samples[0].Value.ValueToString(), "UP")
+	// Deletions are recorded without a value
+	assert.Assert(t, samples[1].Value == nil)
+}
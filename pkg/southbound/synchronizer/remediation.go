@@ -0,0 +1,260 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/store/change/device/state"
+	"github.com/onosproject/onos-config/pkg/store/change/network"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// RemediationPolicy decides when the intended configuration is pushed to a device again
+type RemediationPolicy string
+
+const (
+	// RemediationNone never pushes the intended configuration again
+	RemediationNone RemediationPolicy = "none"
+	// RemediationOnConnect pushes the intended configuration every time the device connects
+	RemediationOnConnect RemediationPolicy = "connect"
+	// RemediationOnDrift pushes the intended configuration when the device has drifted from it
+	RemediationOnDrift RemediationPolicy = "drift"
+	// RemediationAlways pushes the intended configuration on connection and on drift
+	RemediationAlways RemediationPolicy = "always"
+)
+
+// remediationPolicyKey is the device attribute that overrides the remediation policy of its model
+const remediationPolicyKey = "onos-config.remediation"
+
+// RemediationChangePrefix prefixes the ID of the NetworkChanges created by remediation
+const RemediationChangePrefix = "remediation-"
+
+// ParseRemediationPolicy parses a remediation policy name
+func ParseRemediationPolicy(policy string) (RemediationPolicy, error) {
+	switch p := RemediationPolicy(policy); p {
+	case RemediationNone, RemediationOnConnect, RemediationOnDrift, RemediationAlways:
+		return p, nil
+	case "":
+		return RemediationNone, nil
+	}
+	return "", errors.NewInvalid("unknown remediation policy %s", policy)
+}
+
+// ParseModelRemediationPolicies parses a list of model policies in the form
+// <type>-<version>=<policy>,<type>-<version>=<policy>
+func ParseModelRemediationPolicies(policies string) (map[string]RemediationPolicy, error) {
+	modelPolicies := make(map[string]RemediationPolicy)
+	if policies == "" {
+		return modelPolicies, nil
+	}
+	for _, modelPolicy := range strings.Split(policies, ",") {
+		parts := strings.SplitN(modelPolicy, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.NewInvalid("invalid model remediation policy %s", modelPolicy)
+		}
+		policy, err := ParseRemediationPolicy(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		modelPolicies[parts[0]] = policy
+	}
+	return modelPolicies, nil
+}
+
+func (p RemediationPolicy) onConnect() bool {
+	return p == RemediationOnConnect || p == RemediationAlways
+}
+
+func (p RemediationPolicy) onDrift() bool {
+	return p == RemediationOnDrift || p == RemediationAlways
+}
+
+// The reasons a device is remediated for
+const (
+	remediateOnConnect = "on connect"
+	remediateOnDrift   = "on drift"
+)
+
+// remediationBackoffMin and remediationBackoffMax bound the delay before a device is remediated
+// again after a remediation failed, or completed but left the device drifted. The delay doubles
+// with every consecutive such remediation
+var (
+	remediationBackoffMin = 30 * time.Second
+	remediationBackoffMax = 30 * time.Minute
+)
+
+// remediator pushes the full intended configuration of a device again by
+// recording it as a system generated NetworkChange. The intended configuration is read
+// from the device state store, like the configuration drift is checked against
+type remediator struct {
+	device             *topodevice.Device
+	deviceStateStore   state.Store
+	networkChangeStore network.Store
+	// pending is the last remediation made by the remediator, until its outcome is known
+	pending networkchange.ID
+	// failures counts the consecutive remediations that did not succeed, and retryAt is the
+	// time before which the device is not remediated again
+	failures  int
+	retryAt   time.Time
+	recovered bool
+	mu        sync.Mutex
+}
+
+// remediate creates a NetworkChange holding the intended configuration of the device.
+// Nothing is created while a remediation of the device is in flight, made by this node or
+// by another one, or while remediation is backed off after failures
+func (r *remediator) remediate(reason string) (*networkchange.NetworkChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inFlight, err := r.update(reason)
+	if err != nil {
+		return nil, err
+	} else if inFlight {
+		log.Infof("A remediation of %s is still pending", r.device.ID)
+		return nil, nil
+	}
+	if time.Now().Before(r.retryAt) {
+		log.Infof("Remediation of %s is backed off until %s", r.device.ID, r.retryAt.Format(time.RFC3339))
+		return nil, nil
+	}
+
+	versionedID := devicetype.NewVersionedID(devicetype.ID(r.device.ID), devicetype.Version(r.device.Version))
+	intended, err := r.deviceStateStore.Get(versionedID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(intended) == 0 {
+		log.Infof("No configuration to remediate on %s", r.device.ID)
+		return nil, nil
+	}
+
+	changeValues := make([]*devicechange.ChangeValue, 0, len(intended))
+	for _, pathValue := range intended {
+		changeValues = append(changeValues, &devicechange.ChangeValue{
+			Path:  pathValue.Path,
+			Value: pathValue.Value,
+		})
+	}
+	change := &devicechange.Change{
+		DeviceID:      devicetype.ID(r.device.ID),
+		DeviceVersion: devicetype.Version(r.device.Version),
+		DeviceType:    devicetype.Type(r.device.Type),
+		Values:        changeValues,
+	}
+	networkChangeID := fmt.Sprintf("%s%s", RemediationChangePrefix, uuid.New().String())
+	networkChange, err := networkchange.NewNetworkChange(networkChangeID, []*devicechange.Change{change})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.networkChangeStore.Create(networkChange); err != nil {
+		return nil, err
+	}
+	log.Infof("Remediating %d paths on %s %s with %s", len(changeValues), r.device.ID, reason, networkChange.ID)
+	r.pending = networkChange.ID
+	return networkChange, nil
+}
+
+// update accounts for the outcome of the last remediation of the device, and returns whether a
+// remediation of the device is still in flight
+func (r *remediator) update(reason string) (bool, error) {
+	if !r.recovered {
+		if err := r.recover(); err != nil {
+			return false, err
+		}
+		r.recovered = true
+	}
+
+	if r.pending != "" {
+		pending, err := r.networkChangeStore.Get(r.pending)
+		if err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		if pending != nil {
+			switch pending.Status.State {
+			case changetypes.State_PENDING:
+				return true, nil
+			case changetypes.State_FAILED:
+				r.fail(pending.ID)
+			case changetypes.State_COMPLETE:
+				// Drift right after a complete remediation means the device does not keep the configuration
+				if reason == remediateOnDrift {
+					r.fail(pending.ID)
+				} else {
+					r.failures = 0
+				}
+			}
+		}
+		r.pending = ""
+	}
+
+	// Remediations may also be made by the session of another node or before a restart
+	result, err := r.networkChangeStore.Query(network.Query{
+		DeviceID: devicetype.ID(r.device.ID),
+		States:   []changetypes.State{changetypes.State_PENDING},
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, change := range result.Changes {
+		if strings.HasPrefix(string(change.ID), RemediationChangePrefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recover picks up the last change of the device if it is a remediation, so that a remediation
+// made before the session started is followed up like one made by the session
+func (r *remediator) recover() error {
+	result, err := r.networkChangeStore.Query(network.Query{
+		DeviceID: devicetype.ID(r.device.ID),
+		Order:    network.Descending,
+		Limit:    1,
+	})
+	if err != nil {
+		return err
+	}
+	for _, change := range result.Changes {
+		if strings.HasPrefix(string(change.ID), RemediationChangePrefix) {
+			r.pending = change.ID
+		}
+	}
+	return nil
+}
+
+// fail backs off remediation of the device after a remediation that did not succeed
+func (r *remediator) fail(id networkchange.ID) {
+	r.failures++
+	delay := remediationBackoffMin
+	for i := 1; i < r.failures && delay < remediationBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > remediationBackoffMax {
+		delay = remediationBackoffMax
+	}
+	r.retryAt = time.Now().Add(delay)
+	log.Warnf("Remediation %s of %s did not succeed, backing off for %s", id, r.device.ID, delay)
+}
@@ -0,0 +1,174 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"github.com/golang/mock/gomock"
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/store/change/network"
+	storemock "github.com/onosproject/onos-config/pkg/test/mocks/store"
+	"gotest.tools/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ParseRemediationPolicies(t *testing.T) {
+	policy, err := ParseRemediationPolicy("")
+	assert.NilError(t, err)
+	assert.Equal(t, policy, RemediationNone)
+	policy, err = ParseRemediationPolicy("drift")
+	assert.NilError(t, err)
+	assert.Equal(t, policy, RemediationOnDrift)
+	_, err = ParseRemediationPolicy("sometimes")
+	assert.ErrorContains(t, err, "unknown remediation policy")
+
+	policies, err := ParseModelRemediationPolicies("Devicesim-1.0.0=connect,Stratum-1.0.0=always")
+	assert.NilError(t, err)
+	assert.Equal(t, len(policies), 2)
+	assert.Equal(t, policies["Stratum-1.0.0"], RemediationAlways)
+	_, err = ParseModelRemediationPolicies("Devicesim-1.0.0")
+	assert.ErrorContains(t, err, "invalid model remediation policy")
+}
+
+func Test_getRemediationPolicy(t *testing.T) {
+	sm := &SessionManager{
+		remediationPolicy: RemediationOnConnect,
+		modelRemediationPolicies: map[string]RemediationPolicy{
+			"Devicesim-1.0.0": RemediationOnDrift,
+		},
+	}
+	device := &topodevice.Device{ID: device1, Type: "Stratum", Version: "1.0.0"}
+	assert.Equal(t, sm.getRemediationPolicy(device), RemediationOnConnect)
+	device.Type = "Devicesim"
+	assert.Equal(t, sm.getRemediationPolicy(device), RemediationOnDrift)
+	device.Attributes = map[string]string{remediationPolicyKey: "none"}
+	assert.Equal(t, sm.getRemediationPolicy(device), RemediationNone)
+}
+
+func Test_remediate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	device := &topodevice.Device{ID: device1, Type: "Devicesim", Version: "1.0.0"}
+
+	// The intended configuration is the state of the device, which includes its snapshot
+	deviceStateStore := storemock.NewMockDeviceStateStore(ctrl)
+	deviceStateStore.EXPECT().Get(devicetype.NewVersionedID(device1, "1.0.0"), networkchange.Revision(0)).Return(
+		[]*devicechange.PathValue{
+			{Path: cont1aCont2aLeaf2a, Value: devicechange.NewTypedValueString("value2a")},
+		}, nil).Times(2)
+
+	var created *networkchange.NetworkChange
+	var pendingChanges []*networkchange.NetworkChange
+	networkChangeStore := storemock.NewMockNetworkChangesStore(ctrl)
+	networkChangeStore.EXPECT().Create(gomock.Any()).DoAndReturn(
+		func(change *networkchange.NetworkChange) error {
+			created = change
+			change.Status.State = changetypes.State_PENDING
+			return nil
+		}).Times(2)
+	networkChangeStore.EXPECT().Get(gomock.Any()).DoAndReturn(
+		func(id networkchange.ID) (*networkchange.NetworkChange, error) {
+			assert.Equal(t, id, created.ID)
+			return created, nil
+		}).AnyTimes()
+	networkChangeStore.EXPECT().Query(gomock.Any()).DoAndReturn(
+		func(query network.Query) (*network.QueryResult, error) {
+			assert.Equal(t, query.DeviceID, devicetype.ID(device1))
+			if query.Limit == 1 {
+				return &network.QueryResult{}, nil
+			}
+			return &network.QueryResult{Changes: pendingChanges}, nil
+		}).AnyTimes()
+
+	r := &remediator{
+		device:             device,
+		deviceStateStore:   deviceStateStore,
+		networkChangeStore: networkChangeStore,
+	}
+	change, err := r.remediate(remediateOnConnect)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(change.ID), RemediationChangePrefix))
+	assert.Equal(t, len(change.Changes), 1)
+	assert.Equal(t, change.Changes[0].DeviceType, devicetype.Type("Devicesim"))
+	assert.Equal(t, change.Changes[0].Values[0].Path, cont1aCont2aLeaf2a)
+
+	// A second remediation is skipped while the first one is pending
+	change, err = r.remediate(remediateOnDrift)
+	assert.NilError(t, err)
+	assert.Assert(t, change == nil)
+
+	// Remediation backs off after a failure
+	created.Status.State = changetypes.State_FAILED
+	change, err = r.remediate(remediateOnDrift)
+	assert.NilError(t, err)
+	assert.Assert(t, change == nil)
+	assert.Equal(t, r.failures, 1)
+	assert.Assert(t, r.retryAt.After(time.Now().Add(remediationBackoffMin-time.Second)))
+
+	r.retryAt = time.Now()
+	change, err = r.remediate(remediateOnDrift)
+	assert.NilError(t, err)
+	assert.Assert(t, change != nil)
+
+	// Drift right after a complete remediation doubles the delay
+	created.Status.State = changetypes.State_COMPLETE
+	change, err = r.remediate(remediateOnDrift)
+	assert.NilError(t, err)
+	assert.Assert(t, change == nil)
+	assert.Equal(t, r.failures, 2)
+	assert.Assert(t, r.retryAt.After(time.Now().Add(2*remediationBackoffMin-time.Second)))
+
+	// A remediation pending from another node is in flight too
+	r.retryAt = time.Now()
+	pendingChanges = []*networkchange.NetworkChange{{ID: RemediationChangePrefix + "other"}}
+	change, err = r.remediate(remediateOnConnect)
+	assert.NilError(t, err)
+	assert.Assert(t, change == nil)
+}
+
+func Test_remediateRecover(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	device := &topodevice.Device{ID: device1, Type: "Devicesim", Version: "1.0.0"}
+
+	failed := &networkchange.NetworkChange{
+		ID:     RemediationChangePrefix + "failed",
+		Status: changetypes.Status{State: changetypes.State_FAILED},
+	}
+	networkChangeStore := storemock.NewMockNetworkChangesStore(ctrl)
+	networkChangeStore.EXPECT().Query(gomock.Any()).DoAndReturn(
+		func(query network.Query) (*network.QueryResult, error) {
+			if query.Limit == 1 {
+				assert.Equal(t, query.Order, network.Descending)
+				return &network.QueryResult{Changes: []*networkchange.NetworkChange{failed}}, nil
+			}
+			return &network.QueryResult{}, nil
+		}).AnyTimes()
+	networkChangeStore.EXPECT().Get(failed.ID).Return(failed, nil).Times(1)
+
+	// A remediation that failed before the session started backs off the next one
+	r := &remediator{
+		device:             device,
+		deviceStateStore:   storemock.NewMockDeviceStateStore(ctrl),
+		networkChangeStore: networkChangeStore,
+	}
+	change, err := r.remediate(remediateOnConnect)
+	assert.NilError(t, err)
+	assert.Assert(t, change == nil)
+	assert.Equal(t, r.failures, 1)
+}
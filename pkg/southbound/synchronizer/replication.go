@@ -0,0 +1,287 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-config/pkg/store/opstate"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+)
+
+// DefaultOpStateReplicationInterval is the default interval at which the operational state
+// collected by the master of a device is replicated to the other nodes
+const DefaultOpStateReplicationInterval = time.Second
+
+// opStateReplicator replicates the operational state cache of a device mastered by the
+// local node in to the operational state store. The whole state of the device is written
+// as a single snapshot when any of its values changed, so that the store gets at most one
+// write per device at every interval however many values change
+type opStateReplicator struct {
+	deviceID   topodevice.ID
+	store      opstate.Store
+	cache      devicechange.TypedValueMap
+	info       OpStateInfoMap
+	lock       *sync.RWMutex
+	interval   time.Duration
+	replicated map[string]*devicechange.TypedValue
+}
+
+// run replicates the cache at every interval until the context is done
+func (r *opStateReplicator) run(ctx context.Context) {
+	if r.interval <= 0 {
+		r.interval = DefaultOpStateReplicationInterval
+	}
+	r.replicated = r.loadReplicated()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.replicate()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// loadReplicated returns the values of the device already in the store, e.g. from a previous
+// master, so that the state is only written again if it differs from the cache
+func (r *opStateReplicator) loadReplicated() map[string]*devicechange.TypedValue {
+	replicated := make(map[string]*devicechange.TypedValue)
+	snapshot, err := r.store.Get(r.deviceID)
+	if err != nil {
+		log.Warnf("Getting the replicated operational state of %s failed: %v", r.deviceID, err)
+		return replicated
+	}
+	for _, value := range snapshot.Values {
+		replicated[value.Path] = value.Value
+	}
+	return replicated
+}
+
+// replicate writes the state of the device to the store if it changed since the last replication
+func (r *opStateReplicator) replicate() {
+	r.lock.RLock()
+	changed := len(r.cache) != len(r.replicated)
+	values := make([]*opstate.StateValue, 0, len(r.cache))
+	for path, value := range r.cache {
+		if !typedValuesEqual(r.replicated[path], value) {
+			changed = true
+		}
+		values = append(values, &opstate.StateValue{
+			Path:      path,
+			Value:     value,
+			Timestamp: r.info[path].Timestamp,
+		})
+	}
+	r.lock.RUnlock()
+	if !changed {
+		return
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Path < values[j].Path
+	})
+	if err := r.store.Put(&opstate.Snapshot{DeviceID: r.deviceID, Values: values}); err != nil {
+		log.Warnf("Replicating the operational state of %s failed: %v", r.deviceID, err)
+		return
+	}
+	r.replicated = make(map[string]*devicechange.TypedValue)
+	for _, value := range values {
+		r.replicated[value.Path] = value.Value
+	}
+}
+
+// typedValuesEqual indicates whether two values are the same
+func typedValuesEqual(a *devicechange.TypedValue, b *devicechange.TypedValue) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type != b.Type || !bytes.Equal(a.Bytes, b.Bytes) || len(a.TypeOpts) != len(b.TypeOpts) {
+		return false
+	}
+	for i := range a.TypeOpts {
+		if a.TypeOpts[i] != b.TypeOpts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// opStateMirror keeps the operational state cache of a device mastered by another node
+// up to date from the operational state store, so that any node serves the same state
+type opStateMirror struct {
+	deviceID    topodevice.ID
+	store       opstate.Store
+	isMaster    func() bool
+	cache       map[topodevice.ID]devicechange.TypedValueMap
+	info        map[topodevice.ID]OpStateInfoMap
+	lock        *sync.RWMutex
+	opStateChan chan<- events.OperationalStateEvent
+	history     *OpStateHistory
+	interval    time.Duration
+}
+
+// run mirrors the state of the device while the local node is not its master, until
+// the context is done
+func (m *opStateMirror) run(ctx context.Context) {
+	if m.interval <= 0 {
+		m.interval = DefaultOpStateReplicationInterval
+	}
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		if !m.isMaster() {
+			m.mirror(ctx)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// mirror watches the state of the device in the store until the context is done, the local
+// node becomes the master of the device or the mirrored cache is dropped by a session
+func (m *opStateMirror) mirror(ctx context.Context) {
+	ch := make(chan stream.Event)
+	streamCtx, err := m.store.Watch(m.deviceID, ch)
+	if err != nil {
+		log.Warnf("Watching the replicated operational state of %s failed: %v", m.deviceID, err)
+		return
+	}
+	defer func() {
+		streamCtx.Close()
+		go func() {
+			for range ch {
+			}
+		}()
+	}()
+
+	// The watch replays the current values in to a new cache
+	values := make(devicechange.TypedValueMap)
+	m.lock.Lock()
+	m.cache[m.deviceID] = values
+	if m.info != nil {
+		m.info[m.deviceID] = make(OpStateInfoMap)
+	}
+	m.lock.Unlock()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			m.apply(values, event)
+		case <-ticker.C:
+			if m.isMaster() || !m.isMirrored(values) {
+				return
+			}
+		case <-ctx.Done():
+			m.lock.Lock()
+			if m.isMirroredLocked(values) {
+				delete(m.cache, m.deviceID)
+				if m.info != nil {
+					delete(m.info, m.deviceID)
+				}
+			}
+			m.lock.Unlock()
+			return
+		}
+	}
+}
+
+// apply applies a snapshot of the store to the mirrored cache. Only the values that
+// differ from the cache are updated
+func (m *opStateMirror) apply(values devicechange.TypedValueMap, event stream.Event) {
+	snapshot := event.Object.(*opstate.Snapshot)
+	received := time.Now()
+	samples := make([]OpStateSample, 0)
+	stateEvents := make([]events.OperationalStateEvent, 0)
+	m.lock.Lock()
+	if !m.isMirroredLocked(values) {
+		m.lock.Unlock()
+		return
+	}
+	info := m.info[m.deviceID]
+	paths := make(map[string]bool)
+	for _, value := range snapshot.Values {
+		paths[value.Path] = true
+		if cached, ok := values[value.Path]; ok && typedValuesEqual(cached, value.Value) {
+			continue
+		}
+		timestamp := value.Timestamp
+		if timestamp == 0 {
+			timestamp = received.UnixNano()
+		}
+		values[value.Path] = value.Value
+		if info != nil {
+			info[value.Path] = OpStateInfo{
+				Timestamp: timestamp,
+				Received:  received,
+			}
+		}
+		samples = append(samples, OpStateSample{Path: value.Path, Value: value.Value, Timestamp: timestamp})
+		stateEvents = append(stateEvents, events.NewOperationalStateEventWithTimestamp(string(m.deviceID), value.Path,
+			value.Value, events.EventItemUpdated, timestamp))
+	}
+	for path := range values {
+		if !paths[path] {
+			delete(values, path)
+			delete(info, path)
+			samples = append(samples, OpStateSample{Path: path, Timestamp: received.UnixNano()})
+			stateEvents = append(stateEvents, events.NewOperationalStateEvent(string(m.deviceID), path, nil, events.EventItemDeleted))
+		}
+	}
+	m.lock.Unlock()
+	if m.history != nil {
+		for _, sample := range samples {
+			m.history.Record(m.deviceID, sample)
+		}
+	}
+
+	// Replayed values were already known to the master, so they are not notified again
+	if event.Type == stream.None {
+		return
+	}
+	for _, stateEvent := range stateEvents {
+		m.opStateChan <- stateEvent
+	}
+}
+
+// isMirrored indicates whether the given values are still the cache of the device
+func (m *opStateMirror) isMirrored(values devicechange.TypedValueMap) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.isMirroredLocked(values)
+}
+
+func (m *opStateMirror) isMirroredLocked(values devicechange.TypedValueMap) bool {
+	cached, ok := m.cache[m.deviceID]
+	return ok && reflect.ValueOf(cached).Pointer() == reflect.ValueOf(values).Pointer()
+}
@@ -0,0 +1,128 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"github.com/onosproject/onos-config/pkg/store/opstate"
+	"gotest.tools/assert"
+)
+
+func Test_replicateOpState(t *testing.T) {
+	const (
+		mtuPath      = "/interfaces/interface[name=eth1]/state/mtu"
+		hostnamePath = "/system/state/hostname"
+	)
+	store, err := opstate.NewLocalStore()
+	assert.NilError(t, err)
+	defer store.Close()
+
+	// The master replicates its cache
+	masterLock := &sync.RWMutex{}
+	masterCache := devicechange.TypedValueMap{
+		mtuPath:      devicechange.NewTypedValueUint(1500, 16),
+		hostnamePath: devicechange.NewTypedValueString("switch1"),
+	}
+	replicator := &opStateReplicator{
+		deviceID: device1,
+		store:    store,
+		cache:    masterCache,
+		info:     OpStateInfoMap{mtuPath: {Timestamp: 1000}},
+		lock:     masterLock,
+	}
+	replicator.replicated = replicator.loadReplicated()
+	replicator.replicate()
+
+	// The state of the device is replicated as a single snapshot
+	snapshot, err := store.Get(device1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snapshot.Values), 2)
+	assert.Equal(t, snapshot.Values[0].Path, mtuPath)
+	assert.Equal(t, snapshot.Values[0].Timestamp, int64(1000))
+
+	// Another node mirrors it
+	opStateChan := make(chan events.OperationalStateEvent, 10)
+	cache := make(map[topodevice.ID]devicechange.TypedValueMap)
+	info := make(map[topodevice.ID]OpStateInfoMap)
+	lock := &sync.RWMutex{}
+	mirror := &opStateMirror{
+		deviceID:    device1,
+		store:       store,
+		isMaster:    func() bool { return false },
+		cache:       cache,
+		info:        info,
+		lock:        lock,
+		opStateChan: opStateChan,
+		interval:    10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mirror.run(ctx)
+
+	mirrored := func(path string) string {
+		lock.RLock()
+		defer lock.RUnlock()
+		if value, ok := cache[device1][path]; ok {
+			return value.ValueToString()
+		}
+		return ""
+	}
+	waitFor(t, func() bool { return mirrored(mtuPath) == "1500" && mirrored(hostnamePath) == "switch1" })
+	lock.RLock()
+	assert.Equal(t, info[device1][mtuPath].Timestamp, int64(1000))
+	lock.RUnlock()
+	// The replayed values are not notified
+	assert.Equal(t, len(opStateChan), 0)
+
+	// Changes and deletions are mirrored and notified
+	masterLock.Lock()
+	masterCache[mtuPath] = devicechange.NewTypedValueUint(9000, 16)
+	delete(masterCache, hostnamePath)
+	masterLock.Unlock()
+	replicator.replicate()
+	waitFor(t, func() bool { return mirrored(mtuPath) == "9000" && mirrored(hostnamePath) == "" })
+	waitFor(t, func() bool { return len(opStateChan) == 2 })
+
+	// Unchanged values are not replicated again
+	replicator.replicate()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, len(opStateChan), 2)
+
+	// The mirrored cache is dropped when mirroring stops
+	cancel()
+	waitFor(t, func() bool {
+		lock.RLock()
+		defer lock.RUnlock()
+		_, ok := cache[device1]
+		return !ok
+	})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("condition not met in time")
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	configmodel "github.com/onosproject/onos-config-model/pkg/model"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/openconfig/gnmi/proto/gnmi"
 	"strconv"
 	"sync"
 	"time"
@@ -40,6 +41,9 @@ import (
 	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
 	topodevice "github.com/onosproject/onos-config/pkg/device"
 	"github.com/onosproject/onos-config/pkg/store/change/device"
+	"github.com/onosproject/onos-config/pkg/store/change/device/state"
+	"github.com/onosproject/onos-config/pkg/store/change/network"
+	"github.com/onosproject/onos-config/pkg/store/opstate"
 )
 
 const (
@@ -51,22 +55,45 @@ const (
 
 // Session a gNMI session
 type Session struct {
-	deviceStore               devicestore.Store
-	mastershipState           *mastership.Mastership
-	nodeID                    cluster.NodeID
-	connected                 bool
-	opStateChan               chan<- events.OperationalStateEvent
-	deviceResponseChan        chan events.DeviceResponse
-	dispatcher                *dispatcher.Dispatcher
-	modelRegistry             *modelregistry.ModelRegistry
-	operationalStateCache     map[topodevice.ID]devicechange.TypedValueMap
-	operationalStateCacheLock *sync.RWMutex
-	deviceChangeStore         device.Store
-	device                    *topodevice.Device
-	target                    southbound.TargetIf
-	cancel                    context.CancelFunc
-	closed                    bool
-	mu                        sync.RWMutex
+	deviceStore                devicestore.Store
+	mastershipState            *mastership.Mastership
+	nodeID                     cluster.NodeID
+	connected                  bool
+	opStateChan                chan<- events.OperationalStateEvent
+	deviceResponseChan         chan events.DeviceResponse
+	dispatcher                 *dispatcher.Dispatcher
+	modelRegistry              *modelregistry.ModelRegistry
+	operationalStateCache      map[topodevice.ID]devicechange.TypedValueMap
+	operationalStateInfo       map[topodevice.ID]OpStateInfoMap
+	operationalStateCacheLock  *sync.RWMutex
+	deviceChangeStore          device.Store
+	deviceStateStore           state.Store
+	configDriftChan            chan<- events.ConfigDriftEvent
+	configDriftInterval        time.Duration
+	configDrift                *configDriftChecker
+	networkChangeStore         network.Store
+	remediationPolicy          RemediationPolicy
+	remediator                 *remediator
+	credentialsCheckInterval   time.Duration
+	opStateSubscription        OpStateSubscription
+	opStateStale               bool
+	resubscribe                chan struct{}
+	opStateStore               opstate.Store
+	opStateReplicationInterval time.Duration
+	opStateHistory             *OpStateHistory
+	southboundErrorChan        chan<- events.DeviceResponse
+	southboundErrors           *SouthboundErrors
+	strictModels               bool
+	autoDetectModels           bool
+	capabilities               *DeviceCapabilities
+	addresses                  []string
+	addressIndex               int
+	activeAddress              string
+	device                     *topodevice.Device
+	target                     southbound.TargetIf
+	cancel                     context.CancelFunc
+	closed                     bool
+	mu                         sync.RWMutex
 }
 
 func (s *Session) getCurrentTerm() (int, error) {
@@ -118,7 +145,7 @@ func (s *Session) open() error {
 
 // connect connects to a device using a gNMI session
 func (s *Session) connect() error {
-	log.Infof("Connecting to device: %s at %s", s.device.ID, s.device.Address)
+	log.Infof("Connecting to device: %s", s.device.ID)
 	count := 0
 	b := backoff.NewExponentialBackOff()
 	b.InitialInterval = backoffInterval
@@ -161,58 +188,312 @@ func (s *Session) synchronize() error {
 		}
 	}
 	var mReadOnlyPaths modelregistry.ReadOnlyPathMap
+	var mReadWritePaths modelregistry.ReadWritePathMap
+	var pluginModels []*gnmi.ModelData
 	mStateGetMode := configmodel.GetStateOpState // default
 	if plugin != nil {
+		pluginModels = plugin.Model.Data()
 		mReadOnlyPaths = plugin.ReadOnlyPaths
+		mReadWritePaths = plugin.ReadWritePaths
 		pluginStateGetMode := plugin.Model.GetStateMode()
 		if pluginStateGetMode != configmodel.GetStateNone {
 			mStateGetMode = pluginStateGetMode
 		}
 	}
-	valueMap := make(devicechange.TypedValueMap)
+	// The values cached before the device was disconnected are kept, stale, until they
+	// are received again or expire
 	s.operationalStateCacheLock.Lock()
-	s.operationalStateCache[s.device.ID] = valueMap
+	valueMap, ok := s.operationalStateCache[s.device.ID]
+	if !ok {
+		valueMap = make(devicechange.TypedValueMap)
+		s.operationalStateCache[s.device.ID] = valueMap
+	}
+	var infoMap OpStateInfoMap
+	if s.operationalStateInfo != nil {
+		infoMap, ok = s.operationalStateInfo[s.device.ID]
+		if !ok {
+			infoMap = make(OpStateInfoMap)
+			s.operationalStateInfo[s.device.ID] = infoMap
+		}
+	}
 	s.operationalStateCacheLock.Unlock()
 	s.mu.RUnlock()
 
-	sync, err := New(ctx, s.device, s.opStateChan, s.deviceResponseChan,
+	s.mu.Lock()
+	address := s.getAddress()
+	device := *s.device
+	device.Address = address
+	s.mu.Unlock()
+
+	log.Infof("Connecting to device: %s at %s", device.ID, address)
+	sync, err := New(ctx, &device, s.opStateChan, s.deviceResponseChan,
 		valueMap, mReadOnlyPaths, s.target, mStateGetMode, s.operationalStateCacheLock, s.deviceChangeStore)
 	if err != nil {
-		log.Errorf("Error connecting to device %v: %v", s.device, err)
-		//unregistering the listener for changes to the device
+		log.Errorf("Error connecting to device %v at %s: %v", s.device, address, err)
+		// The next attempt is made at the next address of the device
+		s.mu.Lock()
+		s.addressIndex++
+		s.mu.Unlock()
 		//unregistering the listener for changes to the device
 		s.dispatcher.UnregisterOperationalState(string(s.device.ID))
-		s.operationalStateCacheLock.Lock()
-		delete(s.operationalStateCache, s.device.ID)
-		s.operationalStateCacheLock.Unlock()
+		s.setOpStateStale(true)
 		return err
 	}
 
+	resubscribe := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.activeAddress = address
+	s.opStateStale = false
+	s.resubscribe = resubscribe
+	s.mu.Unlock()
+	sync.subscription = s.opStateSubscription
+	sync.resubscribe = resubscribe
+	sync.operationalInfo = infoMap
+	sync.history = s.opStateHistory
+	sync.setStale = s.setOpStateStale
+
+	if plugin == nil && s.autoDetectModels && needsModelDetection(s.device) {
+		s.detectModel(sync.capabilities)
+	}
+	capabilities := newDeviceCapabilities(sync.capabilities, pluginModels)
+	s.mu.Lock()
+	s.capabilities = capabilities
+	s.mu.Unlock()
+	if !capabilities.Compatible() {
+		log.Warnf("Device %s does not advertise the models of its plugin %s: %s",
+			s.device.ID, modelName, capabilities.mismatchSummary())
+		s.deviceResponseChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorModelMismatch, string(s.device.ID),
+			errors.NewInvalid("models of plugin %s not advertised: %s", modelName, capabilities.mismatchSummary()))
+	}
+
 	//spawning two go routines to propagate changes and to get operational state
 	//go sync.syncConfigEventsToDevice(target, respChan)
 	s.deviceResponseChan <- events.NewDeviceConnectedEvent(events.EventTypeDeviceConnected, string(s.device.ID))
-	if sync.getStateMode == configmodel.GetStateOpState {
+	if s.opStateSubscription.Poll {
+		go sync.syncOperationalStateByPolling(ctx, s.deviceResponseChan)
+	} else if sync.getStateMode == configmodel.GetStateOpState {
 		go sync.syncOperationalStateByPartition(ctx, s.deviceResponseChan)
 	} else if sync.getStateMode == configmodel.GetStateExplicitRoPaths ||
 		sync.getStateMode == configmodel.GetStateExplicitRoPathsExpandWildcards {
 		go sync.syncOperationalStateByPaths(ctx, s.deviceResponseChan)
 	}
+	go sync.expireOpState(ctx)
+	if s.opStateStore != nil {
+		replicator := &opStateReplicator{
+			deviceID: s.device.ID,
+			store:    s.opStateStore,
+			cache:    valueMap,
+			info:     infoMap,
+			lock:     s.operationalStateCacheLock,
+			interval: s.opStateReplicationInterval,
+		}
+		go replicator.run(ctx)
+	}
+
+	credentials := newCredentialsWatcher(s.getCertificateFiles, s.credentialsCheckInterval, func() {
+		if err := s.reconnect(); err != nil {
+			log.Warnf("Reconnecting %s with new certificates failed: %v", s.device.ID, err)
+		}
+	})
+	go credentials.run(ctx)
+
+	var remediator *remediator
+	if s.networkChangeStore != nil && s.deviceStateStore != nil && s.remediationPolicy != RemediationNone {
+		remediator = s.getRemediator()
+	}
+
+	var configDrift *configDriftChecker
+	if s.deviceStateStore != nil {
+		configDrift = &configDriftChecker{
+			ctx:            ctx,
+			device:         s.device,
+			target:         s.target,
+			stateStore:     s.deviceStateStore,
+			encoding:       sync.encoding,
+			readOnlyPaths:  mReadOnlyPaths,
+			readWritePaths: mReadWritePaths,
+			driftChan:      s.configDriftChan,
+			interval:       s.configDriftInterval,
+		}
+		if remediator != nil && s.remediationPolicy.onDrift() {
+			configDrift.onDrift = func(report events.ConfigDriftEvent) {
+				if _, err := remediator.remediate(remediateOnDrift); err != nil {
+					log.Warnf("Remediating drift of %s failed: %v", s.device.ID, err)
+				}
+			}
+		}
+		s.mu.Lock()
+		s.configDrift = configDrift
+		s.mu.Unlock()
+	}
+
+	go func() {
+		if remediator != nil && s.remediationPolicy.onConnect() {
+			if _, err := remediator.remediate(remediateOnConnect); err != nil {
+				log.Warnf("Remediating %s on connect failed: %v", s.device.ID, err)
+			}
+		}
+		if configDrift != nil {
+			configDrift.run()
+		}
+	}()
 	return nil
 }
 
+// getAddress returns the address the next connection attempt is made at
+func (s *Session) getAddress() string {
+	if len(s.addresses) == 0 {
+		return s.device.Address
+	}
+	return s.addresses[s.addressIndex%len(s.addresses)]
+}
+
+// getActiveAddress returns the address the device was last connected at
+func (s *Session) getActiveAddress() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.activeAddress
+}
+
+// getCertificateFiles returns the files the TLS material of the device is read from
+func (s *Session) getCertificateFiles() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return southbound.CertificateFiles(*s.device)
+}
+
+// updateCredentials applies new TLS settings and credentials of the device to the session
+func (s *Session) updateCredentials(device *topodevice.Device) error {
+	s.mu.Lock()
+	s.device.TLS = device.TLS
+	s.device.Credentials = device.Credentials
+	s.mu.Unlock()
+	return s.reconnect()
+}
+
+// reconnect connects the target of a synchronized session again to pick up new
+// certificates or credentials. The new gNMI client replaces the current one once it
+// is created, and the operational state subscriptions are then made again with it
+func (s *Session) reconnect() error {
+	s.mu.RLock()
+	synchronized := s.cancel != nil && !s.closed
+	device := *s.device
+	if s.activeAddress != "" {
+		device.Address = s.activeAddress
+	}
+	resubscribe := s.resubscribe
+	s.mu.RUnlock()
+	if !synchronized {
+		return nil
+	}
+	log.Infof("Reconnecting to device %s with new credentials", device.ID)
+	if _, err := s.target.ConnectTarget(*s.target.Context(), device); err != nil {
+		return err
+	}
+	if resubscribe != nil {
+		select {
+		case resubscribe <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// getRemediator returns the remediator of the session, keeping track of a
+// pending remediation across reconnections
+func (s *Session) getRemediator() *remediator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.remediator == nil {
+		s.remediator = &remediator{
+			device:             s.device,
+			deviceStateStore:   s.deviceStateStore,
+			networkChangeStore: s.networkChangeStore,
+		}
+	}
+	return s.remediator
+}
+
+// getCapabilities returns the capabilities the device advertised when it was last connected
+func (s *Session) getCapabilities() (*DeviceCapabilities, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.capabilities == nil {
+		return nil, errors.NewUnavailable("device %s is not synchronized", s.device.ID)
+	}
+	return s.capabilities, nil
+}
+
+// checkConfigDrift compares the running configuration of the device with the intended one
+func (s *Session) checkConfigDrift() (events.ConfigDriftEvent, error) {
+	s.mu.RLock()
+	configDrift := s.configDrift
+	s.mu.RUnlock()
+	if configDrift == nil {
+		return nil, errors.NewUnavailable("device %s is not synchronized", s.device.ID)
+	}
+	return configDrift.check()
+}
+
+// setOpStateStale records whether the operational state of the device is kept up to date
+func (s *Session) setOpStateStale(stale bool) {
+	s.mu.Lock()
+	s.opStateStale = stale
+	s.mu.Unlock()
+	if stale && s.operationalStateInfo != nil {
+		s.operationalStateCacheLock.Lock()
+		markOpStateStale(s.operationalStateInfo[s.device.ID])
+		s.operationalStateCacheLock.Unlock()
+	}
+}
+
+// isOpStateStale indicates whether the operational state of the device is no longer
+// kept up to date, e.g. while its subscription is being re-established
+func (s *Session) isOpStateStale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.opStateStale
+}
+
+// getRunningConfig returns the configuration the device is running
+func (s *Session) getRunningConfig() ([]*devicechange.PathValue, error) {
+	s.mu.RLock()
+	configDrift := s.configDrift
+	s.mu.RUnlock()
+	if configDrift == nil {
+		return nil, errors.NewUnavailable("device %s is not synchronized", s.device.ID)
+	}
+	return configDrift.getRunningConfig()
+}
+
+// getConfigDrift returns the last configuration drift report of the device
+func (s *Session) getConfigDrift() (events.ConfigDriftEvent, error) {
+	s.mu.RLock()
+	configDrift := s.configDrift
+	s.mu.RUnlock()
+	if configDrift == nil {
+		return nil, errors.NewUnavailable("device %s is not synchronized", s.device.ID)
+	}
+	report := configDrift.lastReport()
+	if report == nil {
+		return nil, errors.NewNotFound("configuration drift of device %s has not been checked", s.device.ID)
+	}
+	return report, nil
+}
+
 // disconnects the gNMI session from the device
 func (s *Session) disconnect() error {
 	log.Info("Disconnecting device:", s.device)
 	s.mu.Lock()
 	s.closed = true
+	s.configDrift = nil
 	if s.cancel != nil {
 		s.cancel()
 		s.cancel = nil
 	}
 	s.mu.Unlock()
-	s.operationalStateCacheLock.Lock()
-	delete(s.operationalStateCache, s.device.ID)
-	s.operationalStateCacheLock.Unlock()
+	// The operational state is kept until the device is removed
+	s.setOpStateStale(true)
 	return nil
 }
 
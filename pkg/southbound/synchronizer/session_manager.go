@@ -15,39 +15,69 @@
 package synchronizer
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
 	topodevice "github.com/onosproject/onos-config/pkg/device"
 	"github.com/onosproject/onos-config/pkg/dispatcher"
 	"github.com/onosproject/onos-config/pkg/events"
 	"github.com/onosproject/onos-config/pkg/modelregistry"
 	"github.com/onosproject/onos-config/pkg/southbound"
 	"github.com/onosproject/onos-config/pkg/store/change/device"
+	"github.com/onosproject/onos-config/pkg/store/change/device/state"
+	"github.com/onosproject/onos-config/pkg/store/change/network"
 	devicestore "github.com/onosproject/onos-config/pkg/store/device"
 	"github.com/onosproject/onos-config/pkg/store/mastership"
+	"github.com/onosproject/onos-config/pkg/store/opstate"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
 )
 
 // SessionManager is a gNMI session manager
 type SessionManager struct {
-	topoChannel               chan *topodevice.ListResponse
-	opStateChan               chan<- events.OperationalStateEvent
-	deviceStore               devicestore.Store
-	closeCh                   chan struct{}
-	dispatcher                *dispatcher.Dispatcher
-	modelRegistry             *modelregistry.ModelRegistry
-	sessions                  map[topodevice.ID]*Session
-	operationalStateCache     map[topodevice.ID]devicechange.TypedValueMap
-	newTargetFn               func() southbound.TargetIf
-	operationalStateCacheLock *sync.RWMutex
-	deviceChangeStore         device.Store
-	mastershipStore           mastership.Store
-	mu                        sync.RWMutex
+	topoChannel                chan *topodevice.ListResponse
+	opStateChan                chan<- events.OperationalStateEvent
+	deviceStore                devicestore.Store
+	closeCh                    chan struct{}
+	dispatcher                 *dispatcher.Dispatcher
+	modelRegistry              *modelregistry.ModelRegistry
+	sessions                   map[topodevice.ID]*Session
+	operationalStateCache      map[topodevice.ID]devicechange.TypedValueMap
+	operationalStateInfo       map[topodevice.ID]OpStateInfoMap
+	newTargetFn                func() southbound.TargetIf
+	operationalStateCacheLock  *sync.RWMutex
+	deviceChangeStore          device.Store
+	deviceStateStore           state.Store
+	configDriftChan            chan<- events.ConfigDriftEvent
+	configDriftInterval        time.Duration
+	networkChangeStore         network.Store
+	remediationPolicy          RemediationPolicy
+	modelRemediationPolicies   map[string]RemediationPolicy
+	credentialsCheckInterval   time.Duration
+	opStateSubscription        OpStateSubscription
+	modelOpStateSubscriptions  map[string]OpStateSubscription
+	mastershipStore            mastership.Store
+	opStateStore               opstate.Store
+	opStateReplicationInterval time.Duration
+	opStateMirrors             map[topodevice.ID]context.CancelFunc
+	opStateHistory             *OpStateHistory
+	southboundErrorChan        chan<- events.DeviceResponse
+	southboundErrors           *SouthboundErrors
+	strictModels               bool
+	autoDetectModels           bool
+	mu                         sync.RWMutex
 }
 
 // NewSessionManager create a new session manager
 func NewSessionManager(options ...func(*SessionManager)) (*SessionManager, error) {
-	sessionManager := &SessionManager{}
+	sessionManager := &SessionManager{
+		credentialsCheckInterval: DefaultCredentialsCheckInterval,
+		opStateSubscription:      DefaultOpStateSubscription(),
+		opStateMirrors:           make(map[topodevice.ID]context.CancelFunc),
+	}
 
 	for _, option := range options {
 		option(sessionManager)
@@ -113,6 +143,14 @@ func WithOperationalStateCache(operationalStateCache map[topodevice.ID]devicecha
 	}
 }
 
+// WithOperationalStateInfo sets the timestamps and staleness of the operational state cache.
+// It is guarded by the operational state cache lock
+func WithOperationalStateInfo(operationalStateInfo map[topodevice.ID]OpStateInfoMap) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.operationalStateInfo = operationalStateInfo
+	}
+}
+
 // WithNewTargetFn sets southbound target function
 func WithNewTargetFn(newTargetFn func() southbound.TargetIf) func(*SessionManager) {
 	return func(sessionManager *SessionManager) {
@@ -134,6 +172,222 @@ func WithDeviceChangeStore(deviceChangeStore device.Store) func(*SessionManager)
 	}
 }
 
+// WithDeviceStateStore sets device state store. Configuration drift is only
+// checked when it is set
+func WithDeviceStateStore(deviceStateStore state.Store) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.deviceStateStore = deviceStateStore
+	}
+}
+
+// WithConfigDriftChannel sets the channel configuration drift reports are sent on
+func WithConfigDriftChannel(configDriftChan chan<- events.ConfigDriftEvent) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.configDriftChan = configDriftChan
+	}
+}
+
+// WithConfigDriftInterval sets the interval configuration drift is checked at. With
+// no interval it is only checked on connection and on demand
+func WithConfigDriftInterval(configDriftInterval time.Duration) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.configDriftInterval = configDriftInterval
+	}
+}
+
+// WithNetworkChangeStore sets network change store. Remediation is only
+// possible when it is set
+func WithNetworkChangeStore(networkChangeStore network.Store) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.networkChangeStore = networkChangeStore
+	}
+}
+
+// WithRemediationPolicy sets the remediation policy of devices whose model has none
+func WithRemediationPolicy(policy RemediationPolicy) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.remediationPolicy = policy
+	}
+}
+
+// WithModelRemediationPolicies sets the remediation policies by model name
+func WithModelRemediationPolicies(policies map[string]RemediationPolicy) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.modelRemediationPolicies = policies
+	}
+}
+
+// WithCredentialsCheckInterval sets the interval the certificate files of devices are
+// checked for changes at. With no interval they are not checked
+func WithCredentialsCheckInterval(interval time.Duration) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.credentialsCheckInterval = interval
+	}
+}
+
+// WithOpStateSubscription sets the operational state subscription options of devices
+// whose model has none
+func WithOpStateSubscription(subscription OpStateSubscription) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.opStateSubscription = subscription
+	}
+}
+
+// WithModelOpStateSubscriptions sets the operational state subscription options by model name
+func WithModelOpStateSubscriptions(subscriptions map[string]OpStateSubscription) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.modelOpStateSubscriptions = subscriptions
+	}
+}
+
+// WithOpStateStore sets the store through which the operational state of devices is
+// shared with the other nodes of the cluster
+func WithOpStateStore(opStateStore opstate.Store) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.opStateStore = opStateStore
+	}
+}
+
+// WithOpStateReplicationInterval sets the interval at which the operational state of
+// devices is replicated to the other nodes
+func WithOpStateReplicationInterval(interval time.Duration) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.opStateReplicationInterval = interval
+	}
+}
+
+// WithOpStateHistory sets the history the operational state of devices is recorded in
+func WithOpStateHistory(history *OpStateHistory) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.opStateHistory = history
+	}
+}
+
+// WithSouthboundErrorChannel sets the channel the errors of device sessions are sent on
+func WithSouthboundErrorChannel(southboundErrorChan chan<- events.DeviceResponse) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.southboundErrorChan = southboundErrorChan
+	}
+}
+
+// WithSouthboundErrors sets the buffer the recent errors of device sessions are kept in
+func WithSouthboundErrors(southboundErrors *SouthboundErrors) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.southboundErrors = southboundErrors
+	}
+}
+
+// WithStrictModels sets whether devices that do not advertise the models of their plugin
+// are made unavailable for configuration
+func WithStrictModels(strictModels bool) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.strictModels = strictModels
+	}
+}
+
+// WithAutoDetectModels sets whether the type and version of devices lacking them are
+// detected from the models the devices advertise
+func WithAutoDetectModels(autoDetectModels bool) func(*SessionManager) {
+	return func(sessionManager *SessionManager) {
+		sessionManager.autoDetectModels = autoDetectModels
+	}
+}
+
+// getRemediationPolicy resolves the remediation policy of a device. The device
+// attribute comes first, then the policy of the model and finally the default one
+func (sm *SessionManager) getRemediationPolicy(device *topodevice.Device) RemediationPolicy {
+	if attribute, ok := device.Attributes[remediationPolicyKey]; ok {
+		policy, err := ParseRemediationPolicy(attribute)
+		if err == nil {
+			return policy
+		}
+		log.Warnf("Ignoring remediation policy of %s: %v", device.ID, err)
+	}
+	modelName := utils.ToModelName(devicetype.Type(device.Type), devicetype.Version(device.Version))
+	if policy, ok := sm.modelRemediationPolicies[modelName]; ok {
+		return policy
+	}
+	if sm.remediationPolicy == "" {
+		return RemediationNone
+	}
+	return sm.remediationPolicy
+}
+
+// getOpStateSubscription resolves the operational state subscription options of a device.
+// The options of the device attribute override the ones of its model, or the default ones
+func (sm *SessionManager) getOpStateSubscription(device *topodevice.Device) OpStateSubscription {
+	subscription := sm.opStateSubscription
+	modelName := utils.ToModelName(devicetype.Type(device.Type), devicetype.Version(device.Version))
+	if modelSubscription, ok := sm.modelOpStateSubscriptions[modelName]; ok {
+		subscription = modelSubscription
+	}
+	if attribute, ok := device.Attributes[opStateSubscriptionKey]; ok {
+		deviceSubscription, err := parseOpStateSubscription(attribute, subscription)
+		if err == nil {
+			return deviceSubscription
+		}
+		log.Warnf("Ignoring operational state subscription of %s: %v", device.ID, err)
+	}
+	return subscription
+}
+
+// CheckConfigDrift compares the running configuration of a device with the intended one
+func (sm *SessionManager) CheckConfigDrift(id topodevice.ID) (events.ConfigDriftEvent, error) {
+	session, err := sm.getSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.checkConfigDrift()
+}
+
+// GetConfigDrift returns the last configuration drift report of a device
+func (sm *SessionManager) GetConfigDrift(id topodevice.ID) (events.ConfigDriftEvent, error) {
+	session, err := sm.getSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.getConfigDrift()
+}
+
+// GetDeviceCapabilities returns the capabilities a device advertised when it was last
+// connected, compared with the models of its plugin
+func (sm *SessionManager) GetDeviceCapabilities(id topodevice.ID) (*DeviceCapabilities, error) {
+	session, err := sm.getSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.getCapabilities()
+}
+
+// GetRunningConfig gets the configuration a device is running
+func (sm *SessionManager) GetRunningConfig(id topodevice.ID) ([]*devicechange.PathValue, error) {
+	session, err := sm.getSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.getRunningConfig()
+}
+
+// IsOperationalStateStale indicates whether the cached operational state of a device
+// is no longer kept up to date
+func (sm *SessionManager) IsOperationalStateStale(id topodevice.ID) (bool, error) {
+	session, err := sm.getSession(id)
+	if err != nil {
+		return false, err
+	}
+	return session.isOpStateStale(), nil
+}
+
+func (sm *SessionManager) getSession(id topodevice.ID) (*Session, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	session, ok := sm.sessions[id]
+	if !ok {
+		return nil, errors.NewNotFound("no session for device %s", id)
+	}
+	return session, nil
+}
+
 // Start starts session manager
 func (sm *SessionManager) Start() error {
 	log.Info("Session manager started")
@@ -147,6 +401,22 @@ func (sm *SessionManager) Start() error {
 	return nil
 }
 
+// Close closes the sessions of all the devices and stops mirroring their operational state.
+// The sessions no longer publish events once it returns
+func (sm *SessionManager) Close() {
+	log.Info("Session manager closing")
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for id, session := range sm.sessions {
+		session.Close()
+		delete(sm.sessions, id)
+	}
+	for id, cancel := range sm.opStateMirrors {
+		cancel()
+		delete(sm.opStateMirrors, id)
+	}
+}
+
 // processDeviceEvents process incoming device events
 func (sm *SessionManager) processDeviceEvents(ch <-chan *topodevice.ListResponse) {
 	for event := range ch {
@@ -163,25 +433,30 @@ func (sm *SessionManager) processDeviceEvents(ch <-chan *topodevice.ListResponse
 func (sm *SessionManager) processDeviceEvent(event *topodevice.ListResponse) error {
 	switch event.Type {
 	case topodevice.ListResponseADDED:
+		sm.startOpStateMirror(event.Device.ID)
 		err := sm.createSession(event.Device)
 		if err != nil {
 			return err
 		}
 
 	case topodevice.ListResponseNONE:
+		sm.startOpStateMirror(event.Device.ID)
 		err := sm.createSession(event.Device)
 		if err != nil {
 			return err
 		}
 
 	case topodevice.ListResponseUPDATED:
+		sm.mu.RLock()
 		session, ok := sm.sessions[event.Device.ID]
+		sm.mu.RUnlock()
 		if !ok {
 			log.Error("Session for the device %v does not exist", event.Device.ID)
 			return nil
 		}
-		// If the address is changed, delete the current session and creates  new one
-		if session.device.Address != event.Device.Address {
+		// If the addresses, the subscription or the model are changed, delete the current session and creates  new one
+		if addressesChanged(session.device, event.Device) || opStateSubscriptionChanged(session.device, event.Device) ||
+			modelChanged(session.device, event.Device) {
 			err := sm.deleteSession(event.Device)
 			if err != nil {
 				return err
@@ -190,19 +465,94 @@ func (sm *SessionManager) processDeviceEvent(event *topodevice.ListResponse) err
 			if err != nil {
 				return err
 			}
+		} else if credentialsChanged(session.device, event.Device) {
+			// If only the credentials are changed, reconnect the current session in place
+			return session.updateCredentials(event.Device)
 		}
 
 	case topodevice.ListResponseREMOVED:
+		sm.stopOpStateMirror(event.Device.ID)
+		master := sm.isMaster(event.Device.ID)
 		err := sm.deleteSession(event.Device)
 		if err != nil {
 			return err
 		}
+		sm.deleteOpState(event.Device.ID)
+		if sm.opStateHistory != nil {
+			sm.opStateHistory.Purge(event.Device.ID)
+		}
+		if sm.southboundErrors != nil {
+			sm.southboundErrors.Purge(event.Device.ID)
+		}
+		if master && sm.opStateStore != nil {
+			if err := sm.opStateStore.Purge(event.Device.ID); err != nil {
+				log.Warnf("Purging the replicated operational state of %s failed: %v", event.Device.ID, err)
+			}
+		}
 
 	}
 	return nil
 
 }
 
+// deleteOpState removes the operational state of a device from the cache
+func (sm *SessionManager) deleteOpState(id topodevice.ID) {
+	sm.operationalStateCacheLock.Lock()
+	defer sm.operationalStateCacheLock.Unlock()
+	delete(sm.operationalStateCache, id)
+	if sm.operationalStateInfo != nil {
+		delete(sm.operationalStateInfo, id)
+	}
+}
+
+// isMaster indicates whether the local node is the master of a device
+func (sm *SessionManager) isMaster(id topodevice.ID) bool {
+	state, err := sm.mastershipStore.GetMastership(id)
+	if err != nil || state == nil {
+		return false
+	}
+	return state.Master == sm.mastershipStore.NodeID()
+}
+
+// startOpStateMirror starts mirroring the operational state of a device replicated by
+// its master, if the state is shared through a store
+func (sm *SessionManager) startOpStateMirror(id topodevice.ID) {
+	if sm.opStateStore == nil {
+		return
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, ok := sm.opStateMirrors[id]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sm.opStateMirrors[id] = cancel
+	mirror := &opStateMirror{
+		deviceID: id,
+		store:    sm.opStateStore,
+		isMaster: func() bool {
+			return sm.isMaster(id)
+		},
+		cache:       sm.operationalStateCache,
+		info:        sm.operationalStateInfo,
+		lock:        sm.operationalStateCacheLock,
+		opStateChan: sm.opStateChan,
+		history:     sm.opStateHistory,
+		interval:    sm.opStateReplicationInterval,
+	}
+	go mirror.run(ctx)
+}
+
+// stopOpStateMirror stops mirroring the operational state of a device
+func (sm *SessionManager) stopOpStateMirror(id topodevice.ID) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if cancel, ok := sm.opStateMirrors[id]; ok {
+		cancel()
+		delete(sm.opStateMirrors, id)
+	}
+}
+
 func (sm *SessionManager) handleMastershipEvents(session *Session) {
 	ch := make(chan mastership.Mastership)
 	err := sm.mastershipStore.Watch(session.device.ID, ch)
@@ -255,21 +605,41 @@ func (sm *SessionManager) createSession(device *topodevice.Device) error {
 	}
 
 	session := &Session{
-		opStateChan:               sm.opStateChan,
-		dispatcher:                sm.dispatcher,
-		modelRegistry:             sm.modelRegistry,
-		operationalStateCache:     sm.operationalStateCache,
-		operationalStateCacheLock: sm.operationalStateCacheLock,
-		deviceChangeStore:         sm.deviceChangeStore,
-		device:                    device,
-		target:                    sm.newTargetFn(),
-		deviceStore:               sm.deviceStore,
-		mastershipState:           state,
-		nodeID:                    sm.mastershipStore.NodeID(),
+		opStateChan:                sm.opStateChan,
+		dispatcher:                 sm.dispatcher,
+		modelRegistry:              sm.modelRegistry,
+		operationalStateCache:      sm.operationalStateCache,
+		operationalStateInfo:       sm.operationalStateInfo,
+		operationalStateCacheLock:  sm.operationalStateCacheLock,
+		deviceChangeStore:          sm.deviceChangeStore,
+		deviceStateStore:           sm.deviceStateStore,
+		configDriftChan:            sm.configDriftChan,
+		configDriftInterval:        sm.configDriftInterval,
+		networkChangeStore:         sm.networkChangeStore,
+		credentialsCheckInterval:   sm.credentialsCheckInterval,
+		opStateStore:               sm.opStateStore,
+		opStateReplicationInterval: sm.opStateReplicationInterval,
+		opStateHistory:             sm.opStateHistory,
+		southboundErrorChan:        sm.southboundErrorChan,
+		southboundErrors:           sm.southboundErrors,
+		strictModels:               sm.strictModels,
+		autoDetectModels:           sm.autoDetectModels,
+		device:                     device,
+		target:                     sm.newTargetFn(),
+		deviceStore:                sm.deviceStore,
+		mastershipState:            state,
+		nodeID:                     sm.mastershipStore.NodeID(),
 	}
 	if session.device.Attributes == nil {
 		session.device.Attributes = make(map[string]string)
 	}
+	session.remediationPolicy = sm.getRemediationPolicy(session.device)
+	session.opStateSubscription = sm.getOpStateSubscription(session.device)
+	// Start with the address the device was last connected at
+	session.addresses = getAddresses(session.device)
+	if index := indexOfAddress(session.addresses, session.device.Attributes[activeAddressKey]); index >= 0 {
+		session.addressIndex = index
+	}
 
 	err = session.open()
 	if err != nil {
@@ -281,11 +651,13 @@ func (sm *SessionManager) createSession(device *topodevice.Device) error {
 	}()
 
 	// Close the old session and adds the new session to the list of sessions
+	sm.mu.Lock()
 	oldSession, ok := sm.sessions[device.ID]
 	if ok {
 		oldSession.Close()
 	}
 	sm.sessions[device.ID] = session
+	sm.mu.Unlock()
 
 	return nil
 }
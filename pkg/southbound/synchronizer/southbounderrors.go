@@ -0,0 +1,96 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+)
+
+// DefaultSouthboundErrorsSize is the default number of recent errors kept per device
+const DefaultSouthboundErrorsSize = 100
+
+// SouthboundErrors keeps the recent errors of the sessions of devices, up to a number
+// of errors per device
+type SouthboundErrors struct {
+	// dropped is accessed atomically, and kept first for its alignment
+	dropped uint64
+	size    int
+	devices map[topodevice.ID][]events.DeviceResponse
+	mu      sync.RWMutex
+}
+
+// NewSouthboundErrors returns a buffer keeping the last size errors of each device. It
+// returns nil if size is not positive
+func NewSouthboundErrors(size int) *SouthboundErrors {
+	if size <= 0 {
+		return nil
+	}
+	return &SouthboundErrors{
+		size:    size,
+		devices: make(map[topodevice.ID][]events.DeviceResponse),
+	}
+}
+
+// Record adds an error event of the device it is about
+func (e *SouthboundErrors) Record(event events.DeviceResponse) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	deviceID := topodevice.ID(event.Subject())
+	errs := e.devices[deviceID]
+	if len(errs) == e.size {
+		errs = append(errs[:0], errs[1:]...)
+	}
+	e.devices[deviceID] = append(errs, event)
+}
+
+// List returns the recent errors of a device, or of all devices if deviceID is empty,
+// oldest first
+func (e *SouthboundErrors) List(deviceID topodevice.ID) []events.DeviceResponse {
+	e.mu.RLock()
+	errs := make([]events.DeviceResponse, 0)
+	for id, deviceErrs := range e.devices {
+		if deviceID == "" || id == deviceID {
+			errs = append(errs, deviceErrs...)
+		}
+	}
+	e.mu.RUnlock()
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Time().Before(errs[j].Time())
+	})
+	return errs
+}
+
+// Dropped returns the number of errors that were recorded but could not be published to
+// the northbound, as the southbound error channel was full
+func (e *SouthboundErrors) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
+// drop counts an error that could not be published to the northbound
+func (e *SouthboundErrors) drop() {
+	atomic.AddUint64(&e.dropped, 1)
+}
+
+// Purge removes the errors of a device
+func (e *SouthboundErrors) Purge(deviceID topodevice.ID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.devices, deviceID)
+}
@@ -0,0 +1,96 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer
+
+import (
+	"fmt"
+	"testing"
+
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/events"
+	"gotest.tools/assert"
+)
+
+func Test_SouthboundErrors(t *testing.T) {
+	assert.Assert(t, NewSouthboundErrors(0) == nil)
+
+	errs := NewSouthboundErrors(2)
+	for i := 0; i < 3; i++ {
+		errs.Record(events.NewErrorEventNoChangeID(events.EventTypeErrorSubscribe,
+			device1, fmt.Errorf("subscribe failed %d", i)))
+	}
+	errs.Record(events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceCapabilities,
+		"device2", fmt.Errorf("capabilities failed")))
+
+	// Only the last errors of each device are kept
+	device1Errs := errs.List(device1)
+	assert.Equal(t, len(device1Errs), 2)
+	assert.Error(t, device1Errs[0].Error(), "subscribe failed 1")
+	assert.Error(t, device1Errs[1].Error(), "subscribe failed 2")
+
+	// All devices, oldest first
+	allErrs := errs.List("")
+	assert.Equal(t, len(allErrs), 3)
+	assert.Equal(t, allErrs[2].Subject(), "device2")
+
+	errs.Purge(device1)
+	assert.Equal(t, len(errs.List(device1)), 0)
+	assert.Equal(t, len(errs.List("")), 1)
+}
+
+func Test_reportError(t *testing.T) {
+	errChan := make(chan events.DeviceResponse, 1)
+	s := &Session{
+		device:              &topodevice.Device{ID: device1},
+		deviceResponseChan:  make(chan events.DeviceResponse),
+		southboundErrorChan: errChan,
+		southboundErrors:    NewSouthboundErrors(10),
+	}
+	go func() {
+		_ = s.updateDeviceState()
+	}()
+	s.deviceResponseChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorTranslation,
+		device1, fmt.Errorf("translation failed"))
+	close(s.deviceResponseChan)
+
+	event := <-errChan
+	assert.Equal(t, event.EventType(), events.EventTypeErrorTranslation)
+	assert.Equal(t, len(s.southboundErrors.List(device1)), 1)
+}
+
+func Test_reportErrorDropsWhenFull(t *testing.T) {
+	errChan := make(chan events.DeviceResponse, 1)
+	s := &Session{
+		device:              &topodevice.Device{ID: device1},
+		southboundErrorChan: errChan,
+		southboundErrors:    NewSouthboundErrors(10),
+	}
+	for i := 0; i < 3; i++ {
+		s.reportError(events.NewErrorEventNoChangeID(events.EventTypeErrorSubscribe,
+			device1, fmt.Errorf("subscribe failed %d", i)))
+	}
+
+	// The errors that do not fit in the channel are still recorded, but counted as dropped
+	assert.Equal(t, len(errChan), 1)
+	assert.Equal(t, len(s.southboundErrors.List(device1)), 3)
+	assert.Equal(t, s.southboundErrors.Dropped(), uint64(2))
+
+	// Once the session is closed, nothing is published
+	<-errChan
+	s.Close()
+	s.reportError(events.NewErrorEventNoChangeID(events.EventTypeErrorSubscribe,
+		device1, fmt.Errorf("subscribe failed")))
+	assert.Equal(t, len(errChan), 0)
+}
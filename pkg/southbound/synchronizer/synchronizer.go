@@ -22,7 +22,9 @@ import (
 	"regexp"
 	"strings"
 	syncPrimitives "sync"
+	"time"
 
+	"github.com/cenkalti/backoff"
 	"github.com/golang/protobuf/proto"
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	topodevice "github.com/onosproject/onos-config/pkg/device"
@@ -44,6 +46,17 @@ var log = logging.GetLogger("southbound", "synchronizer")
 
 const matchOnIndex = `(\=.*?]).*?`
 
+const (
+	resubscribeInterval    = 100 * time.Millisecond
+	maxResubscribeInterval = 30 * time.Second
+)
+
+// errResubscribe ends the subscriptions to a device that is connected again, e.g. with new credentials
+var errResubscribe = fmt.Errorf("device connected again")
+
+// errSubscriptionEnded is returned when the device ends a subscription without an error
+var errSubscriptionEnded = fmt.Errorf("subscription ended")
+
 // Synchronizer enables proper configuring of a device based on store events and cache of operational data
 type Synchronizer struct {
 	context.Context
@@ -53,10 +66,16 @@ type Synchronizer struct {
 	query                client.Query
 	modelReadOnlyPaths   modelregistry.ReadOnlyPathMap
 	operationalCache     devicechange.TypedValueMap
+	operationalInfo      OpStateInfoMap
+	history              *OpStateHistory
 	operationalCacheLock *syncPrimitives.RWMutex
 	encoding             gnmi.Encoding
 	getStateMode         configmodel.GetStateMode
+	subscription         OpStateSubscription
+	setStale             func(stale bool)
+	resubscribe          <-chan struct{}
 	target               southbound.TargetIf
+	capabilities         *gnmi.CapabilityResponse
 }
 
 // New builds a new Synchronizer given the parameters, starts the connection with the device and polls the capabilities
@@ -82,6 +101,8 @@ func New(context context.Context,
 	sync.key = key
 	if err != nil {
 		log.Warn(err)
+		errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceConnect,
+			string(device.ID), err)
 		return nil, err
 	}
 	log.Info(sync.Device.Address, " connected over gNMI")
@@ -94,6 +115,7 @@ func New(context context.Context,
 			string(device.ID), capErr)
 		return nil, capErr
 	}
+	sync.capabilities = capResponse
 	sync.encoding = gnmi.Encoding_PROTO // Default
 	if capResponse != nil {
 		for _, enc := range capResponse.SupportedEncodings {
@@ -113,6 +135,18 @@ func (sync Synchronizer) syncOperationalStateByPartition(ctx context.Context,
 	errChan chan<- events.DeviceResponse) {
 
 	log.Infof("Syncing Op & State of %s started. Mode %v", string(sync.key), sync.getStateMode)
+	// Whatever could be retrieved is subscribed to
+	_ = sync.getOpStateByPartition(ctx, errChan)
+
+	// Now try the subscribe with the read only paths and the expanded wildcard
+	// paths (if any) from above
+	sync.subscribeOpState(ctx, errChan, sync.getOpStateByPartition)
+}
+
+// getOpStateByPartition fills the cache with the STATE and OPERATIONAL partitions
+func (sync Synchronizer) getOpStateByPartition(ctx context.Context,
+	errChan chan<- events.DeviceResponse) error {
+
 	notifications := make([]*gnmi.Notification, 0)
 	stateNotif, errState := sync.getOpStatePathsByType(ctx, gnmi.GetRequest_STATE, errChan)
 	if errState != nil {
@@ -137,10 +171,10 @@ func (sync Synchronizer) syncOperationalStateByPartition(ctx context.Context,
 	}
 
 	sync.opCacheUpdate(notifications, errChan)
-
-	// Now try the subscribe with the read only paths and the expanded wildcard
-	// paths (if any) from above
-	sync.subscribeOpState(errChan)
+	if errState != nil && errOp != nil {
+		return errOp
+	}
+	return nil
 }
 
 // For use when device model has
@@ -150,18 +184,31 @@ func (sync Synchronizer) syncOperationalStateByPaths(ctx context.Context,
 	errChan chan<- events.DeviceResponse) {
 
 	log.Infof("Syncing Op & State of %s started. Mode %v", string(sync.key), sync.getStateMode)
+	if err := sync.getOpStateByPaths(ctx, errChan); err != nil {
+		return
+	}
+
+	// Now try the subscribe with the read only paths and the expanded wildcard
+	// paths (if any) from above
+	sync.subscribeOpState(ctx, errChan, sync.getOpStateByPaths)
+}
+
+// getOpStateByPaths fills the cache with the read only paths of the model
+func (sync Synchronizer) getOpStateByPaths(ctx context.Context,
+	errChan chan<- events.DeviceResponse) error {
+
 	if sync.modelReadOnlyPaths == nil {
 		errMp := fmt.Errorf("no model plugin, cant work in operational state cache")
 		log.Error(errMp)
 		errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorMissingModelPlugin,
 			string(sync.key), errMp)
-		return
+		return errMp
 	} else if len(sync.modelReadOnlyPaths) == 0 {
 		noPathErr := fmt.Errorf("target %#v has no paths to subscribe to", sync.ID)
 		errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorSubscribe,
 			string(sync.key), noPathErr)
 		log.Warn(noPathErr)
-		return
+		return noPathErr
 	}
 	log.Infof("Getting state by %d ReadOnly paths for %s", len(sync.modelReadOnlyPaths), string(sync.key))
 	getPaths := make([]*gnmi.Path, 0)
@@ -176,7 +223,7 @@ func (sync Synchronizer) syncOperationalStateByPaths(ctx context.Context,
 			log.Warn("Error converting RO path to gNMI")
 			errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorTranslation,
 				string(sync.key), err)
-			return
+			return err
 		}
 		getPaths = append(getPaths, gnmiPath)
 	}
@@ -217,12 +264,12 @@ func (sync Synchronizer) syncOperationalStateByPaths(ctx context.Context,
 				if !ok && (status.Code() == codes.Unknown || status.Code() == codes.Unavailable) {
 					errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceConnect, string(sync.ID), errRoPaths)
 				}
-				return
+				return errRoPaths
 			}
 			for _, n := range responseEwRoPaths.Notification {
 				for _, u := range n.Update {
 					if sync.encoding == gnmi.Encoding_JSON || sync.encoding == gnmi.Encoding_JSON_IETF {
-						configValues, err := sync.getValuesFromJSON(u)
+						configValues, err := sync.getValuesFromJSON(n, u)
 						if err != nil {
 							errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorTranslation,
 								string(sync.key), err)
@@ -277,13 +324,10 @@ func (sync Synchronizer) syncOperationalStateByPaths(ctx context.Context,
 		if !ok && (status.Code() == codes.Unknown || status.Code() == codes.Unavailable) {
 			errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceConnect, string(sync.ID), errRoPaths)
 		}
-		return
+		return errRoPaths
 	}
 	sync.opCacheUpdate(responseRoPaths.Notification, errChan)
-
-	// Now try the subscribe with the read only paths and the expanded wildcard
-	// paths (if any) from above
-	sync.subscribeOpState(errChan)
+	return nil
 }
 
 /**
@@ -326,50 +370,146 @@ func (sync Synchronizer) opCacheUpdate(notifications []*gnmi.Notification,
 	defer sync.operationalCacheLock.Unlock()
 	for _, notification := range notifications {
 		for _, update := range notification.Update {
-			if sync.encoding == gnmi.Encoding_JSON || sync.encoding == gnmi.Encoding_JSON_IETF {
-				configValues, err := sync.getValuesFromJSON(update)
-				if err != nil {
-					errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorTranslation,
-						string(sync.key), err)
-					continue
-				}
-				for _, cv := range configValues {
-					value := cv.GetValue()
-					sync.operationalCache[cv.Path] = value
-				}
-			} else if sync.encoding == gnmi.Encoding_PROTO {
-				// TODO: Look up the model path from the update.Path
-				typedVal, err := values.GnmiTypedValueToNativeType(update.Val, nil)
-				if err != nil {
-					log.Warn("Error converting gnmi value to Typed"+
-						" Value", update.Val, " for ", update.Path)
-				} else {
-					sync.operationalCache[utils.StrPath(update.Path)] = typedVal
-				}
+			pathValues, err := sync.getUpdateValues(notification, update)
+			if err != nil {
+				log.Warn("Error converting gnmi value to Typed"+
+					" Value", update.Val, " for ", update.Path)
+				errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorTranslation,
+					string(sync.key), err)
+				continue
+			}
+			for _, pathValue := range pathValues {
+				sync.cacheValue(pathValue.Path, pathValue.GetValue(), notification.Timestamp)
+			}
+		}
+	}
+}
+
+// getUpdateValues decodes the values of an update, either a JSON tree or a single
+// leaf, typed after the read only paths of the model. Ignored values are dropped
+func (sync Synchronizer) getUpdateValues(notification *gnmi.Notification, update *gnmi.Update) ([]*devicechange.PathValue, error) {
+	var pathValues []*devicechange.PathValue
+	if update.GetVal().GetJsonVal() != nil || update.GetVal().GetJsonIetfVal() != nil {
+		configValues, err := sync.getValuesFromJSON(notification, update)
+		if err != nil {
+			return nil, err
+		}
+		pathValues = configValues
+	} else {
+		path := utils.StrPath(updatePath(notification.GetPrefix(), update.Path))
+		typedVal, err := values.GnmiTypedValueToNativeType(update.Val, sync.modelPathElem(path))
+		if err != nil {
+			return nil, err
+		}
+		pathValues = []*devicechange.PathValue{{Path: path, Value: typedVal}}
+	}
+
+	filtered := pathValues[:0]
+	for _, pathValue := range pathValues {
+		if !sync.subscription.ignoresValue(pathValue.GetValue()) {
+			filtered = append(filtered, pathValue)
+		}
+	}
+	return filtered, nil
+}
+
+// modelPathElem looks up the read only path of the model an instance path is of
+func (sync Synchronizer) modelPathElem(path string) *modelregistry.ReadWritePathElem {
+	pathNoIndices := modelregistry.RemovePathIndices(path)
+	for roPath, subPaths := range sync.modelReadOnlyPaths {
+		for subPath, attrib := range subPaths {
+			fullPath := roPath
+			if subPath != "/" {
+				fullPath = roPath + subPath
+			}
+			if modelregistry.RemovePathIndices(fullPath) == pathNoIndices {
+				return &modelregistry.ReadWritePathElem{ReadOnlyAttrib: attrib}
 			}
 		}
 	}
+	return nil
 }
 
-func (sync Synchronizer) getValuesFromJSON(update *gnmi.Update) ([]*devicechange.PathValue, error) {
+func (sync Synchronizer) getValuesFromJSON(notification *gnmi.Notification, update *gnmi.Update) ([]*devicechange.PathValue, error) {
 	jsonVal := update.Val.GetJsonVal()
 	if jsonVal == nil {
 		jsonVal = update.Val.GetJsonIetfVal()
 	}
-	configValues, err := jsonvalues.DecomposeJSONWithPaths("", jsonVal, sync.modelReadOnlyPaths, nil)
+	prefix := utils.StrPath(updatePath(notification.GetPrefix(), update.Path))
+	if prefix == "/" {
+		prefix = ""
+	}
+	configValues, err := jsonvalues.DecomposeJSONWithPaths(prefix, jsonVal, sync.modelReadOnlyPaths, nil)
 	if err != nil {
 		return nil, err
 	}
 	return configValues, nil
 }
 
+// updatePath returns the path of an update or delete prefixed with the prefix of its notification
+func updatePath(prefix *gnmi.Path, path *gnmi.Path) *gnmi.Path {
+	if prefix == nil || len(prefix.Elem) == 0 {
+		return path
+	}
+	elems := make([]*gnmi.PathElem, 0, len(prefix.Elem)+len(path.GetElem()))
+	elems = append(elems, prefix.Elem...)
+	return &gnmi.Path{Elem: append(elems, path.GetElem()...)}
+}
+
 /**
  *	subscribeOpState only subscribes to the paths that were successfully retrieved
  *	with Get (of state - which ever method was successful).
  *  This can be found from the OpStateCache
  *  At this stage the wildcards will have been expanded and the ReadOnly paths traversed
  */
-func (sync *Synchronizer) subscribeOpState(errChan chan<- events.DeviceResponse) {
+func (sync *Synchronizer) subscribeOpState(ctx context.Context, errChan chan<- events.DeviceResponse,
+	getOpState func(context.Context, chan<- events.DeviceResponse) error) {
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = resubscribeInterval
+	b.MaxInterval = maxResubscribeInterval
+	b.MaxElapsedTime = 0
+	for {
+		subscribed := time.Now()
+		err := sync.subscribe(ctx, errChan)
+		if err == errResubscribe {
+			// The subscriptions are made again with the settings the device is now connected with
+			log.Infof("Resubscribing to %s connected again", string(sync.key))
+			b.Reset()
+			continue
+		} else if err == nil || ctx.Err() != nil {
+			return
+		}
+		// Only back off further if the last subscription did not last
+		if time.Since(subscribed) > maxResubscribeInterval {
+			b.Reset()
+		}
+
+		// The cached values are not updated until the subscription is back, whether it
+		// failed or the device ended it
+		sync.markStale(true)
+		for {
+			wait := b.NextBackOff()
+			log.Infof("Resubscribing to %s in %v", string(sync.key), wait)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+			if err := getOpState(ctx, errChan); err != nil {
+				log.Warnf("Refreshing state of %s failed: %v", string(sync.key), err)
+				continue
+			}
+			break
+		}
+		sync.markStale(false)
+	}
+}
+
+// subscribe subscribes to the paths in the cache and blocks until the subscriptions end.
+// If any subscription fails the others are cancelled and its error is returned. Nil is
+// only returned if there is nothing to subscribe to
+func (sync *Synchronizer) subscribe(ctx context.Context, errChan chan<- events.DeviceResponse) error {
 	subscribePaths := make([][]string, 0)
 	sync.operationalCacheLock.RLock()
 	for p := range sync.operationalCache {
@@ -377,43 +517,90 @@ func (sync *Synchronizer) subscribeOpState(errChan chan<- events.DeviceResponse)
 	}
 	sync.operationalCacheLock.RUnlock()
 
-	options := &southbound.SubscribeOptions{
-		UpdatesOnly:       false,
-		Prefix:            "",
-		Mode:              "stream",
-		StreamMode:        "target_defined",
-		SampleInterval:    15,
-		HeartbeatInterval: 15,
-		Paths:             subscribePaths,
-		Origin:            "",
-	}
-
 	if len(subscribePaths) == 0 {
 		log.Info("No operational state path found for subscription")
-		return
+		return nil
 	}
 
-	log.Infof("Subscribing to %d paths. %s", len(subscribePaths), string(sync.key))
-	req, err := southbound.NewSubscribeRequest(options)
-	if err != nil {
-		errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorParseConfig,
-			string(sync.key), err)
-		return
+	groups := groupPaths(subscribePaths, sync.subscription.Grouping)
+	log.Infof("Subscribing to %d paths in %d subscriptions. %s", len(subscribePaths), len(groups), string(sync.key))
+	subscriptionContext, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resubscribe := make(chan struct{})
+	go func() {
+		select {
+		case <-sync.resubscribe:
+			close(resubscribe)
+			cancel()
+		case <-subscriptionContext.Done():
+		}
+	}()
+	subErrs := make(chan error, len(groups))
+	subscriptions := 0
+	for _, paths := range groups {
+		options := &southbound.SubscribeOptions{
+			UpdatesOnly:       false,
+			Prefix:            "",
+			Mode:              "stream",
+			StreamMode:        string(sync.subscription.Mode),
+			SampleInterval:    uint64(sync.subscription.SampleInterval.Nanoseconds()),
+			HeartbeatInterval: uint64(sync.subscription.HeartbeatInterval.Nanoseconds()),
+			SuppressRedundant: sync.subscription.SuppressRedundant,
+			Paths:             paths,
+			Origin:            "",
+		}
+		req, err := southbound.NewSubscribeRequest(options)
+		if err != nil {
+			errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorParseConfig,
+				string(sync.key), err)
+			continue
+		}
+		subscriptions++
+		go func() {
+			subErrs <- sync.target.Subscribe(subscriptionContext, req, sync.opStateSubHandler) // Blocks here until error in handler
+		}()
 	}
-	subscriptionContext, cancel := context.WithCancel(context.Background())
-	subErr := sync.target.Subscribe(subscriptionContext, req, sync.opStateSubHandler) // Blocks here until error in handler
-	cancel()
-	if subErr != nil {
-		log.Warn("Error in subscribe ", subErr)
-		stat, ok := status.FromError(subErr)
-		if !ok && (stat.Code() == codes.Unknown || stat.Code() == codes.Unavailable) {
-			errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceConnect, string(sync.ID), err)
+
+	var subErr error
+	for i := 0; i < subscriptions; i++ {
+		if err := <-subErrs; err != nil && subErr == nil {
+			subErr = err
+			cancel()
 		}
-		errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorSubscribe,
-			string(sync.key), subErr)
-		return
 	}
-	log.Info("Subscribe for OpState notifications on ", string(sync.key), " started")
+	select {
+	case <-resubscribe:
+		return errResubscribe
+	default:
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	} else if subscriptions == 0 {
+		return nil
+	} else if subErr == nil {
+		log.Info("Subscribe for OpState notifications on ", string(sync.key), " ended")
+		return errSubscriptionEnded
+	}
+	log.Warn("Error in subscribe ", subErr)
+	stat, ok := status.FromError(subErr)
+	if !ok && (stat.Code() == codes.Unknown || stat.Code() == codes.Unavailable) {
+		errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorDeviceConnect, string(sync.ID), subErr)
+	}
+	errChan <- events.NewErrorEventNoChangeID(events.EventTypeErrorSubscribe,
+		string(sync.key), subErr)
+	return subErr
+}
+
+// markStale reports whether the cached values are kept up to date
+func (sync *Synchronizer) markStale(stale bool) {
+	if stale {
+		log.Warnf("Operational state of %s is stale", string(sync.key))
+	} else {
+		log.Infof("Operational state of %s is refreshed", string(sync.key))
+	}
+	if sync.setStale != nil {
+		sync.setStale(stale)
+	}
 }
 
 func (sync *Synchronizer) getOpStatePathsByType(ctx context.Context,
@@ -455,27 +642,21 @@ func (sync *Synchronizer) opStateSubHandler(msg proto.Message) error {
 		}
 	case *gnmi.SubscribeResponse_Update:
 		notification := v.Update
+		timestamp := deviceTimestamp(notification.Timestamp, time.Now())
 		for _, update := range notification.Update {
 			if update.Path == nil {
 				return fmt.Errorf("invalid nil path in update: %v", update)
 			}
-			pathStr := utils.StrPath(update.Path)
-
-			//TODO this currently supports only leaf values, and no * paths,
-			// parsing of json is needed and a per path storage
-			valStr := utils.StrVal(update.Val)
-
-			// FIXME: this is a hack to ignore bogus values in phantom notifications coming from Stratum for some reason
-			if valStr != "unsupported yet" {
-				// TODO: Look up the model path from the update.Path
-				val, err := values.GnmiTypedValueToNativeType(update.Val, nil)
-				if err != nil {
-					return fmt.Errorf("can't translate to Typed value %s", err)
-				}
-				sync.operationalStateChan <- events.NewOperationalStateEvent(string(sync.Device.ID), pathStr, val, events.EventItemUpdated)
+			pathValues, err := sync.getUpdateValues(notification, update)
+			if err != nil {
+				return fmt.Errorf("can't translate to Typed value %s", err)
+			}
+			for _, pathValue := range pathValues {
+				sync.operationalStateChan <- events.NewOperationalStateEventWithTimestamp(string(sync.Device.ID),
+					pathValue.Path, pathValue.GetValue(), events.EventItemUpdated, timestamp)
 
 				sync.operationalCacheLock.Lock()
-				sync.operationalCache[pathStr] = val
+				sync.cacheValue(pathValue.Path, pathValue.GetValue(), timestamp)
 				sync.operationalCacheLock.Unlock()
 			}
 		}
@@ -484,12 +665,25 @@ func (sync *Synchronizer) opStateSubHandler(msg proto.Message) error {
 			if del.Elem == nil {
 				return fmt.Errorf("invalid nil path in update: %v", del)
 			}
-			pathStr := utils.StrPathElem(del.Elem)
+			pathStr := utils.StrPathElem(updatePath(notification.GetPrefix(), del).Elem)
 			log.Info("Delete path ", pathStr, " for device ", sync.ID)
-			sync.operationalStateChan <- events.NewOperationalStateEvent(string(sync.Device.ID), pathStr, nil, events.EventItemDeleted)
+			// Deleting a container deletes all the leaves under it
 			sync.operationalCacheLock.Lock()
-			delete(sync.operationalCache, pathStr)
+			deleted := make([]string, 0)
+			for path := range sync.operationalCache {
+				if path == pathStr || strings.HasPrefix(path, pathStr+"/") {
+					deleted = append(deleted, path)
+					sync.uncacheValue(path)
+				}
+			}
 			sync.operationalCacheLock.Unlock()
+			if len(deleted) == 0 {
+				deleted = append(deleted, pathStr)
+			}
+			for _, path := range deleted {
+				sync.operationalStateChan <- events.NewOperationalStateEventWithTimestamp(string(sync.Device.ID),
+					path, nil, events.EventItemDeleted, timestamp)
+			}
 		}
 	}
 	return nil
@@ -32,6 +32,7 @@ import (
 	storemock "github.com/onosproject/onos-config/pkg/test/mocks/store"
 	"github.com/onosproject/onos-config/pkg/utils"
 	"github.com/onosproject/onos-config/pkg/utils/values"
+	"github.com/openconfig/gnmi/client"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -234,17 +235,23 @@ func TestNew(t *testing.T) {
 		},
 	}, nil)
 
+	// The subscription is only ended by the test, else it is made again
+	ctx, cancel := context2.WithCancel(context2.Background())
+	defer cancel()
 	mockTarget.EXPECT().Subscribe(
 		gomock.Any(),
 		gomock.AssignableToTypeOf(&gnmi.SubscribeRequest{}),
 		gomock.Any(),
-	).Return(nil).MinTimes(1)
+	).DoAndReturn(func(context2.Context, *gnmi.SubscribeRequest, client.ProtoHandler) error {
+		cancel()
+		return nil
+	}).MinTimes(1)
 
 	// Called asynchronously as after building up the opStateCache it subscribes and waits
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		s.syncOperationalStateByPaths(context2.Background(), params.responseChan)
+		s.syncOperationalStateByPaths(ctx, params.responseChan)
 		wg.Done()
 	}()
 
@@ -448,11 +455,17 @@ func TestNewWithExistingConfig(t *testing.T) {
 		},
 	}, nil)
 
+	// The subscription is only ended by the test, else it is made again
+	ctx, cancel := context2.WithCancel(context2.Background())
+	defer cancel()
 	mockTarget.EXPECT().Subscribe(
 		gomock.Any(),
 		gomock.AssignableToTypeOf(&gnmi.SubscribeRequest{}),
 		gomock.Any(),
-	).Return(nil).MinTimes(1)
+	).DoAndReturn(func(context2.Context, *gnmi.SubscribeRequest, client.ProtoHandler) error {
+		cancel()
+		return nil
+	}).MinTimes(1)
 
 	go func() {
 		// Handles any errors coming back from functions
@@ -487,7 +500,7 @@ func TestNewWithExistingConfig(t *testing.T) {
 	}()
 
 	// Called asynchronously as after building up the opStateCache it subscribes and waits
-	go s.syncOperationalStateByPartition(context2.Background(), params.responseChan)
+	go s.syncOperationalStateByPartition(ctx, params.responseChan)
 	subscribeResp1 := gnmi.SubscribeResponse_Update{
 		Update: &gnmi.Notification{
 			Timestamp: time.Now().Unix(),
@@ -816,17 +829,23 @@ func Test_LikeStratum(t *testing.T) {
 		},
 	}, nil)
 
+	// The subscription is only ended by the test, else it is made again
+	ctx, cancel := context2.WithCancel(context2.Background())
+	defer cancel()
 	mockTarget.EXPECT().Subscribe(
 		gomock.Any(),
 		gomock.AssignableToTypeOf(&gnmi.SubscribeRequest{}),
 		gomock.Any(),
-	).Return(nil).MinTimes(1)
+	).DoAndReturn(func(context2.Context, *gnmi.SubscribeRequest, client.ProtoHandler) error {
+		cancel()
+		return nil
+	}).MinTimes(1)
 
 	// Called asynchronously as after building up the opStateCache it subscribes and waits
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		s.syncOperationalStateByPaths(context2.Background(), responseChan)
+		s.syncOperationalStateByPaths(ctx, responseChan)
 		wg.Done()
 	}()
 	wg.Wait()
@@ -945,3 +964,129 @@ func Test_pathMatchesWildcardNoMatch(t *testing.T) {
 	_, err := pathMatchesWildcard(wildcards, testpath)
 	assert.ErrorContains(t, err, "no match")
 }
+
+func Test_subscribeOpStateResubscribes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context2.WithCancel(context2.Background())
+	defer cancel()
+	mockTarget := southbound.NewMockTargetIf(ctrl)
+	gomock.InOrder(
+		mockTarget.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(status.Error(codes.Unavailable, "stream reset")),
+		mockTarget.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(context2.Context, *gnmi.SubscribeRequest, client.ProtoHandler) error {
+				cancel()
+				return nil
+			}),
+	)
+
+	s := &Synchronizer{
+		Device:               &topodevice.Device{ID: device1},
+		key:                  device1,
+		operationalCache:     devicechange.TypedValueMap{cont1aLeaf1a: devicechange.NewTypedValueString("a")},
+		operationalCacheLock: &sync.RWMutex{},
+		target:               mockTarget,
+	}
+	staleness := make([]bool, 0)
+	s.setStale = func(stale bool) {
+		staleness = append(staleness, stale)
+	}
+	errChan := make(chan events.DeviceResponse, 10)
+	refreshed := 0
+	s.subscribeOpState(ctx, errChan, func(ctx context2.Context, errChan chan<- events.DeviceResponse) error {
+		// The cache is refreshed before subscribing again, until it succeeds
+		refreshed++
+		if refreshed == 1 {
+			return errors.New("device not ready")
+		}
+		return nil
+	})
+	assert.Equal(t, refreshed, 2)
+	assert.DeepEqual(t, staleness, []bool{true, false})
+	assert.Equal(t, len(errChan), 1)
+	assert.Equal(t, (<-errChan).EventType(), events.EventTypeErrorSubscribe)
+}
+
+func Test_subscribeOpStateRestartsOnReconnect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context2.WithCancel(context2.Background())
+	defer cancel()
+	resubscribe := make(chan struct{}, 1)
+	mockTarget := southbound.NewMockTargetIf(ctrl)
+	gomock.InOrder(
+		mockTarget.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context2.Context, request *gnmi.SubscribeRequest, handler client.ProtoHandler) error {
+				// The device is connected again while subscribed
+				resubscribe <- struct{}{}
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+		mockTarget.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(context2.Context, *gnmi.SubscribeRequest, client.ProtoHandler) error {
+				cancel()
+				return nil
+			}),
+	)
+
+	s := &Synchronizer{
+		Device:               &topodevice.Device{ID: device1},
+		key:                  device1,
+		operationalCache:     devicechange.TypedValueMap{cont1aLeaf1a: devicechange.NewTypedValueString("a")},
+		operationalCacheLock: &sync.RWMutex{},
+		resubscribe:          resubscribe,
+		target:               mockTarget,
+	}
+	stale := false
+	s.setStale = func(value bool) {
+		stale = stale || value
+	}
+	errChan := make(chan events.DeviceResponse, 10)
+	s.subscribeOpState(ctx, errChan, func(ctx context2.Context, errChan chan<- events.DeviceResponse) error {
+		t.Fatal("state is not refreshed when the device is connected again")
+		return nil
+	})
+	assert.Assert(t, !stale)
+	assert.Equal(t, len(errChan), 0)
+}
+
+func Test_subscribeOpStateResubscribesAfterEnd(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context2.WithCancel(context2.Background())
+	defer cancel()
+	mockTarget := southbound.NewMockTargetIf(ctrl)
+	gomock.InOrder(
+		// The device ends the subscription without an error
+		mockTarget.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+		mockTarget.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context2.Context, request *gnmi.SubscribeRequest, handler client.ProtoHandler) error {
+				cancel()
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+	)
+
+	s := &Synchronizer{
+		Device:               &topodevice.Device{ID: device1},
+		key:                  device1,
+		operationalCache:     devicechange.TypedValueMap{cont1aLeaf1a: devicechange.NewTypedValueString("a")},
+		operationalCacheLock: &sync.RWMutex{},
+		target:               mockTarget,
+	}
+	staleness := make([]bool, 0)
+	s.setStale = func(stale bool) {
+		staleness = append(staleness, stale)
+	}
+	errChan := make(chan events.DeviceResponse, 10)
+	refreshed := 0
+	s.subscribeOpState(ctx, errChan, func(ctx context2.Context, errChan chan<- events.DeviceResponse) error {
+		refreshed++
+		return nil
+	})
+	// The cache is stale until it is refreshed, and the end is not reported as an error
+	assert.Equal(t, refreshed, 1)
+	assert.DeepEqual(t, staleness, []bool{true, false})
+	assert.Equal(t, len(errChan), 0)
+}
@@ -0,0 +1,91 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package boltdb provides the primitives of the stores backed by an embedded bbolt database
+// file, for single-node deployments that keep their configuration history without Atomix.
+//
+// Every write is a bbolt transaction which is synced to the file before it returns, so that
+// the stores are left consistent if the process or the host crashes.
+package boltdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	bolt "go.etcd.io/bbolt"
+)
+
+var log = logging.GetLogger("store", "boltdb")
+
+// openTimeout is the time to wait for the lock of a database file held by another process
+const openTimeout = 5 * time.Second
+
+// Open opens the database file at the given path, creating it if it does not exist
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, errors.NewUnavailable("failed to open database %s: %v", path, err)
+	}
+	log.Infof("Opened database %s", path)
+	return &DB{
+		db:   db,
+		maps: make(map[string]*IndexedMap),
+	}, nil
+}
+
+// DB is a database file holding the primitives of the stores
+type DB struct {
+	db   *bolt.DB
+	maps map[string]*IndexedMap
+	mu   sync.Mutex
+}
+
+// GetIndexedMap returns the indexed map with the given name, creating it if it does not exist.
+// Stores sharing the database get the same map for the same name, so that they see each
+// other's events
+func (d *DB) GetIndexedMap(name string) (*IndexedMap, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if m, ok := d.maps[name]; ok {
+		return m, nil
+	}
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+		if _, err := root.CreateBucketIfNotExists(entriesBucket); err != nil {
+			return err
+		}
+		_, err = root.CreateBucketIfNotExists(keysBucket)
+		return err
+	})
+	if err != nil {
+		return nil, errors.NewInternal("failed to create map %s: %v", name, err)
+	}
+	m := &IndexedMap{
+		db:       d.db,
+		name:     []byte(name),
+		watchers: make(map[*watcher]struct{}),
+	}
+	d.maps[name] = m
+	return m, nil
+}
+
+// Close closes the database file
+func (d *DB) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,560 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The bucket of a map holds its entries by index, and the index of each key. The indexes are
+// big endian so that the entries are iterated in the order of their indexes
+var (
+	entriesBucket = []byte("entries")
+	keysBucket    = []byte("keys")
+	lastIndexKey  = []byte("last-index")
+)
+
+// Index is the index of an entry in a map
+type Index uint64
+
+// Version is the version of an entry, which changes with every update of the entry
+type Version uint64
+
+// Entry is an entry of a map
+type Entry struct {
+	Key     string
+	Index   Index
+	Value   []byte
+	Version Version
+	Created time.Time
+	Updated time.Time
+}
+
+// EventType is the type of a map event
+type EventType string
+
+const (
+	// EventNone is the type of the events of existing entries replayed to a watch
+	EventNone EventType = ""
+	// EventInserted is the type of the events of new entries
+	EventInserted EventType = "inserted"
+	// EventUpdated is the type of the events of updated entries
+	EventUpdated EventType = "updated"
+	// EventRemoved is the type of the events of removed entries
+	EventRemoved EventType = "removed"
+)
+
+// Event is an event of a map
+type Event struct {
+	Type  EventType
+	Entry *Entry
+}
+
+type updateOptions struct {
+	ifNotSet  bool
+	ifVersion Version
+}
+
+// UpdateOption is a precondition of an update of a map
+type UpdateOption func(*updateOptions)
+
+// IfNotSet returns an UpdateOption that fails the update if the key is already set
+func IfNotSet() UpdateOption {
+	return func(options *updateOptions) {
+		options.ifNotSet = true
+	}
+}
+
+// IfVersion returns an UpdateOption that fails the update if the entry is not at the given version
+func IfVersion(version Version) UpdateOption {
+	return func(options *updateOptions) {
+		options.ifVersion = version
+	}
+}
+
+type watchOptions struct {
+	replay bool
+	key    string
+}
+
+// WatchOption is a configuration option for Watch calls
+type WatchOption func(*watchOptions)
+
+// WithReplay returns a WatchOption that sends the existing entries before the events
+func WithReplay() WatchOption {
+	return func(options *watchOptions) {
+		options.replay = true
+	}
+}
+
+// WithFilter returns a WatchOption that only sends the events of the given key
+func WithFilter(key string) WatchOption {
+	return func(options *watchOptions) {
+		options.key = key
+	}
+}
+
+// IndexedMap is a map of keys to values ordered by the index of the entries
+type IndexedMap struct {
+	db       *bolt.DB
+	name     []byte
+	watchers map[*watcher]struct{}
+	// mu orders the events of the writes with the registration of the watchers
+	mu sync.Mutex
+}
+
+// Get gets the entry of a key, or nil if the key is not set
+func (m *IndexedMap) Get(key string) (*Entry, error) {
+	var entry *Entry
+	err := m.view(func(b *mapBucket) error {
+		entry = b.getKey(key)
+		return nil
+	})
+	return entry, err
+}
+
+// GetIndex gets the entry at an index, or nil if there is none
+func (m *IndexedMap) GetIndex(index Index) (*Entry, error) {
+	var entry *Entry
+	err := m.view(func(b *mapBucket) error {
+		entry = b.get(index)
+		return nil
+	})
+	return entry, err
+}
+
+// PrevEntry gets the entry before an index, or nil if there is none
+func (m *IndexedMap) PrevEntry(index Index) (*Entry, error) {
+	var entry *Entry
+	err := m.view(func(b *mapBucket) error {
+		cursor := b.entries.Cursor()
+		k, v := cursor.Seek(encodeIndex(index))
+		if k == nil {
+			k, v = cursor.Last()
+		} else {
+			k, v = cursor.Prev()
+		}
+		if k != nil {
+			entry = decodeEntry(k, v)
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// NextEntry gets the entry after an index, or nil if there is none
+func (m *IndexedMap) NextEntry(index Index) (*Entry, error) {
+	var entry *Entry
+	err := m.view(func(b *mapBucket) error {
+		if k, v := b.entries.Cursor().Seek(encodeIndex(index + 1)); k != nil {
+			entry = decodeEntry(k, v)
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// Append adds an entry for a new key after the last index of the map
+func (m *IndexedMap) Append(key string, value []byte) (*Entry, error) {
+	return m.update(func(b *mapBucket) (*Event, error) {
+		if b.keys.Get([]byte(key)) != nil {
+			return nil, errors.NewAlreadyExists("key %s already exists", key)
+		}
+		return b.put(b.lastIndex()+1, key, value, nil)
+	})
+}
+
+// Set sets the entry of a key at an index
+func (m *IndexedMap) Set(index Index, key string, value []byte, opts ...UpdateOption) (*Entry, error) {
+	if index == 0 {
+		return nil, errors.NewInvalid("no index specified")
+	}
+	return m.update(func(b *mapBucket) (*Event, error) {
+		entry := b.get(index)
+		if entry != nil && entry.Key != key {
+			return nil, errors.NewConflict("index %d is set for key %s", index, entry.Key)
+		}
+		if entry == nil && b.keys.Get([]byte(key)) != nil {
+			return nil, errors.NewConflict("key %s is set at another index", key)
+		}
+		if err := checkPreconditions(key, entry, opts); err != nil {
+			return nil, err
+		}
+		return b.put(index, key, value, entry)
+	})
+}
+
+// Put sets the entry of a key, appending it to the map if the key is not set
+func (m *IndexedMap) Put(key string, value []byte, opts ...UpdateOption) (*Entry, error) {
+	return m.update(func(b *mapBucket) (*Event, error) {
+		entry := b.getKey(key)
+		if err := checkPreconditions(key, entry, opts); err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return b.put(b.lastIndex()+1, key, value, nil)
+		}
+		return b.put(entry.Index, key, value, entry)
+	})
+}
+
+// Remove removes the entry of a key
+func (m *IndexedMap) Remove(key string, opts ...UpdateOption) (*Entry, error) {
+	return m.update(func(b *mapBucket) (*Event, error) {
+		entry := b.getKey(key)
+		if entry == nil {
+			return nil, errors.NewNotFound("key %s not found", key)
+		}
+		return b.remove(entry, opts)
+	})
+}
+
+// RemoveIndex removes the entry at an index
+func (m *IndexedMap) RemoveIndex(index Index, opts ...UpdateOption) (*Entry, error) {
+	return m.update(func(b *mapBucket) (*Event, error) {
+		entry := b.get(index)
+		if entry == nil {
+			return nil, errors.NewNotFound("index %d not found", index)
+		}
+		return b.remove(entry, opts)
+	})
+}
+
+// Entries sends the entries of the map in the order of their indexes, and closes the channel
+func (m *IndexedMap) Entries(ctx context.Context, ch chan<- *Entry) error {
+	var entries []*Entry
+	err := m.view(func(b *mapBucket) error {
+		entries = b.list("")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer close(ch)
+		for _, entry := range entries {
+			select {
+			case ch <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Watch sends the events of the map until the context is canceled, and then closes the channel.
+// The channel is also closed if the consumer falls too far behind the writes
+func (m *IndexedMap) Watch(ctx context.Context, ch chan<- *Event, opts ...WatchOption) error {
+	options := &watchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w := &watcher{
+		name:      string(m.name),
+		key:       options.key,
+		queueSize: watcherQueueSize,
+		signal:    make(chan struct{}, 1),
+	}
+	if options.replay {
+		err := m.view(func(b *mapBucket) error {
+			for _, entry := range b.list(options.key) {
+				w.events = append(w.events, &Event{Type: EventNone, Entry: entry})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		w.signal <- struct{}{}
+	}
+	m.watchers[w] = struct{}{}
+
+	go func() {
+		defer close(ch)
+		w.run(ctx, ch)
+		m.mu.Lock()
+		delete(m.watchers, w)
+		m.mu.Unlock()
+	}()
+	return nil
+}
+
+func (m *IndexedMap) view(f func(*mapBucket) error) error {
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return f(m.bucket(tx))
+	})
+	if err != nil && errors.TypeOf(err) == errors.Unknown {
+		return errors.NewInternal("failed to read map %s: %v", m.name, err)
+	}
+	return err
+}
+
+// update runs a write transaction, and sends its event to the watchers once it is committed
+func (m *IndexedMap) update(f func(*mapBucket) (*Event, error)) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var event *Event
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		event, err = f(m.bucket(tx))
+		return err
+	})
+	if err != nil && errors.TypeOf(err) == errors.Unknown {
+		return nil, errors.NewInternal("failed to update map %s: %v", m.name, err)
+	} else if err != nil {
+		return nil, err
+	}
+	for w := range m.watchers {
+		w.send(event)
+	}
+	return event.Entry, nil
+}
+
+func (m *IndexedMap) bucket(tx *bolt.Tx) *mapBucket {
+	root := tx.Bucket(m.name)
+	return &mapBucket{
+		root:    root,
+		entries: root.Bucket(entriesBucket),
+		keys:    root.Bucket(keysBucket),
+	}
+}
+
+func checkPreconditions(key string, entry *Entry, opts []UpdateOption) error {
+	options := &updateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.ifNotSet && entry != nil {
+		return errors.NewAlreadyExists("key %s already exists", key)
+	}
+	if options.ifVersion != 0 {
+		if entry == nil {
+			return errors.NewNotFound("key %s not found", key)
+		} else if entry.Version != options.ifVersion {
+			return errors.NewConflict("key %s has been updated since version %d", key, options.ifVersion)
+		}
+	}
+	return nil
+}
+
+// mapBucket is the bucket of a map within a transaction
+type mapBucket struct {
+	root    *bolt.Bucket
+	entries *bolt.Bucket
+	keys    *bolt.Bucket
+}
+
+func (b *mapBucket) get(index Index) *Entry {
+	key := encodeIndex(index)
+	value := b.entries.Get(key)
+	if value == nil {
+		return nil
+	}
+	return decodeEntry(key, value)
+}
+
+func (b *mapBucket) getKey(key string) *Entry {
+	index := b.keys.Get([]byte(key))
+	if index == nil {
+		return nil
+	}
+	return b.get(decodeIndex(index))
+}
+
+func (b *mapBucket) lastIndex() Index {
+	if value := b.root.Get(lastIndexKey); value != nil {
+		return decodeIndex(value)
+	}
+	return 0
+}
+
+// list returns the entries of the map, or the entry of a key if it is not empty
+func (b *mapBucket) list(key string) []*Entry {
+	if key != "" {
+		if entry := b.getKey(key); entry != nil {
+			return []*Entry{entry}
+		}
+		return nil
+	}
+	var entries []*Entry
+	_ = b.entries.ForEach(func(k, v []byte) error {
+		entries = append(entries, decodeEntry(k, v))
+		return nil
+	})
+	return entries
+}
+
+// put writes the entry of a key at an index, replacing the previous entry of the key if any
+func (b *mapBucket) put(index Index, key string, value []byte, prev *Entry) (*Event, error) {
+	version, err := b.root.NextSequence()
+	if err != nil {
+		return nil, err
+	}
+	// The monotonic clock reading is dropped, as it is not stored
+	now := time.Now().Round(0)
+	entry := &Entry{
+		Key:     key,
+		Index:   index,
+		Value:   value,
+		Version: Version(version),
+		Created: now,
+		Updated: now,
+	}
+	eventType := EventInserted
+	if prev != nil {
+		entry.Created = prev.Created
+		eventType = EventUpdated
+	}
+	if err := b.entries.Put(encodeIndex(index), encodeEntry(entry)); err != nil {
+		return nil, err
+	}
+	if err := b.keys.Put([]byte(key), encodeIndex(index)); err != nil {
+		return nil, err
+	}
+	if index > b.lastIndex() {
+		if err := b.root.Put(lastIndexKey, encodeIndex(index)); err != nil {
+			return nil, err
+		}
+	}
+	return &Event{Type: eventType, Entry: entry}, nil
+}
+
+func (b *mapBucket) remove(entry *Entry, opts []UpdateOption) (*Event, error) {
+	if err := checkPreconditions(entry.Key, entry, opts); err != nil {
+		return nil, err
+	}
+	if err := b.entries.Delete(encodeIndex(entry.Index)); err != nil {
+		return nil, err
+	}
+	if err := b.keys.Delete([]byte(entry.Key)); err != nil {
+		return nil, err
+	}
+	entry.Updated = time.Now().Round(0)
+	return &Event{Type: EventRemoved, Entry: entry}, nil
+}
+
+func encodeIndex(index Index) []byte {
+	bytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(bytes, uint64(index))
+	return bytes
+}
+
+func decodeIndex(bytes []byte) Index {
+	return Index(binary.BigEndian.Uint64(bytes))
+}
+
+// encodeEntry encodes the version, the timestamps, the key and the value of an entry
+func encodeEntry(entry *Entry) []byte {
+	bytes := make([]byte, 28, 28+len(entry.Key)+len(entry.Value))
+	binary.BigEndian.PutUint64(bytes[0:], uint64(entry.Version))
+	binary.BigEndian.PutUint64(bytes[8:], uint64(entry.Created.UnixNano()))
+	binary.BigEndian.PutUint64(bytes[16:], uint64(entry.Updated.UnixNano()))
+	binary.BigEndian.PutUint32(bytes[24:], uint32(len(entry.Key)))
+	bytes = append(bytes, entry.Key...)
+	return append(bytes, entry.Value...)
+}
+
+// decodeEntry decodes an entry, copying its value out of the transaction
+func decodeEntry(index []byte, bytes []byte) *Entry {
+	keyLength := int(binary.BigEndian.Uint32(bytes[24:]))
+	value := make([]byte, len(bytes)-28-keyLength)
+	copy(value, bytes[28+keyLength:])
+	return &Entry{
+		Key:     string(bytes[28 : 28+keyLength]),
+		Index:   decodeIndex(index),
+		Value:   value,
+		Version: Version(binary.BigEndian.Uint64(bytes[0:])),
+		Created: time.Unix(0, int64(binary.BigEndian.Uint64(bytes[8:]))),
+		Updated: time.Unix(0, int64(binary.BigEndian.Uint64(bytes[16:]))),
+	}
+}
+
+// watcherQueueSize is the number of events queued for a watch whose consumer is not
+// keeping up. A watch falling further behind is dropped, and its channel closed
+const watcherQueueSize = 10000
+
+// watcher queues the events of a watch, so that writers are not blocked by the consumer
+type watcher struct {
+	name      string
+	key       string
+	events    []*Event
+	queueSize int
+	// queued is the number of events written since the consumer last caught up,
+	// which excludes the replayed entries
+	queued  int
+	dropped bool
+	signal  chan struct{}
+	mu      sync.Mutex
+}
+
+func (w *watcher) send(event *Event) {
+	if w.key != "" && event.Entry.Key != w.key {
+		return
+	}
+	w.mu.Lock()
+	if w.dropped {
+		w.mu.Unlock()
+		return
+	}
+	if w.queued == w.queueSize {
+		log.Errorf("Dropping a watch of map %s: %d events are queued", w.name, w.queued)
+		w.dropped = true
+		w.events = nil
+	} else {
+		w.events = append(w.events, event)
+		w.queued++
+	}
+	w.mu.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) run(ctx context.Context, ch chan<- *Event) {
+	for {
+		select {
+		case <-w.signal:
+		case <-ctx.Done():
+			return
+		}
+		w.mu.Lock()
+		if w.dropped {
+			w.mu.Unlock()
+			return
+		}
+		events := w.events
+		w.events = nil
+		w.queued = 0
+		w.mu.Unlock()
+		for _, event := range events {
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDB(t *testing.T) (*DB, string) {
+	dir, err := ioutil.TempDir("", "boltdb")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "onos-config.db")
+	db, err := Open(path)
+	assert.NoError(t, err)
+	return db, path
+}
+
+func TestIndexedMap(t *testing.T) {
+	db, path := newTestDB(t)
+	m, err := db.GetIndexedMap("test")
+	assert.NoError(t, err)
+
+	entry1, err := m.Append("a", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, Index(1), entry1.Index)
+	entry2, err := m.Append("b", []byte("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, Index(2), entry2.Index)
+	_, err = m.Append("a", []byte("1"))
+	assert.True(t, errors.IsAlreadyExists(err))
+
+	entry5, err := m.Set(5, "c", []byte("5"), IfNotSet())
+	assert.NoError(t, err)
+	_, err = m.Set(5, "d", []byte("5"))
+	assert.True(t, errors.IsConflict(err))
+
+	// Updates must be of the current version
+	updated, err := m.Set(1, "a", []byte("11"), IfVersion(entry1.Version))
+	assert.NoError(t, err)
+	assert.NotEqual(t, entry1.Version, updated.Version)
+	assert.Equal(t, entry1.Created, updated.Created)
+	_, err = m.Set(1, "a", []byte("12"), IfVersion(entry1.Version))
+	assert.True(t, errors.IsConflict(err))
+
+	prev, err := m.PrevEntry(5)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", prev.Key)
+	prev, err = m.PrevEntry(10)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", prev.Key)
+	prev, err = m.PrevEntry(1)
+	assert.NoError(t, err)
+	assert.Nil(t, prev)
+	next, err := m.NextEntry(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", next.Key)
+	next, err = m.NextEntry(5)
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	_, err = m.RemoveIndex(2, IfVersion(entry2.Version))
+	assert.NoError(t, err)
+	_, err = m.RemoveIndex(2)
+	assert.True(t, errors.IsNotFound(err))
+
+	// The entries are kept when the database is reopened, and new entries are appended
+	// after the last index
+	assert.NoError(t, db.Close())
+	db, err = Open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	m, err = db.GetIndexedMap("test")
+	assert.NoError(t, err)
+
+	entry, err := m.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "11", string(entry.Value))
+	assert.Equal(t, updated.Version, entry.Version)
+	entry, err = m.GetIndex(5)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", entry.Key)
+	assert.Equal(t, entry5.Created.UnixNano(), entry.Created.UnixNano())
+	entry, err = m.Get("b")
+	assert.NoError(t, err)
+	assert.Nil(t, entry)
+
+	entry, err = m.Put("d", []byte("6"))
+	assert.NoError(t, err)
+	assert.Equal(t, Index(6), entry.Index)
+
+	ch := make(chan *Entry)
+	assert.NoError(t, m.Entries(context.Background(), ch))
+	var keys []string
+	for entry := range ch {
+		keys = append(keys, entry.Key)
+	}
+	assert.Equal(t, []string{"a", "c", "d"}, keys)
+}
+
+func TestIndexedMapWatch(t *testing.T) {
+	db, _ := newTestDB(t)
+	defer db.Close()
+	m, err := db.GetIndexedMap("test")
+	assert.NoError(t, err)
+
+	_, err = m.Append("a", []byte("1"))
+	assert.NoError(t, err)
+	_, err = m.Append("b", []byte("2"))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan *Event)
+	assert.NoError(t, m.Watch(ctx, ch, WithReplay()))
+	keyCh := make(chan *Event)
+	assert.NoError(t, m.Watch(ctx, keyCh, WithFilter("c")))
+
+	// Writes are not blocked by watchers that are not consuming their events
+	_, err = m.Append("c", []byte("3"))
+	assert.NoError(t, err)
+	entry, err := m.Put("c", []byte("4"))
+	assert.NoError(t, err)
+	_, err = m.Remove("c", IfVersion(entry.Version))
+	assert.NoError(t, err)
+
+	for _, key := range []string{"a", "b"} {
+		event := <-ch
+		assert.Equal(t, EventNone, event.Type)
+		assert.Equal(t, key, event.Entry.Key)
+	}
+	for _, eventType := range []EventType{EventInserted, EventUpdated, EventRemoved} {
+		event := <-ch
+		assert.Equal(t, eventType, event.Type)
+		assert.Equal(t, "c", event.Entry.Key)
+		assert.Equal(t, eventType, (<-keyCh).Type)
+	}
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+	_, ok = <-keyCh
+	assert.False(t, ok)
+}
+
+func TestWatcherQueueLimit(t *testing.T) {
+	w := &watcher{
+		name:      "test",
+		queueSize: 2,
+		signal:    make(chan struct{}, 1),
+	}
+
+	// A consumer falling behind by more than the queue size is dropped
+	for _, key := range []string{"a", "b", "c"} {
+		w.send(&Event{Type: EventInserted, Entry: &Entry{Key: key}})
+	}
+	ch := make(chan *Event)
+	done := make(chan struct{})
+	go func() {
+		w.run(context.Background(), ch)
+		close(done)
+	}()
+	<-done
+	assert.True(t, w.dropped)
+	assert.Empty(t, w.events)
+}
@@ -0,0 +1,269 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/proto"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// NewBoltStore returns a device change store kept in an embedded database file
+func NewBoltStore(db *boltdb.DB) (Store, error) {
+	return &boltStore{
+		db: db,
+	}, nil
+}
+
+// boltStore is a device change store kept in an embedded database file, with a map
+// of changes for each device version
+type boltStore struct {
+	db *boltdb.DB
+}
+
+func (s *boltStore) getDeviceChanges(deviceID device.VersionedID) (*boltdb.IndexedMap, error) {
+	return s.db.GetIndexedMap(getDeviceChangesName(deviceID))
+}
+
+func (s *boltStore) Get(id devicechange.ID) (*devicechange.DeviceChange, error) {
+	changes, err := s.getDeviceChanges(id.GetDeviceVersionedID())
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := changes.Get(string(id))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, nil
+	}
+	return decodeBoltChange(entry)
+}
+
+func (s *boltStore) Create(change *devicechange.DeviceChange) error {
+	if change.Index == 0 {
+		return errors.NewInvalid("no change index specified")
+	}
+	if change.NetworkChange.ID == "" {
+		return errors.NewInvalid("no NetworkChange ID specified")
+	}
+	if change.Revision != 0 {
+		return errors.NewInvalid("not a new object")
+	}
+	if change.Change.DeviceID == "" {
+		return errors.NewInvalid("no device ID specified")
+	}
+	if change.Change.DeviceVersion == "" {
+		return errors.NewInvalid("no device version specified")
+	}
+	if change.Change.DeviceType == "" {
+		return errors.NewInvalid("no device type specified")
+	}
+
+	change.ID = devicechange.NewID(change.NetworkChange.ID, change.Change.DeviceID, change.Change.DeviceVersion)
+
+	changes, err := s.getDeviceChanges(change.Change.GetVersionedDeviceID())
+	if err != nil {
+		return err
+	}
+
+	bytes, err := proto.Marshal(change)
+	if err != nil {
+		return errors.NewInvalid("change encoding failed: %v", err)
+	}
+
+	entry, err := changes.Set(boltdb.Index(change.Index), string(change.ID), bytes, boltdb.IfNotSet())
+	if err != nil {
+		return err
+	}
+
+	change.Index = devicechange.Index(entry.Index)
+	change.Revision = devicechange.Revision(entry.Version)
+	change.Created = entry.Created
+	change.Updated = entry.Updated
+	log.Infof("Created new device change %s", change.ID)
+	return nil
+}
+
+func (s *boltStore) Update(change *devicechange.DeviceChange) error {
+	if change.ID == "" {
+		return errors.NewInvalid("no change ID configured")
+	}
+	if change.Index == 0 {
+		return errors.NewInvalid("not a stored object: no storage index found")
+	}
+	if change.Revision == 0 {
+		return errors.NewInvalid("not a stored object: no storage revision found")
+	}
+	if change.Change.DeviceID == "" {
+		return errors.NewInvalid("no device ID specified")
+	}
+	if change.Change.DeviceVersion == "" {
+		return errors.NewInvalid("no device version specified")
+	}
+	if change.Change.DeviceType == "" {
+		return errors.NewInvalid("no device type specified")
+	}
+
+	changes, err := s.getDeviceChanges(change.Change.GetVersionedDeviceID())
+	if err != nil {
+		return err
+	}
+
+	bytes, err := proto.Marshal(change)
+	if err != nil {
+		return errors.NewInvalid("change encoding failed: %v", err)
+	}
+
+	entry, err := changes.Set(boltdb.Index(change.Index), string(change.ID), bytes, boltdb.IfVersion(boltdb.Version(change.Revision)))
+	if err != nil {
+		return err
+	}
+
+	change.Revision = devicechange.Revision(entry.Version)
+	if change.Created.IsZero() {
+		change.Created = entry.Created
+	}
+	change.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) Delete(change *devicechange.DeviceChange) error {
+	if change.ID == "" {
+		return errors.NewInvalid("no change ID configured")
+	}
+	if change.Index == 0 {
+		return errors.NewInvalid("not a stored object: no storage index found")
+	}
+	if change.Revision == 0 {
+		return errors.NewInvalid("not a stored object")
+	}
+
+	changes, err := s.getDeviceChanges(change.Change.GetVersionedDeviceID())
+	if err != nil {
+		return err
+	}
+
+	entry, err := changes.RemoveIndex(boltdb.Index(change.Index), boltdb.IfVersion(boltdb.Version(change.Revision)))
+	if err != nil {
+		return err
+	}
+
+	change.Revision = 0
+	change.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) List(deviceID device.VersionedID, ch chan<- *devicechange.DeviceChange) (stream.Context, error) {
+	changes, err := s.getDeviceChanges(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Entry)
+	if err := changes.Entries(ctx, mapCh); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for entry := range mapCh {
+			if change, err := decodeBoltChange(entry); err == nil {
+				ch <- change
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+func (s *boltStore) Watch(deviceID device.VersionedID, ch chan<- stream.Event, opts ...WatchOption) (stream.Context, error) {
+	changes, err := s.getDeviceChanges(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	watchOpts := make([]boltdb.WatchOption, 0)
+	for _, opt := range opts {
+		watchOpts = opt.applyBolt(watchOpts)
+	}
+	filter := newWatchFilter(opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mapCh := make(chan *boltdb.Event)
+	if err := changes.Watch(ctx, mapCh, watchOpts...); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if change, err := decodeBoltChange(event.Entry); err == nil {
+				if event.Type == boltdb.EventNone && !filter.replays(change) {
+					continue
+				}
+				switch event.Type {
+				case boltdb.EventNone:
+					ch <- stream.Event{
+						Type:   stream.None,
+						Object: change,
+					}
+				case boltdb.EventInserted:
+					ch <- stream.Event{
+						Type:   stream.Created,
+						Object: change,
+					}
+				case boltdb.EventUpdated:
+					ch <- stream.Event{
+						Type:   stream.Updated,
+						Object: change,
+					}
+				case boltdb.EventRemoved:
+					ch <- stream.Event{
+						Type:   stream.Deleted,
+						Object: change,
+					}
+				}
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+// Close does not close the database, which is shared with the other stores
+func (s *boltStore) Close() error {
+	return nil
+}
+
+func decodeBoltChange(entry *boltdb.Entry) (*devicechange.DeviceChange, error) {
+	change := &devicechange.DeviceChange{}
+	if err := proto.Unmarshal(entry.Value, change); err != nil {
+		return nil, errors.NewInvalid("change decoding failed: %v", err)
+	}
+	change.ID = devicechange.ID(entry.Key)
+	change.Index = devicechange.Index(entry.Index)
+	change.Revision = devicechange.Revision(entry.Version)
+	change.Created = entry.Created
+	change.Updated = entry.Updated
+	return change, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	types "github.com/onosproject/onos-api/go/onos/config"
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBoltChange(networkChangeID types.ID, index devicechange.Index) *devicechange.DeviceChange {
+	return &devicechange.DeviceChange{
+		Index:         index,
+		NetworkChange: devicechange.NetworkChangeRef{ID: networkChangeID, Index: types.Index(index)},
+		Change: &devicechange.Change{
+			DeviceID:      "device-1",
+			DeviceVersion: "1.0.0",
+			DeviceType:    "Stratum",
+			Values:        []*devicechange.ChangeValue{{Path: "foo", Value: devicechange.NewTypedValueString("bar")}},
+		},
+	}
+}
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "device-changes")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "onos-config.db")
+
+	db, err := boltdb.Open(path)
+	assert.NoError(t, err)
+	store, err := NewBoltStore(db)
+	assert.NoError(t, err)
+
+	change1 := newBoltChange("change-1", 1)
+	assert.NoError(t, store.Create(change1))
+	change2 := newBoltChange("change-2", 3)
+	assert.NoError(t, store.Create(change2))
+	assert.True(t, errors.IsAlreadyExists(store.Create(newBoltChange("change-2", 3))))
+
+	change1.Status.State = changetypes.State_COMPLETE
+	assert.NoError(t, store.Update(change1))
+	change2.Revision++
+	assert.True(t, errors.IsConflict(store.Update(change2)))
+	change2.Revision--
+
+	// The changes are kept when the database is reopened
+	assert.NoError(t, db.Close())
+	db, err = boltdb.Open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	store, err = NewBoltStore(db)
+	assert.NoError(t, err)
+
+	change, err := store.Get(change1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, changetypes.State_COMPLETE, change.Status.State)
+	assert.Equal(t, "bar", change.Change.Values[0].Value.ValueToString())
+
+	listCh := make(chan *devicechange.DeviceChange)
+	_, err = store.List(change1.Change.GetVersionedDeviceID(), listCh)
+	assert.NoError(t, err)
+	assert.Equal(t, change1.ID, nextDeviceChange(t, listCh).ID)
+	assert.Equal(t, devicechange.Index(3), nextDeviceChange(t, listCh).Index)
+
+	// A watch resumed from an index replays the changes from the index on
+	resumeCh := make(chan stream.Event)
+	resumeCtx, err := store.Watch(change1.Change.GetVersionedDeviceID(), resumeCh, WithIndex(2))
+	assert.NoError(t, err)
+	defer resumeCtx.Close()
+	assert.Equal(t, devicechange.Index(3), nextEvent(t, resumeCh).Index)
+
+	// A watch resumed from a revision replays the changes updated since
+	revisionCh := make(chan stream.Event)
+	revisionCtx, err := store.Watch(change1.Change.GetVersionedDeviceID(), revisionCh, WithRevision(change2.Revision))
+	assert.NoError(t, err)
+	defer revisionCtx.Close()
+	assert.Equal(t, change1.ID, nextEvent(t, revisionCh).ID)
+
+	ch := make(chan stream.Event)
+	ctx, err := store.Watch(change1.Change.GetVersionedDeviceID(), ch, WithChangeID(change1.ID))
+	assert.NoError(t, err)
+	defer ctx.Close()
+	change, err = store.Get(change1.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Delete(change))
+	event := <-ch
+	assert.Equal(t, stream.Deleted, event.Type)
+	assert.Equal(t, change1.ID, event.Object.(*devicechange.DeviceChange).ID)
+}
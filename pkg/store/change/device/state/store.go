@@ -20,12 +20,12 @@ import (
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
 	devicetype "github.com/onosproject/onos-api/go/onos/config/device"
+	devicesnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/device"
 	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
 	devicesnapshotstore "github.com/onosproject/onos-config/pkg/store/snapshot/device"
 	"github.com/onosproject/onos-config/pkg/store/stream"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 )
@@ -41,6 +41,9 @@ func NewStore(networkChangeStore networkchangestore.Store, deviceSnapshotStore d
 	if err := store.listen(); err != nil {
 		return nil, err
 	}
+	if err := store.watchSnapshots(); err != nil {
+		return nil, err
+	}
 	return store, nil
 }
 
@@ -57,7 +60,6 @@ type deviceChangeStoreStateStore struct {
 	devices       map[devicetype.VersionedID]*deviceChangeStateStore
 	waiters       map[networkchange.Revision]chan struct{}
 	changeIndex   networkchange.Index
-	rollbackIndex networkchange.Index
 	revision      networkchange.Revision
 	mu            sync.RWMutex
 }
@@ -79,10 +81,37 @@ func (s *deviceChangeStoreStateStore) watch() error {
 	return nil
 }
 
+// watchSnapshots drops the state of device versions whose snapshot is purged
+func (s *deviceChangeStoreStateStore) watchSnapshots() error {
+	ch := make(chan stream.Event)
+	watchCtx, err := s.snapshotStore.WatchAll(ch)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer watchCtx.Close()
+		for event := range ch {
+			if event.Type != stream.Deleted {
+				continue
+			}
+			snapshot := event.Object.(*devicesnapshot.Snapshot)
+			s.mu.Lock()
+			s.dropDevice(snapshot.GetVersionedDeviceID(), false)
+			s.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
 func (s *deviceChangeStoreStateStore) processCh(ch chan stream.Event) {
 	for event := range ch {
 		s.mu.Lock()
-		err := s.processChange(event.Object.(*networkchange.NetworkChange))
+		var err error
+		if event.Type == stream.Deleted {
+			s.processNetworkDelete(event.Object.(*networkchange.NetworkChange))
+		} else {
+			err = s.processChange(event.Object.(*networkchange.NetworkChange))
+		}
 		s.mu.Unlock()
 		if err != nil {
 			go func() {
@@ -104,13 +133,9 @@ func (s *deviceChangeStoreStateStore) processChange(networkChange *networkchange
 		}
 		s.changeIndex = networkChange.Index
 	case changetype.Phase_ROLLBACK:
-		if networkChange.Index <= s.rollbackIndex {
-			return nil
-		}
 		if err := s.processNetworkRollback(networkChange); err != nil {
 			return err
 		}
-		s.rollbackIndex = networkChange.Index
 	}
 
 	if networkChange.Revision > s.revision {
@@ -125,96 +150,75 @@ func (s *deviceChangeStoreStateStore) processChange(networkChange *networkchange
 	return nil
 }
 
-func (s *deviceChangeStoreStateStore) processNetworkChange(networkChange *networkchange.NetworkChange) error {
-	for _, deviceChange := range networkChange.Changes {
-		state, ok := s.devices[deviceChange.GetVersionedDeviceID()]
-		if !ok {
-			state = &deviceChangeStateStore{
-				deviceID: deviceChange.GetVersionedDeviceID(),
-				state:    make(map[string]*devicechange.TypedValue),
-			}
-			snapshot, err := s.snapshotStore.Load(deviceChange.GetVersionedDeviceID())
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					return err
-				}
-			} else {
-				for _, value := range snapshot.Values {
-					state.update(value)
-				}
-			}
-			s.devices[deviceChange.GetVersionedDeviceID()] = state
+// getDevice returns the state of a device, starting from the snapshot of the device if it has no state yet
+func (s *deviceChangeStoreStateStore) getDevice(id devicetype.VersionedID) (*deviceChangeStateStore, error) {
+	state, ok := s.devices[id]
+	if ok {
+		return state, nil
+	}
+	state = newDeviceChangeStateStore(id)
+	snapshot, err := s.snapshotStore.Load(id)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else if snapshot != nil {
+		for _, value := range snapshot.Values {
+			state.tree.set(value.Path, value.Value)
 		}
+	}
+	s.devices[id] = state
+	return state, nil
+}
 
-		for _, value := range deviceChange.Values {
-			if value.Removed {
-				state.remove(value.Path)
-			} else {
-				state.update(&devicechange.PathValue{
-					Path:  value.Path,
-					Value: value.Value,
-				})
-			}
+func (s *deviceChangeStoreStateStore) processNetworkChange(networkChange *networkchange.NetworkChange) error {
+	for _, deviceChange := range networkChange.Changes {
+		state, err := s.getDevice(deviceChange.GetVersionedDeviceID())
+		if err != nil {
+			return err
 		}
+		state.apply(networkChange.Index, deviceChange.Values)
 	}
 	return nil
 }
 
+// processNetworkRollback reverts the devices of a network change to their state before the change,
+// from the inverse of the changes applied since. A change that is not applied to a device, or that
+// is already reverted, leaves the device unchanged
 func (s *deviceChangeStoreStateStore) processNetworkRollback(networkChange *networkchange.NetworkChange) error {
-	listCh := make(chan *networkchange.NetworkChange)
-	listCtx, err := s.changeStore.List(listCh)
-	if err != nil {
-		listCtx.Close()
-		return err
-	}
-
-	states := make(map[devicetype.VersionedID]*deviceChangeStateStore)
-	for _, devChange := range networkChange.Changes {
-		state := &deviceChangeStateStore{
-			deviceID: devChange.GetVersionedDeviceID(),
-			state:    make(map[string]*devicechange.TypedValue),
-		}
-		snapshot, err := s.snapshotStore.Load(devChange.GetVersionedDeviceID())
+	for _, deviceChange := range networkChange.Changes {
+		state, err := s.getDevice(deviceChange.GetVersionedDeviceID())
 		if err != nil {
-			if !errors.IsNotFound(err) {
-				listCtx.Close()
-				return err
-			}
-		} else if snapshot != nil {
-			for _, value := range snapshot.Values {
-				state.update(value)
-			}
+			return err
 		}
-		states[devChange.GetVersionedDeviceID()] = state
+		state.rollback(networkChange.Index)
 	}
+	return nil
+}
 
-	for netChange := range listCh {
-		if netChange.Index >= networkChange.Index {
-			listCtx.Close()
-			break
-		}
-		if netChange.Status.Phase == changetype.Phase_CHANGE {
-			for _, devChange := range netChange.Changes {
-				state, ok := states[devChange.GetVersionedDeviceID()]
-				if ok {
-					for _, value := range devChange.Values {
-						if value.Removed {
-							state.remove(value.Path)
-						} else {
-							state.update(&devicechange.PathValue{
-								Path:  value.Path,
-								Value: value.Value,
-							})
-						}
-					}
-				}
-			}
+// processNetworkDelete drops the inverse of a deleted network change, which can no longer be rolled back
+func (s *deviceChangeStoreStateStore) processNetworkDelete(networkChange *networkchange.NetworkChange) {
+	for _, deviceChange := range networkChange.Changes {
+		if state, ok := s.devices[deviceChange.GetVersionedDeviceID()]; ok {
+			state.discard(networkChange.Index)
+			s.dropDevice(state.deviceID, true)
 		}
 	}
-	for device, state := range states {
-		s.devices[device] = state
+}
+
+// dropDevice drops the state of a device once no applied change and no snapshot is left to
+// build it from, as when a version of the device is deleted
+func (s *deviceChangeStoreStateStore) dropDevice(id devicetype.VersionedID, checkSnapshot bool) {
+	state, ok := s.devices[id]
+	if !ok || len(state.changes) > 0 {
+		return
 	}
-	return nil
+	if checkSnapshot {
+		if _, err := s.snapshotStore.Load(id); err == nil || !errors.IsNotFound(err) {
+			return
+		}
+	}
+	delete(s.devices, id)
 }
 
 func (s *deviceChangeStoreStateStore) Get(id devicetype.VersionedID, revision networkchange.Revision) ([]*devicechange.PathValue, error) {
@@ -250,36 +254,86 @@ func (s *deviceChangeStoreStateStore) Get(id devicetype.VersionedID, revision ne
 	return device.get()
 }
 
-// deviceChangeStateStore is a device state store that listens to changes for a specific device
+func newDeviceChangeStateStore(id devicetype.VersionedID) *deviceChangeStateStore {
+	return &deviceChangeStateStore{
+		deviceID: id,
+		tree:     newPathTree(),
+	}
+}
+
+// deviceChangeStateStore is the state of a device, from its snapshot and the log of the network
+// changes applied to it since in index order
 type deviceChangeStateStore struct {
 	deviceID devicetype.VersionedID
-	state    map[string]*devicechange.TypedValue
+	tree     *pathTree
+	changes  []*appliedChange
 }
 
-func (s *deviceChangeStateStore) update(value *devicechange.PathValue) {
-	s.state[value.Path] = value.Value
+// appliedChange is a network change applied to a device, with the values of the paths before the
+// change in the order they are restored. A nil value restores a path without value
+type appliedChange struct {
+	index   networkchange.Index
+	inverse []*devicechange.PathValue
 }
 
-func (s *deviceChangeStateStore) remove(rootPath string) {
-	delete(s.state, rootPath)
-	for path := range s.state {
-		if strings.Contains(path, rootPath) {
-			delete(s.state, path)
+// apply applies the values of a change to the device and logs the inverse of the change
+func (s *deviceChangeStateStore) apply(index networkchange.Index, values []*devicechange.ChangeValue) {
+	inverse := make([]*devicechange.PathValue, 0, len(values))
+	for _, value := range values {
+		if value.Removed {
+			inverse = append(inverse, s.tree.remove(value.Path)...)
+		} else {
+			inverse = append(inverse, &devicechange.PathValue{
+				Path:  value.Path,
+				Value: s.tree.set(value.Path, value.Value),
+			})
 		}
 	}
+	s.changes = append(s.changes, &appliedChange{
+		index:   index,
+		inverse: inverse,
+	})
 }
 
-// get gets the state of the device up to the given revision
-func (s *deviceChangeStateStore) get() ([]*devicechange.PathValue, error) {
-	state := make([]*devicechange.PathValue, 0, len(s.state))
-	for path, value := range s.state {
-		state = append(state, &devicechange.PathValue{
-			Path:  path,
-			Value: value,
-		})
+// rollback reverts the device to its state before the change at the given index, reverting the
+// changes applied since in reverse order
+func (s *deviceChangeStateStore) rollback(index networkchange.Index) {
+	if !s.applied(index) {
+		return
 	}
-	sort.Slice(state, func(i, j int) bool {
-		return state[i].Path < state[j].Path
-	})
-	return state, nil
+	for len(s.changes) > 0 && s.changes[len(s.changes)-1].index >= index {
+		change := s.changes[len(s.changes)-1]
+		for i := len(change.inverse) - 1; i >= 0; i-- {
+			value := change.inverse[i]
+			if value.Value == nil {
+				s.tree.clear(value.Path)
+			} else {
+				s.tree.set(value.Path, value.Value)
+			}
+		}
+		s.changes = s.changes[:len(s.changes)-1]
+	}
+}
+
+// applied returns whether the change at the given index is applied to the device
+func (s *deviceChangeStateStore) applied(index networkchange.Index) bool {
+	n := sort.Search(len(s.changes), func(i int) bool { return s.changes[i].index >= index })
+	return n < len(s.changes) && s.changes[n].index == index
+}
+
+// discard drops the inverse of the change at the given index from the log
+func (s *deviceChangeStateStore) discard(index networkchange.Index) {
+	for i, change := range s.changes {
+		if change.index == index {
+			s.changes = append(s.changes[:i], s.changes[i+1:]...)
+			return
+		} else if change.index > index {
+			return
+		}
+	}
+}
+
+// get gets the state of the device
+func (s *deviceChangeStateStore) get() ([]*devicechange.PathValue, error) {
+	return s.tree.values(), nil
 }
@@ -15,13 +15,16 @@
 package state
 
 import (
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
 	"github.com/onosproject/onos-api/go/onos/config/device"
+	devicesnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/device"
 	networkchangestore "github.com/onosproject/onos-config/pkg/store/change/network"
 	devicesnapstore "github.com/onosproject/onos-config/pkg/store/snapshot/device"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 // TestDeviceStateStore tests that device changes are propagated to the device state store
@@ -86,3 +89,127 @@ func TestDeviceStateStore(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, state, 0)
 }
+
+func newTestChange(values ...*devicechange.ChangeValue) *networkchange.NetworkChange {
+	return &networkchange.NetworkChange{
+		Changes: []*devicechange.Change{
+			{
+				DeviceID:      "test",
+				DeviceVersion: "1.0.0",
+				DeviceType:    "Stratum",
+				Values:        values,
+			},
+		},
+	}
+}
+
+// TestDeviceStateStoreRollback tests that rolled back changes are reverted in the device state store
+func TestDeviceStateStoreRollback(t *testing.T) {
+	changeStore, err := networkchangestore.NewLocalStore()
+	assert.NoError(t, err)
+	snapshotStore, err := devicesnapstore.NewLocalStore()
+	assert.NoError(t, err)
+
+	store, err := NewStore(changeStore, snapshotStore)
+	assert.NoError(t, err)
+	deviceID := device.NewVersionedID("test", "1.0.0")
+
+	change1 := newTestChange(
+		&devicechange.ChangeValue{Path: "/a/b", Value: devicechange.NewTypedValueString("b1")},
+		&devicechange.ChangeValue{Path: "/a/b/c", Value: devicechange.NewTypedValueString("c1")},
+		&devicechange.ChangeValue{Path: "/x/a/b", Value: devicechange.NewTypedValueString("x1")})
+	assert.NoError(t, changeStore.Create(change1))
+
+	// Removing a path does not remove the paths containing it
+	change2 := newTestChange(
+		&devicechange.ChangeValue{Path: "/a/b", Removed: true},
+		&devicechange.ChangeValue{Path: "/a/d", Value: devicechange.NewTypedValueString("d2")})
+	assert.NoError(t, changeStore.Create(change2))
+	state, err := store.Get(deviceID, change2.Revision)
+	assert.NoError(t, err)
+	assert.Len(t, state, 2)
+	assert.Equal(t, "/a/d", state[0].Path)
+	assert.Equal(t, "/x/a/b", state[1].Path)
+
+	change3 := newTestChange(
+		&devicechange.ChangeValue{Path: "/x/a/b", Value: devicechange.NewTypedValueString("x3")})
+	assert.NoError(t, changeStore.Create(change3))
+	state, err = store.Get(deviceID, change3.Revision)
+	assert.NoError(t, err)
+	assert.Len(t, state, 2)
+	assert.Equal(t, "x3", string(state[1].Value.Bytes))
+
+	change3.Status.Phase = changetypes.Phase_ROLLBACK
+	assert.NoError(t, changeStore.Update(change3))
+	state, err = store.Get(deviceID, change3.Revision)
+	assert.NoError(t, err)
+	assert.Len(t, state, 2)
+	assert.Equal(t, "x1", string(state[1].Value.Bytes))
+
+	change2.Status.Phase = changetypes.Phase_ROLLBACK
+	assert.NoError(t, changeStore.Update(change2))
+	state, err = store.Get(deviceID, change2.Revision)
+	assert.NoError(t, err)
+	assert.Len(t, state, 3)
+	assert.Equal(t, "/a/b", state[0].Path)
+	assert.Equal(t, "b1", string(state[0].Value.Bytes))
+	assert.Equal(t, "/a/b/c", state[1].Path)
+	assert.Equal(t, "c1", string(state[1].Value.Bytes))
+	assert.Equal(t, "/x/a/b", state[2].Path)
+}
+
+// TestDeviceStateStoreDelete tests that the state of a device is dropped once its changes and snapshot are deleted
+func TestDeviceStateStoreDelete(t *testing.T) {
+	changeStore, err := networkchangestore.NewLocalStore()
+	assert.NoError(t, err)
+	snapshotStore, err := devicesnapstore.NewLocalStore()
+	assert.NoError(t, err)
+
+	store, err := NewStore(changeStore, snapshotStore)
+	assert.NoError(t, err)
+	deviceID := device.NewVersionedID("test", "1.0.0")
+
+	err = snapshotStore.Store(&devicesnapshot.Snapshot{
+		DeviceID:      "test",
+		DeviceVersion: "1.0.0",
+		DeviceType:    "Stratum",
+		Values: []*devicechange.PathValue{
+			{Path: "/a/b", Value: devicechange.NewTypedValueString("b0")},
+		},
+	})
+	assert.NoError(t, err)
+
+	change := newTestChange(
+		&devicechange.ChangeValue{Path: "/a/c", Value: devicechange.NewTypedValueString("c1")})
+	assert.NoError(t, changeStore.Create(change))
+	state, err := store.Get(deviceID, change.Revision)
+	assert.NoError(t, err)
+	assert.Len(t, state, 2)
+
+	// The snapshot still holds the state of the device
+	assert.NoError(t, changeStore.Delete(change))
+	assert.Never(t, func() bool {
+		state, err := store.Get(deviceID, 0)
+		return err != nil || len(state) != 2
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	assert.NoError(t, snapshotStore.Purge(deviceID))
+	assert.Eventually(t, func() bool {
+		state, err := store.Get(deviceID, 0)
+		return err == nil && len(state) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Without a snapshot the state is dropped with the last change
+	change = newTestChange(
+		&devicechange.ChangeValue{Path: "/a/d", Value: devicechange.NewTypedValueString("d2")})
+	assert.NoError(t, changeStore.Create(change))
+	state, err = store.Get(deviceID, change.Revision)
+	assert.NoError(t, err)
+	assert.Len(t, state, 1)
+
+	assert.NoError(t, changeStore.Delete(change))
+	assert.Eventually(t, func() bool {
+		state, err := store.Get(deviceID, 0)
+		return err == nil && len(state) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,188 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"sort"
+	"strings"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+)
+
+func newPathTree() *pathTree {
+	return &pathTree{
+		root: newPathNode(),
+	}
+}
+
+// pathTree is a tree of the values of a device keyed by the elements of their paths, so that
+// a path and its descendants are found without visiting the other paths of the device
+type pathTree struct {
+	root *pathNode
+}
+
+func newPathNode() *pathNode {
+	return &pathNode{
+		children: make(map[string]*pathNode),
+	}
+}
+
+// pathNode is a path element of the tree. The path and the value are set when the path has a value
+type pathNode struct {
+	children map[string]*pathNode
+	path     string
+	value    *devicechange.TypedValue
+}
+
+// get returns the value of a path, or nil if the path has no value
+func (t *pathTree) get(path string) *devicechange.TypedValue {
+	node := t.root
+	for _, elem := range splitPath(path) {
+		child, ok := node.children[elem]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	return node.value
+}
+
+// set sets the value of a path, and returns its previous value or nil
+func (t *pathTree) set(path string, value *devicechange.TypedValue) *devicechange.TypedValue {
+	node := t.root
+	for _, elem := range splitPath(path) {
+		child, ok := node.children[elem]
+		if !ok {
+			child = newPathNode()
+			node.children[elem] = child
+		}
+		node = child
+	}
+	prev := node.value
+	node.path = path
+	node.value = value
+	return prev
+}
+
+// clear removes the value of a path, but not the values of its descendants
+func (t *pathTree) clear(path string) {
+	t.root.clear(splitPath(path))
+}
+
+func (n *pathNode) clear(elems []string) {
+	if len(elems) == 0 {
+		n.path = ""
+		n.value = nil
+		return
+	}
+	child, ok := n.children[elems[0]]
+	if !ok {
+		return
+	}
+	child.clear(elems[1:])
+	if child.isEmpty() {
+		delete(n.children, elems[0])
+	}
+}
+
+// remove removes the values of a path and of its descendants, and returns the removed values.
+// An element without keys also matches the entries of the list it names, so that removing
+// /a/b removes /a/b[name=x]/c, but not /x/a/b nor /a/bc
+func (t *pathTree) remove(path string) []*devicechange.PathValue {
+	var removed []*devicechange.PathValue
+	t.root.remove(splitPath(path), &removed)
+	return removed
+}
+
+func (n *pathNode) remove(elems []string, removed *[]*devicechange.PathValue) {
+	if len(elems) == 0 {
+		n.collect(removed)
+		n.children = make(map[string]*pathNode)
+		n.path = ""
+		n.value = nil
+		return
+	}
+	for name, child := range n.children {
+		if matchesElem(elems[0], name) {
+			child.remove(elems[1:], removed)
+			if child.isEmpty() {
+				delete(n.children, name)
+			}
+		}
+	}
+}
+
+// values returns the values of the tree sorted by path
+func (t *pathTree) values() []*devicechange.PathValue {
+	values := make([]*devicechange.PathValue, 0)
+	t.root.collect(&values)
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Path < values[j].Path
+	})
+	return values
+}
+
+func (n *pathNode) collect(values *[]*devicechange.PathValue) {
+	if n.value != nil {
+		*values = append(*values, &devicechange.PathValue{
+			Path:  n.path,
+			Value: n.value,
+		})
+	}
+	for _, child := range n.children {
+		child.collect(values)
+	}
+}
+
+func (n *pathNode) isEmpty() bool {
+	return n.value == nil && len(n.children) == 0
+}
+
+// matchesElem returns whether a path element matches an element of the tree. An element
+// without keys matches the element of the same name with any keys
+func matchesElem(elem string, name string) bool {
+	if elem == name {
+		return true
+	}
+	return !strings.Contains(elem, "[") && strings.HasPrefix(name, elem+"[")
+}
+
+// splitPath splits a path into its elements, ignoring the slashes within the keys of the elements
+func splitPath(path string) []string {
+	elems := make([]string, 0)
+	var inBrackets, escape bool
+	start := 0
+	for i, c := range path {
+		switch {
+		case escape:
+			escape = false
+		case c == '\\':
+			escape = true
+		case c == '[':
+			inBrackets = true
+		case c == ']':
+			inBrackets = false
+		case c == '/' && !inBrackets:
+			if i > start {
+				elems = append(elems, path[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if len(path) > start {
+		elems = append(elems, path[start:])
+	}
+	return elems
+}
@@ -0,0 +1,61 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"testing"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func treePaths(tree *pathTree) []string {
+	paths := make([]string, 0)
+	for _, value := range tree.values() {
+		paths = append(paths, value.Path)
+	}
+	return paths
+}
+
+func TestSplitPath(t *testing.T) {
+	assert.Equal(t, []string{"a", "b[name=x/y]", "c"}, splitPath("/a/b[name=x/y]/c"))
+	assert.Equal(t, []string{"a", `b[name=x\]/y]`, "c"}, splitPath(`/a/b[name=x\]/y]/c`))
+	assert.Equal(t, []string{"foo"}, splitPath("foo"))
+	assert.Empty(t, splitPath("/"))
+}
+
+func TestPathTree(t *testing.T) {
+	tree := newPathTree()
+	value := devicechange.NewTypedValueString("value")
+	for _, path := range []string{"/a/b", "/a/b/c", "/a/bc", "/x/a/b", "/a/b[name=x/y]/c", "/a/d"} {
+		assert.Nil(t, tree.set(path, value))
+	}
+	assert.Equal(t, value, tree.set("/a/d", devicechange.NewTypedValueString("other")))
+	assert.Equal(t, "other", string(tree.get("/a/d").Bytes))
+	assert.Nil(t, tree.get("/a"))
+
+	removed := tree.remove("/a/b")
+	assert.Len(t, removed, 3)
+	assert.Equal(t, []string{"/a/bc", "/a/d", "/x/a/b"}, treePaths(tree))
+	assert.Empty(t, tree.remove("/a/b"))
+
+	// Clearing a path keeps the values of its descendants
+	tree.set("/x/a", value)
+	tree.clear("/x/a")
+	assert.Equal(t, []string{"/a/bc", "/a/d", "/x/a/b"}, treePaths(tree))
+	tree.clear("/x/a/b")
+	assert.Equal(t, []string{"/a/bc", "/a/d"}, treePaths(tree))
+	assert.NotContains(t, tree.root.children, "x")
+}
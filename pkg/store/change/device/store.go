@@ -29,6 +29,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
 	"github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
 	"github.com/onosproject/onos-config/pkg/store/stream"
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
@@ -112,6 +113,7 @@ type Store interface {
 // WatchOption is a configuration option for Watch calls
 type WatchOption interface {
 	apply([]indexedmap.WatchOption) []indexedmap.WatchOption
+	applyBolt([]boltdb.WatchOption) []boltdb.WatchOption
 }
 
 // watchReplyOption is an option to replay events on watch
@@ -122,6 +124,10 @@ func (o watchReplayOption) apply(opts []indexedmap.WatchOption) []indexedmap.Wat
 	return append(opts, indexedmap.WithReplay())
 }
 
+func (o watchReplayOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithReplay())
+}
+
 // WithReplay returns a WatchOption that replays past changes
 func WithReplay() WatchOption {
 	return watchReplayOption{}
@@ -137,11 +143,87 @@ func (o watchIDOption) apply(opts []indexedmap.WatchOption) []indexedmap.WatchOp
 	}))
 }
 
+func (o watchIDOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithFilter(string(o.id)))
+}
+
 // WithChangeID returns a Watch option that watches for changes to the given change ID
 func WithChangeID(id devicechange.ID) WatchOption {
 	return watchIDOption{id: id}
 }
 
+// watchFilterOption is a WatchOption that filters the replayed changes
+type watchFilterOption interface {
+	applyFilter(*watchFilter)
+}
+
+// watchFilter filters the changes replayed to a watch
+type watchFilter struct {
+	index    devicechange.Index
+	revision devicechange.Revision
+}
+
+func newWatchFilter(opts []WatchOption) watchFilter {
+	filter := watchFilter{}
+	for _, opt := range opts {
+		if filterOpt, ok := opt.(watchFilterOption); ok {
+			filterOpt.applyFilter(&filter)
+		}
+	}
+	return filter
+}
+
+// replays returns whether a change is replayed to the watch
+func (f watchFilter) replays(change *devicechange.DeviceChange) bool {
+	return change.Index >= f.index && change.Revision > f.revision
+}
+
+type watchIndexOption struct {
+	index devicechange.Index
+}
+
+func (o watchIndexOption) apply(opts []indexedmap.WatchOption) []indexedmap.WatchOption {
+	return append(opts, indexedmap.WithReplay())
+}
+
+func (o watchIndexOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithReplay())
+}
+
+func (o watchIndexOption) applyFilter(filter *watchFilter) {
+	filter.index = o.index
+}
+
+// WithIndex returns a WatchOption that replays the changes from the given index on, e.g. to
+// resume a watch from the index after the last change received
+func WithIndex(index devicechange.Index) WatchOption {
+	return watchIndexOption{index: index}
+}
+
+type watchRevisionOption struct {
+	revision devicechange.Revision
+}
+
+func (o watchRevisionOption) apply(opts []indexedmap.WatchOption) []indexedmap.WatchOption {
+	return append(opts, indexedmap.WithReplay())
+}
+
+func (o watchRevisionOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithReplay())
+}
+
+func (o watchRevisionOption) applyFilter(filter *watchFilter) {
+	filter.revision = o.revision
+}
+
+// WithRevision returns a WatchOption that replays the changes created or updated after the
+// given revision, e.g. to resume a watch from the highest revision received. The revisions of
+// the changes increase with each update of the store, and changes deleted since the revision
+// are not replayed
+func WithRevision(revision devicechange.Revision) WatchOption {
+	return watchRevisionOption{revision: revision}
+}
+
 // atomixStore is the default implementation of the NetworkConfig store
 type atomixStore struct {
 	changesFactory func(device.VersionedID) (indexedmap.IndexedMap, error)
@@ -349,6 +431,7 @@ func (s *atomixStore) Watch(deviceID device.VersionedID, ch chan<- stream.Event,
 	for _, opt := range opts {
 		watchOpts = opt.apply(watchOpts)
 	}
+	filter := newWatchFilter(opts)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	mapCh := make(chan *indexedmap.Event)
@@ -361,6 +444,9 @@ func (s *atomixStore) Watch(deviceID device.VersionedID, ch chan<- stream.Event,
 		defer close(ch)
 		for event := range mapCh {
 			if change, err := decodeChange(event.Entry); err == nil {
+				if event.Type == indexedmap.EventNone && !filter.replays(change) {
+					continue
+				}
 				switch event.Type {
 				case indexedmap.EventNone:
 					ch <- stream.Event{
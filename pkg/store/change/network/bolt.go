@@ -0,0 +1,237 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/proto"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// NewBoltStore returns a network change store kept in an embedded database file
+func NewBoltStore(db *boltdb.DB) (Store, error) {
+	changes, err := db.GetIndexedMap(changesName)
+	if err != nil {
+		return nil, err
+	}
+	store := &boltStore{
+		changes: changes,
+	}
+	store.index = newChangeIndex(store)
+	return store, nil
+}
+
+// boltStore is a network change store kept in an embedded database file
+type boltStore struct {
+	changes *boltdb.IndexedMap
+	index   *changeIndex
+}
+
+func (s *boltStore) Get(id networkchange.ID) (*networkchange.NetworkChange, error) {
+	entry, err := s.changes.Get(string(id))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, nil
+	}
+	return decodeBoltChange(entry)
+}
+
+func (s *boltStore) GetByIndex(index networkchange.Index) (*networkchange.NetworkChange, error) {
+	entry, err := s.changes.GetIndex(boltdb.Index(index))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, nil
+	}
+	return decodeBoltChange(entry)
+}
+
+func (s *boltStore) GetPrev(index networkchange.Index) (*networkchange.NetworkChange, error) {
+	entry, err := s.changes.PrevEntry(boltdb.Index(index))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, nil
+	}
+	return decodeBoltChange(entry)
+}
+
+func (s *boltStore) GetNext(index networkchange.Index) (*networkchange.NetworkChange, error) {
+	entry, err := s.changes.NextEntry(boltdb.Index(index))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, nil
+	}
+	return decodeBoltChange(entry)
+}
+
+func (s *boltStore) Create(change *networkchange.NetworkChange) error {
+	if change.ID == "" {
+		change.ID = newChangeID()
+	}
+	if change.Revision != 0 {
+		return errors.NewInvalid("not a new object")
+	}
+
+	bytes, err := proto.Marshal(change)
+	if err != nil {
+		return errors.NewInvalid("change encoding failed: %v", err)
+	}
+
+	entry, err := s.changes.Append(string(change.ID), bytes)
+	if err != nil {
+		return err
+	}
+
+	change.Index = networkchange.Index(entry.Index)
+	change.Revision = networkchange.Revision(entry.Version)
+	change.Created = entry.Created
+	change.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) Update(change *networkchange.NetworkChange) error {
+	if change.Revision == 0 {
+		return errors.NewInvalid("not a stored object")
+	}
+
+	bytes, err := proto.Marshal(change)
+	if err != nil {
+		return errors.NewInvalid("change encoding failed: %v", err)
+	}
+
+	entry, err := s.changes.Set(boltdb.Index(change.Index), string(change.ID), bytes, boltdb.IfVersion(boltdb.Version(change.Revision)))
+	if err != nil {
+		return err
+	}
+
+	change.Revision = networkchange.Revision(entry.Version)
+	change.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) Delete(change *networkchange.NetworkChange) error {
+	if change.Revision == 0 {
+		return errors.NewInvalid("not a stored object")
+	}
+
+	entry, err := s.changes.RemoveIndex(boltdb.Index(change.Index), boltdb.IfVersion(boltdb.Version(change.Revision)))
+	if err != nil {
+		return err
+	}
+
+	change.Revision = 0
+	change.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) List(ch chan<- *networkchange.NetworkChange) (stream.Context, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Entry)
+	if err := s.changes.Entries(ctx, mapCh); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for entry := range mapCh {
+			if change, err := decodeBoltChange(entry); err == nil {
+				ch <- change
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+func (s *boltStore) Query(query Query) (*QueryResult, error) {
+	return s.index.query(query)
+}
+
+func (s *boltStore) Watch(ch chan<- stream.Event, opts ...WatchOption) (stream.Context, error) {
+	watchOpts := make([]boltdb.WatchOption, 0)
+	for _, opt := range opts {
+		watchOpts = opt.applyBolt(watchOpts)
+	}
+	filter := newWatchFilter(opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Event)
+	if err := s.changes.Watch(ctx, mapCh, watchOpts...); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if change, err := decodeBoltChange(event.Entry); err == nil {
+				if event.Type == boltdb.EventNone && !filter.replays(change) {
+					continue
+				}
+				switch event.Type {
+				case boltdb.EventNone:
+					ch <- stream.Event{
+						Type:   stream.None,
+						Object: change,
+					}
+				case boltdb.EventInserted:
+					ch <- stream.Event{
+						Type:   stream.Created,
+						Object: change,
+					}
+				case boltdb.EventUpdated:
+					ch <- stream.Event{
+						Type:   stream.Updated,
+						Object: change,
+					}
+				case boltdb.EventRemoved:
+					ch <- stream.Event{
+						Type:   stream.Deleted,
+						Object: change,
+					}
+				}
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+// Close does not close the database, which is shared with the other stores
+func (s *boltStore) Close() error {
+	s.index.close()
+	return nil
+}
+
+func decodeBoltChange(entry *boltdb.Entry) (*networkchange.NetworkChange, error) {
+	change := &networkchange.NetworkChange{}
+	if err := proto.Unmarshal(entry.Value, change); err != nil {
+		return nil, errors.NewInvalid("change decoding failed: %v", err)
+	}
+	change.ID = networkchange.ID(entry.Key)
+	change.Index = networkchange.Index(entry.Index)
+	change.Revision = networkchange.Revision(entry.Version)
+	change.Created = entry.Created
+	change.Updated = entry.Updated
+	return change, nil
+}
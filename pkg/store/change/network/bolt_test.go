@@ -0,0 +1,99 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network-changes")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "onos-config.db")
+
+	db, err := boltdb.Open(path)
+	assert.NoError(t, err)
+	store, err := NewBoltStore(db)
+	assert.NoError(t, err)
+
+	for _, id := range []networkchange.ID{"change-1", "change-2", "change-3"} {
+		change := &networkchange.NetworkChange{
+			ID:      id,
+			Changes: []*devicechange.Change{{DeviceID: "device-1", DeviceVersion: "1.0.0"}},
+		}
+		assert.NoError(t, store.Create(change))
+	}
+	change2, err := store.Get("change-2")
+	assert.NoError(t, err)
+	assert.Equal(t, networkchange.Index(2), change2.Index)
+	change2.Status.State = changetypes.State_COMPLETE
+	assert.NoError(t, store.Update(change2))
+
+	change1, err := store.Get("change-1")
+	assert.NoError(t, err)
+	change1.Revision++
+	assert.True(t, errors.IsConflict(store.Update(change1)))
+	change1.Revision--
+	assert.NoError(t, store.Delete(change1))
+
+	// The changes are kept when the database is reopened
+	assert.NoError(t, db.Close())
+	db, err = boltdb.Open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	store, err = NewBoltStore(db)
+	assert.NoError(t, err)
+
+	change, err := store.Get("change-2")
+	assert.NoError(t, err)
+	assert.Equal(t, change2.Revision, change.Revision)
+	assert.Equal(t, change2.Status.State, change.Status.State)
+	change, err = store.GetPrev(2)
+	assert.NoError(t, err)
+	assert.Nil(t, change)
+	change, err = store.GetNext(2)
+	assert.NoError(t, err)
+	assert.Equal(t, networkchange.ID("change-3"), change.ID)
+
+	ch := make(chan stream.Event)
+	ctx, err := store.Watch(ch, WithReplay())
+	assert.NoError(t, err)
+	defer ctx.Close()
+	assert.Equal(t, networkchange.ID("change-2"), nextEvent(t, ch).ID)
+	assert.Equal(t, networkchange.ID("change-3"), nextEvent(t, ch).ID)
+
+	change4 := &networkchange.NetworkChange{}
+	assert.NoError(t, store.Create(change4))
+	assert.Equal(t, networkchange.Index(4), change4.Index)
+	assert.Equal(t, change4.ID, nextEvent(t, ch).ID)
+
+	idCh := make(chan stream.Event)
+	idCtx, err := store.Watch(idCh, WithReplay(), WithChangeID("change-3"))
+	assert.NoError(t, err)
+	defer idCtx.Close()
+	assert.Equal(t, networkchange.ID("change-3"), nextEvent(t, idCh).ID)
+}
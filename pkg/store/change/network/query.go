@@ -0,0 +1,336 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	"github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+)
+
+// Order is the order of the network changes of a query
+type Order int
+
+const (
+	// Ascending lists the network changes from the oldest to the newest index
+	Ascending Order = iota
+	// Descending lists the network changes from the newest to the oldest index
+	Descending
+)
+
+// Query is a query of network changes. The empty fields of the query match any change
+type Query struct {
+	// DeviceID matches the changes to the device
+	DeviceID device.ID
+	// Phases match the changes in any of the phases
+	Phases []changetypes.Phase
+	// States match the changes in any of the states
+	States []changetypes.State
+	// CreatedAfter matches the changes created at or after the time
+	CreatedAfter time.Time
+	// CreatedBefore matches the changes created before the time
+	CreatedBefore time.Time
+	// Order is the order of the changes by index
+	Order Order
+	// Cursor is the index after which changes are listed in the order of the query, from the
+	// Next index of the previous page
+	Cursor networkchange.Index
+	// Limit is the maximum number of changes of a page; 0 lists all the changes
+	Limit int
+}
+
+// QueryResult is a page of the network changes of a query
+type QueryResult struct {
+	// Changes are the network changes of the page
+	Changes []*networkchange.NetworkChange
+	// Next is the cursor of the next page, or 0 if this is the last page
+	Next networkchange.Index
+}
+
+// changeSummary holds the fields of a network change that are queried
+type changeSummary struct {
+	revision networkchange.Revision
+	devices  []device.ID
+	phase    changetypes.Phase
+	state    changetypes.State
+	created  time.Time
+}
+
+func newChangeSummary(change *networkchange.NetworkChange) *changeSummary {
+	summary := &changeSummary{
+		revision: change.Revision,
+		devices:  make([]device.ID, 0, len(change.Changes)),
+		phase:    change.Status.Phase,
+		state:    change.Status.State,
+		created:  change.Created,
+	}
+	for _, deviceChange := range change.Changes {
+		summary.devices = append(summary.devices, deviceChange.DeviceID)
+	}
+	return summary
+}
+
+// matches returns whether the status and the creation time of a change match the query. The
+// device is matched by the index of the devices
+func (q Query) matches(summary *changeSummary) bool {
+	if len(q.Phases) > 0 && !containsPhase(q.Phases, summary.phase) {
+		return false
+	}
+	if len(q.States) > 0 && !containsState(q.States, summary.state) {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && summary.created.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !summary.created.Before(q.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func containsPhase(phases []changetypes.Phase, phase changetypes.Phase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+func containsState(states []changetypes.State, state changetypes.State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func newChangeIndex(store Store) *changeIndex {
+	return &changeIndex{
+		store:   store,
+		changes: make(map[networkchange.Index]*changeSummary),
+		devices: make(map[device.ID][]networkchange.Index),
+	}
+}
+
+// changeIndex is an in-memory secondary index of the network changes, so that queries do not
+// read every change of the store. It is loaded on the first query, and then kept up to date
+// by watching the store, which also keeps it up to date with the changes of the other nodes
+type changeIndex struct {
+	store   Store
+	changes map[networkchange.Index]*changeSummary
+	indexes []networkchange.Index
+	devices map[device.ID][]networkchange.Index
+	// removed are the changes removed while the index is loaded, so that they are not added
+	// back by the list of the changes
+	removed map[networkchange.Index]bool
+	watch   stream.Context
+	loaded  bool
+	// loadMu serializes the loads. A load that fails is retried by the next query
+	loadMu sync.Mutex
+	mu     sync.RWMutex
+}
+
+// load lists the changes of the store into the index, and watches the store for their updates.
+// If listing or watching the changes fails, the index is loaded again on the next call
+func (i *changeIndex) load() error {
+	i.loadMu.Lock()
+	defer i.loadMu.Unlock()
+	if i.loaded {
+		return nil
+	}
+
+	// A failed load may have left some changes in the index, which are listed again
+	i.mu.Lock()
+	i.changes = make(map[networkchange.Index]*changeSummary)
+	i.indexes = nil
+	i.devices = make(map[device.ID][]networkchange.Index)
+	i.removed = make(map[networkchange.Index]bool)
+	i.mu.Unlock()
+
+	// The store is watched before the changes are listed so that no update is missed
+	eventCh := make(chan stream.Event)
+	watch, err := i.store.Watch(eventCh)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for event := range eventCh {
+			change := event.Object.(*networkchange.NetworkChange)
+			if event.Type == stream.Deleted {
+				i.remove(change)
+			} else {
+				i.update(change)
+			}
+		}
+	}()
+
+	changeCh := make(chan *networkchange.NetworkChange)
+	ctx, err := i.store.List(changeCh)
+	if err != nil {
+		watch.Close()
+		return err
+	}
+	defer ctx.Close()
+	for change := range changeCh {
+		i.update(change)
+	}
+
+	i.mu.Lock()
+	i.removed = nil
+	i.watch = watch
+	i.mu.Unlock()
+	i.loaded = true
+	return nil
+}
+
+// update adds a change to the index or updates it, unless the index has a newer revision
+func (i *changeIndex) update(change *networkchange.NetworkChange) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.removed[change.Index] {
+		return
+	}
+	summary, ok := i.changes[change.Index]
+	if ok && summary.revision >= change.Revision {
+		return
+	}
+	summary = newChangeSummary(change)
+	i.changes[change.Index] = summary
+	if !ok {
+		i.indexes = insertIndex(i.indexes, change.Index)
+		for _, deviceID := range summary.devices {
+			i.devices[deviceID] = insertIndex(i.devices[deviceID], change.Index)
+		}
+	}
+}
+
+func (i *changeIndex) remove(change *networkchange.NetworkChange) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.removed != nil {
+		i.removed[change.Index] = true
+	}
+	summary, ok := i.changes[change.Index]
+	if !ok {
+		return
+	}
+	delete(i.changes, change.Index)
+	i.indexes = removeIndex(i.indexes, change.Index)
+	for _, deviceID := range summary.devices {
+		i.devices[deviceID] = removeIndex(i.devices[deviceID], change.Index)
+		if len(i.devices[deviceID]) == 0 {
+			delete(i.devices, deviceID)
+		}
+	}
+}
+
+// query returns a page of the changes of a query
+func (i *changeIndex) query(query Query) (*QueryResult, error) {
+	if err := i.load(); err != nil {
+		return nil, err
+	}
+
+	i.mu.RLock()
+	indexes := i.indexes
+	if query.DeviceID != "" {
+		indexes = i.devices[query.DeviceID]
+	}
+	var matches []networkchange.Index
+	more := false
+	visit := func(index networkchange.Index) bool {
+		if !query.matches(i.changes[index]) {
+			return true
+		}
+		if query.Limit > 0 && len(matches) == query.Limit {
+			more = true
+			return false
+		}
+		matches = append(matches, index)
+		return true
+	}
+	if query.Order == Descending {
+		start := len(indexes)
+		if query.Cursor != 0 {
+			start = sort.Search(len(indexes), func(n int) bool { return indexes[n] >= query.Cursor })
+		}
+		for n := start - 1; n >= 0; n-- {
+			if !visit(indexes[n]) {
+				break
+			}
+		}
+	} else {
+		start := sort.Search(len(indexes), func(n int) bool { return indexes[n] > query.Cursor })
+		for n := start; n < len(indexes); n++ {
+			if !visit(indexes[n]) {
+				break
+			}
+		}
+	}
+	i.mu.RUnlock()
+
+	// The changes may have been updated since they were indexed, so they are matched again
+	result := &QueryResult{
+		Changes: make([]*networkchange.NetworkChange, 0, len(matches)),
+	}
+	for _, index := range matches {
+		change, err := i.store.GetByIndex(index)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil && query.matches(newChangeSummary(change)) {
+			result.Changes = append(result.Changes, change)
+		}
+	}
+	if more {
+		result.Next = matches[len(matches)-1]
+	}
+	return result, nil
+}
+
+func (i *changeIndex) close() {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.watch != nil {
+		i.watch.Close()
+	}
+}
+
+// insertIndex inserts an index into a sorted list of indexes
+func insertIndex(indexes []networkchange.Index, index networkchange.Index) []networkchange.Index {
+	n := sort.Search(len(indexes), func(n int) bool { return indexes[n] >= index })
+	if n < len(indexes) && indexes[n] == index {
+		return indexes
+	}
+	indexes = append(indexes, 0)
+	copy(indexes[n+1:], indexes[n:])
+	indexes[n] = index
+	return indexes
+}
+
+// removeIndex removes an index from a sorted list of indexes
+func removeIndex(indexes []networkchange.Index, index networkchange.Index) []networkchange.Index {
+	n := sort.Search(len(indexes), func(n int) bool { return indexes[n] >= index })
+	if n == len(indexes) || indexes[n] != index {
+		return indexes
+	}
+	return append(indexes[:n], indexes[n+1:]...)
+}
@@ -0,0 +1,146 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	changetypes "github.com/onosproject/onos-api/go/onos/config/change"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
+	"github.com/onosproject/onos-api/go/onos/config/device"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func changeIndexes(result *QueryResult) []networkchange.Index {
+	indexes := make([]networkchange.Index, 0, len(result.Changes))
+	for _, change := range result.Changes {
+		indexes = append(indexes, change.Index)
+	}
+	return indexes
+}
+
+func TestQuery(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	// Changes 1 to 6 alternate between device-1 and device-2, and the even ones fail
+	createChange := func(deviceID device.ID) *networkchange.NetworkChange {
+		change := &networkchange.NetworkChange{
+			Changes: []*devicechange.Change{{DeviceID: deviceID, DeviceVersion: "1.0.0"}},
+		}
+		assert.NoError(t, store.Create(change))
+		return change
+	}
+	for i := 1; i <= 6; i++ {
+		deviceID := device.ID("device-1")
+		if i%2 == 0 {
+			deviceID = "device-2"
+		}
+		change := createChange(deviceID)
+		if i%2 == 0 {
+			change.Status.State = changetypes.State_FAILED
+			assert.NoError(t, store.Update(change))
+		}
+	}
+
+	result, err := store.Query(Query{DeviceID: "device-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{1, 3, 5}, changeIndexes(result))
+	assert.Equal(t, networkchange.Index(0), result.Next)
+
+	result, err = store.Query(Query{States: []changetypes.State{changetypes.State_FAILED}, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{2, 4}, changeIndexes(result))
+	assert.Equal(t, networkchange.Index(4), result.Next)
+	result, err = store.Query(Query{States: []changetypes.State{changetypes.State_FAILED}, Limit: 2, Cursor: result.Next})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{6}, changeIndexes(result))
+	assert.Equal(t, networkchange.Index(0), result.Next)
+
+	result, err = store.Query(Query{Order: Descending, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{6, 5}, changeIndexes(result))
+	result, err = store.Query(Query{Order: Descending, Limit: 2, Cursor: result.Next})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{4, 3}, changeIndexes(result))
+
+	// The index follows the changes made after it is loaded
+	createChange("device-2")
+	change1, err := store.GetByIndex(1)
+	assert.NoError(t, err)
+	change1.Status.State = changetypes.State_FAILED
+	assert.NoError(t, store.Update(change1))
+	change2, err := store.GetByIndex(2)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Delete(change2))
+
+	assert.Eventually(t, func() bool {
+		result, err = store.Query(Query{DeviceID: "device-2"})
+		return err == nil && assert.ObjectsAreEqual([]networkchange.Index{4, 6, 7}, changeIndexes(result))
+	}, 5*time.Second, 10*time.Millisecond)
+	result, err = store.Query(Query{DeviceID: "device-1", States: []changetypes.State{changetypes.State_FAILED}})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{1}, changeIndexes(result))
+
+	change7, err := store.GetByIndex(7)
+	assert.NoError(t, err)
+	result, err = store.Query(Query{CreatedAfter: change7.Created})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{7}, changeIndexes(result))
+	result, err = store.Query(Query{CreatedBefore: change7.Created, Phases: []changetypes.Phase{changetypes.Phase_ROLLBACK}})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Changes)
+}
+
+// failingWatchStore is a store whose first watches fail
+type failingWatchStore struct {
+	Store
+	failures int
+}
+
+func (s *failingWatchStore) Watch(ch chan<- stream.Event, opts ...WatchOption) (stream.Context, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.NewUnavailable("watch failed")
+	}
+	return s.Store.Watch(ch, opts...)
+}
+
+func TestQueryRetriesLoad(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.NoError(t, store.Create(&networkchange.NetworkChange{
+		Changes: []*devicechange.Change{{DeviceID: "device-1", DeviceVersion: "1.0.0"}},
+	}))
+
+	index := newChangeIndex(&failingWatchStore{Store: store, failures: 1})
+	defer index.close()
+	_, err = index.query(Query{})
+	assert.True(t, errors.IsUnavailable(err))
+
+	// The index is loaded again once the store is available
+	result, err := index.query(Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, []networkchange.Index{1}, changeIndexes(result))
+}
@@ -28,6 +28,7 @@ import (
 	types "github.com/onosproject/onos-api/go/onos/config"
 	networkchange "github.com/onosproject/onos-api/go/onos/config/change/network"
 	"github.com/onosproject/onos-config/pkg/config"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
 	"github.com/onosproject/onos-config/pkg/store/stream"
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 	"github.com/onosproject/onos-lib-go/pkg/cluster"
@@ -48,9 +49,11 @@ func NewAtomixStore(cluster cluster.Cluster, config config.Config) (Store, error
 		return nil, errors.FromAtomix(err)
 	}
 
-	return &atomixStore{
+	store := &atomixStore{
 		changes: changes,
-	}, nil
+	}
+	store.index = newChangeIndex(store)
+	return store, nil
 }
 
 // NewLocalStore returns a new local network change store
@@ -76,9 +79,11 @@ func newLocalStore(address net.Address) (Store, error) {
 		return nil, errors.FromAtomix(err)
 	}
 
-	return &atomixStore{
+	store := &atomixStore{
 		changes: changes,
-	}, nil
+	}
+	store.index = newChangeIndex(store)
+	return store, nil
 }
 
 // Store stores NetworkConfig changes
@@ -109,6 +114,9 @@ type Store interface {
 	// List lists network configurations
 	List(chan<- *networkchange.NetworkChange) (stream.Context, error)
 
+	// Query lists a page of the network changes matching a query
+	Query(query Query) (*QueryResult, error)
+
 	// Watch watches the network configuration store for changes
 	Watch(chan<- stream.Event, ...WatchOption) (stream.Context, error)
 }
@@ -116,6 +124,7 @@ type Store interface {
 // WatchOption is a configuration option for Watch calls
 type WatchOption interface {
 	apply([]indexedmap.WatchOption) []indexedmap.WatchOption
+	applyBolt([]boltdb.WatchOption) []boltdb.WatchOption
 }
 
 // watchReplyOption is an option to replay events on watch
@@ -126,6 +135,10 @@ func (o watchReplayOption) apply(opts []indexedmap.WatchOption) []indexedmap.Wat
 	return append(opts, indexedmap.WithReplay())
 }
 
+func (o watchReplayOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithReplay())
+}
+
 // WithReplay returns a WatchOption that replays past changes
 func WithReplay() WatchOption {
 	return watchReplayOption{}
@@ -141,11 +154,87 @@ func (o watchIDOption) apply(opts []indexedmap.WatchOption) []indexedmap.WatchOp
 	}))
 }
 
+func (o watchIDOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithFilter(string(o.id)))
+}
+
 // WithChangeID returns a Watch option that watches for changes to the given change ID
 func WithChangeID(id networkchange.ID) WatchOption {
 	return watchIDOption{id: id}
 }
 
+// watchFilterOption is a WatchOption that filters the replayed changes
+type watchFilterOption interface {
+	applyFilter(*watchFilter)
+}
+
+// watchFilter filters the changes replayed to a watch
+type watchFilter struct {
+	index    networkchange.Index
+	revision networkchange.Revision
+}
+
+func newWatchFilter(opts []WatchOption) watchFilter {
+	filter := watchFilter{}
+	for _, opt := range opts {
+		if filterOpt, ok := opt.(watchFilterOption); ok {
+			filterOpt.applyFilter(&filter)
+		}
+	}
+	return filter
+}
+
+// replays returns whether a change is replayed to the watch
+func (f watchFilter) replays(change *networkchange.NetworkChange) bool {
+	return change.Index >= f.index && change.Revision > f.revision
+}
+
+type watchIndexOption struct {
+	index networkchange.Index
+}
+
+func (o watchIndexOption) apply(opts []indexedmap.WatchOption) []indexedmap.WatchOption {
+	return append(opts, indexedmap.WithReplay())
+}
+
+func (o watchIndexOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithReplay())
+}
+
+func (o watchIndexOption) applyFilter(filter *watchFilter) {
+	filter.index = o.index
+}
+
+// WithIndex returns a WatchOption that replays the changes from the given index on, e.g. to
+// resume a watch from the index after the last change received
+func WithIndex(index networkchange.Index) WatchOption {
+	return watchIndexOption{index: index}
+}
+
+type watchRevisionOption struct {
+	revision networkchange.Revision
+}
+
+func (o watchRevisionOption) apply(opts []indexedmap.WatchOption) []indexedmap.WatchOption {
+	return append(opts, indexedmap.WithReplay())
+}
+
+func (o watchRevisionOption) applyBolt(opts []boltdb.WatchOption) []boltdb.WatchOption {
+	return append(opts, boltdb.WithReplay())
+}
+
+func (o watchRevisionOption) applyFilter(filter *watchFilter) {
+	filter.revision = o.revision
+}
+
+// WithRevision returns a WatchOption that replays the changes created or updated after the
+// given revision, e.g. to resume a watch from the highest revision received. The revisions of
+// the changes increase with each update of the store, and changes deleted since the revision
+// are not replayed
+func WithRevision(revision networkchange.Revision) WatchOption {
+	return watchRevisionOption{revision: revision}
+}
+
 // newChangeID creates a new network change ID
 func newChangeID() networkchange.ID {
 	newUUID := types.NewUUID()
@@ -155,6 +244,7 @@ func newChangeID() networkchange.ID {
 // atomixStore is the default implementation of the NetworkConfig store
 type atomixStore struct {
 	changes indexedmap.IndexedMap
+	index   *changeIndex
 }
 
 func (s *atomixStore) Get(id networkchange.ID) (*networkchange.NetworkChange, error) {
@@ -298,11 +388,16 @@ func (s *atomixStore) List(ch chan<- *networkchange.NetworkChange) (stream.Conte
 	return stream.NewCancelContext(cancel), nil
 }
 
+func (s *atomixStore) Query(query Query) (*QueryResult, error) {
+	return s.index.query(query)
+}
+
 func (s *atomixStore) Watch(ch chan<- stream.Event, opts ...WatchOption) (stream.Context, error) {
 	watchOpts := make([]indexedmap.WatchOption, 0)
 	for _, opt := range opts {
 		watchOpts = opt.apply(watchOpts)
 	}
+	filter := newWatchFilter(opts)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -316,6 +411,9 @@ func (s *atomixStore) Watch(ch chan<- stream.Event, opts ...WatchOption) (stream
 		defer close(ch)
 		for event := range mapCh {
 			if change, err := decodeChange(event.Entry); err == nil {
+				if event.Type == indexedmap.EventNone && !filter.replays(change) {
+					continue
+				}
 				switch event.Type {
 				case indexedmap.EventNone:
 					ch <- stream.Event{
@@ -347,6 +445,7 @@ func (s *atomixStore) Watch(ch chan<- stream.Event, opts ...WatchOption) (stream
 }
 
 func (s *atomixStore) Close() error {
+	s.index.close()
 	return s.changes.Close(context.Background())
 }
 
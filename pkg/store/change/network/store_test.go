@@ -280,3 +280,40 @@ func nextEvent(t *testing.T, ch chan stream.Event) *networkchange.NetworkChange
 	}
 	return nil
 }
+
+func TestWatchResume(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	for _, id := range []networkchange.ID{"change-1", "change-2", "change-3"} {
+		assert.NoError(t, store.Create(&networkchange.NetworkChange{ID: id}))
+	}
+	change2, err := store.Get("change-2")
+	assert.NoError(t, err)
+	change1, err := store.Get("change-1")
+	assert.NoError(t, err)
+	change1.Status.State = changetypes.State_COMPLETE
+	assert.NoError(t, store.Update(change1))
+
+	// Resuming from the revision of change-2 replays the changes created or updated since
+	ch := make(chan stream.Event)
+	ctx, err := store.Watch(ch, WithRevision(change2.Revision))
+	assert.NoError(t, err)
+	defer ctx.Close()
+	change := nextEvent(t, ch)
+	assert.Equal(t, networkchange.ID("change-1"), change.ID)
+	assert.Equal(t, change1.Revision, change.Revision)
+	assert.Equal(t, networkchange.ID("change-3"), nextEvent(t, ch).ID)
+
+	indexCh := make(chan stream.Event)
+	indexCtx, err := store.Watch(indexCh, WithIndex(3))
+	assert.NoError(t, err)
+	defer indexCtx.Close()
+	assert.Equal(t, networkchange.ID("change-3"), nextEvent(t, indexCh).ID)
+
+	assert.NoError(t, store.Create(&networkchange.NetworkChange{ID: "change-4"}))
+	assert.Equal(t, networkchange.ID("change-4"), nextEvent(t, ch).ID)
+	assert.Equal(t, networkchange.ID("change-4"), nextEvent(t, indexCh).ID)
+}
@@ -64,6 +64,8 @@ func NewCache(networkChangeStore networkchangestore.Store,
 		networkChangeStore:  networkChangeStore,
 		deviceSnapshotStore: deviceSnapshotStore,
 		devices:             make(map[device.VersionedID]*Info),
+		refs:                make(map[cacheRef][]device.VersionedID),
+		counts:              make(map[device.VersionedID]int),
 		listeners:           make(map[chan<- stream.Event]struct{}),
 	}
 
@@ -78,8 +80,19 @@ type networkChangeStoreCache struct {
 	networkChangeStore  networkchangestore.Store
 	deviceSnapshotStore devicesnapshotstore.Store
 	devices             map[device.VersionedID]*Info
-	mu                  sync.RWMutex
-	listeners           map[chan<- stream.Event]struct{}
+	// refs are the devices referred to by each NetworkChange and DeviceSnapshot, and counts the
+	// number of references to each device, so that a device is removed from the cache on every
+	// replica once no change or snapshot refers to it
+	refs      map[cacheRef][]device.VersionedID
+	counts    map[device.VersionedID]int
+	mu        sync.RWMutex
+	listeners map[chan<- stream.Event]struct{}
+}
+
+// cacheRef is a NetworkChange or a DeviceSnapshot referring to devices of the cache
+type cacheRef struct {
+	change   networkchange.ID
+	snapshot devicesnapshot.ID
 }
 
 func (c *networkChangeStoreCache) getListeners() []chan<- stream.Event {
@@ -106,70 +119,33 @@ func (c *networkChangeStoreCache) listen() error {
 
 	go func() {
 		for event := range ch {
-			if event.Type == stream.Deleted {
-				// TODO handle delete properly - it should check to see if any
-				//  of the devices in the removed/deleted NW change exist in any
-				//  of the remaining NW changes. If they don't then remove them
-				//  from the cache and send an event to listeners to that effect
-				//  This will be needed in the case where a network change is made
-				//  but something in it is invalid and it needs to be removed.
-				//  The related devices should not be present in the cache after
-				//  this check
-				continue
-			}
-			netChange, ok := event.Object.(*networkchange.NetworkChange)
-			if ok {
-				for _, devChange := range netChange.Changes {
-					key := device.NewVersionedID(devChange.DeviceID, devChange.DeviceVersion)
-					c.mu.Lock()
-					if _, ok := c.devices[key]; !ok {
-						info := Info{
-							DeviceID: devChange.DeviceID,
-							Type:     devChange.DeviceType,
-							Version:  devChange.DeviceVersion,
-						}
-						c.devices[key] = &info
-						log.Infof("Updating cache with %v. Size %d Listeners %d", info, len(c.devices), len(c.listeners))
-						listeners := c.getListeners()
-						c.mu.Unlock()
-						for _, l := range listeners {
-							if l != nil {
-								l <- stream.Event{
-									Type:   stream.Created,
-									Object: &info,
-								}
-							}
-						}
-					} else {
-						c.mu.Unlock()
-					}
+			switch object := event.Object.(type) {
+			case *networkchange.NetworkChange:
+				ref := cacheRef{change: object.ID}
+				if event.Type == stream.Deleted {
+					c.refer(ref)
+					continue
 				}
-			}
-			ssChange, ok := event.Object.(*devicesnapshot.DeviceSnapshot)
-			if ok {
-				key := device.NewVersionedID(ssChange.DeviceID, ssChange.DeviceVersion)
-				info := Info{
-					DeviceID: ssChange.DeviceID,
-					Type:     ssChange.DeviceType,
-					Version:  ssChange.DeviceVersion,
+				infos := make([]*Info, 0, len(object.Changes))
+				for _, devChange := range object.Changes {
+					infos = append(infos, &Info{
+						DeviceID: devChange.DeviceID,
+						Type:     devChange.DeviceType,
+						Version:  devChange.DeviceVersion,
+					})
 				}
-				c.mu.Lock()
-				if _, ok := c.devices[key]; !ok {
-					c.devices[key] = &info
-					log.Infof("Updating cache with %v. Size %d Listeners %d", info, len(c.devices), len(c.listeners))
-					listeners := c.getListeners()
-					c.mu.Unlock()
-					for _, l := range listeners {
-						if l != nil {
-							l <- stream.Event{
-								Type:   stream.Created,
-								Object: &info,
-							}
-						}
-					}
-				} else {
-					c.mu.Unlock()
+				c.refer(ref, infos...)
+			case *devicesnapshot.DeviceSnapshot:
+				ref := cacheRef{snapshot: object.ID}
+				if event.Type == stream.Deleted {
+					c.refer(ref)
+					continue
 				}
+				c.refer(ref, &Info{
+					DeviceID: object.DeviceID,
+					Type:     object.DeviceType,
+					Version:  object.DeviceVersion,
+				})
 			}
 		}
 		ctx.Close()
@@ -178,6 +154,57 @@ func (c *networkChangeStoreCache) listen() error {
 	return nil
 }
 
+// refer replaces the devices a change or a snapshot refers to. Devices are added to the cache on
+// their first reference and removed from it when their last reference is released
+func (c *networkChangeStoreCache) refer(ref cacheRef, infos ...*Info) {
+	events := make([]stream.Event, 0)
+	c.mu.Lock()
+	keys := make([]device.VersionedID, 0, len(infos))
+	for _, info := range infos {
+		key := device.NewVersionedID(info.DeviceID, info.Version)
+		keys = append(keys, key)
+		c.counts[key]++
+		if _, ok := c.devices[key]; !ok {
+			c.devices[key] = info
+			log.Infof("Updating cache with %v. Size %d Listeners %d", *info, len(c.devices), len(c.listeners))
+			events = append(events, stream.Event{
+				Type:   stream.Created,
+				Object: info,
+			})
+		}
+	}
+	for _, key := range c.refs[ref] {
+		c.counts[key]--
+		if c.counts[key] > 0 {
+			continue
+		}
+		delete(c.counts, key)
+		if info, ok := c.devices[key]; ok {
+			delete(c.devices, key)
+			log.Infof("Removing %v from cache. Size %d Listeners %d", *info, len(c.devices), len(c.listeners))
+			events = append(events, stream.Event{
+				Type:   stream.Deleted,
+				Object: info,
+			})
+		}
+	}
+	if len(keys) > 0 {
+		c.refs[ref] = keys
+	} else {
+		delete(c.refs, ref)
+	}
+	listeners := c.getListeners()
+	c.mu.Unlock()
+
+	for _, event := range events {
+		for _, l := range listeners {
+			if l != nil {
+				l <- event
+			}
+		}
+	}
+}
+
 func (c *networkChangeStoreCache) GetDevicesByID(id device.ID) []*Info {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
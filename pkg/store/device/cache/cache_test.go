@@ -140,7 +140,7 @@ func TestDeviceCache(t *testing.T) {
 	chNwChanges <- stream.Event{
 		Type: stream.Created,
 		Object: &networkchange.NetworkChange{
-			ID:    "network-change-1",
+			ID:    "network-change-2",
 			Index: 1,
 			Changes: []*devicechange.Change{
 				{
@@ -159,7 +159,7 @@ func TestDeviceCache(t *testing.T) {
 	chNwChanges <- stream.Event{
 		Type: stream.Created,
 		Object: &networkchange.NetworkChange{
-			ID:    "network-change-1",
+			ID:    "network-change-3",
 			Index: 1,
 			Changes: []*devicechange.Change{
 				{
@@ -203,7 +203,7 @@ func TestDeviceCache(t *testing.T) {
 	chNwChanges <- stream.Event{
 		Type: stream.Created,
 		Object: &networkchange.NetworkChange{
-			ID:    "network-change-1",
+			ID:    "network-change-5",
 			Index: 1,
 			Changes: []*devicechange.Change{
 				{
@@ -266,7 +266,7 @@ func TestDeviceCache(t *testing.T) {
 		},
 	}
 
-	////////////// Send a deleted event - should be ignored ////////////////////
+	////////////// Send a deleted event - removes the devices of the change ////////////////////
 	chNwChanges <- stream.Event{
 		Type: stream.Deleted,
 		Object: &networkchange.NetworkChange{
@@ -284,4 +284,86 @@ func TestDeviceCache(t *testing.T) {
 
 	// Wait for the test to complete
 	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, cache.GetDevicesByID("device-1"), 2)
+}
+
+func TestDeviceCacheReferences(t *testing.T) {
+	chNwChangesVal := &atomic.Value{}
+	chSnapshotsVal := &atomic.Value{}
+
+	ctrl := gomock.NewController(t)
+	netChangeStore := store.NewMockNetworkChangesStore(ctrl)
+	netChangeStore.EXPECT().Watch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ch chan<- stream.Event, opts ...networkchangestore.WatchOption) (stream.Context, error) {
+			chNwChangesVal.Store(ch)
+			return stream.NewContext(func() {
+			}), nil
+		}).AnyTimes()
+
+	devSnapshotStore := store.NewMockDeviceSnapshotStore(ctrl)
+	devSnapshotStore.EXPECT().Watch(gomock.Any()).DoAndReturn(
+		func(chSs chan<- stream.Event) (stream.Context, error) {
+			chSnapshotsVal.Store(chSs)
+			return stream.NewContext(func() {
+			}), nil
+		}).AnyTimes()
+	cache, err := NewCache(netChangeStore, devSnapshotStore)
+	assert.NoError(t, err)
+	chNwChanges := chNwChangesVal.Load().(chan<- stream.Event)
+	chSnapshots := chSnapshotsVal.Load().(chan<- stream.Event)
+
+	change1 := &networkchange.NetworkChange{
+		ID: "network-change-1",
+		Changes: []*devicechange.Change{
+			{DeviceID: "device-1", DeviceType: "Stratum", DeviceVersion: "1.0.0"},
+			{DeviceID: "device-2", DeviceType: "Stratum", DeviceVersion: "1.0.0"},
+		},
+	}
+	chNwChanges <- stream.Event{Type: stream.Created, Object: change1}
+	chSnapshots <- stream.Event{
+		Type: stream.Created,
+		Object: &devicesnapshot.DeviceSnapshot{
+			ID:            "dev-snapshot-1",
+			DeviceID:      "device-1",
+			DeviceType:    "Stratum",
+			DeviceVersion: "1.0.0",
+		},
+	}
+
+	cacheChan := make(chan stream.Event, 10)
+	watcherCtx, err := cache.Watch(cacheChan, false)
+	assert.NoError(t, err)
+	defer watcherCtx.Close()
+
+	// Removing device-2 from the change removes it from the cache
+	chNwChanges <- stream.Event{
+		Type: stream.Updated,
+		Object: &networkchange.NetworkChange{
+			ID:      "network-change-1",
+			Changes: change1.Changes[:1],
+		},
+	}
+	event := <-cacheChan
+	assert.Equal(t, stream.Deleted, event.Type)
+	assert.Equal(t, devicebase.ID("device-2"), event.Object.(*Info).DeviceID)
+	assert.Len(t, cache.GetDevicesByID("device-2"), 0)
+
+	// The snapshot keeps device-1 once the change is deleted
+	chNwChanges <- stream.Event{Type: stream.Deleted, Object: change1}
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, cache.GetDevicesByID("device-1"), 1)
+
+	chSnapshots <- stream.Event{
+		Type: stream.Deleted,
+		Object: &devicesnapshot.DeviceSnapshot{
+			ID:            "dev-snapshot-1",
+			DeviceID:      "device-1",
+			DeviceType:    "Stratum",
+			DeviceVersion: "1.0.0",
+		},
+	}
+	event = <-cacheChan
+	assert.Equal(t, stream.Deleted, event.Type)
+	assert.Equal(t, devicebase.ID("device-1"), event.Object.(*Info).DeviceID)
+	assert.Len(t, cache.GetDevices(), 0)
 }
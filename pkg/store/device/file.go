@@ -0,0 +1,241 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// fileDevice is a device of an inventory file
+type fileDevice struct {
+	ID          string            `yaml:"id"`
+	Address     string            `yaml:"address"`
+	Target      string            `yaml:"target,omitempty"`
+	Type        string            `yaml:"type,omitempty"`
+	Version     string            `yaml:"version,omitempty"`
+	Role        string            `yaml:"role,omitempty"`
+	Displayname string            `yaml:"displayname,omitempty"`
+	Timeout     string            `yaml:"timeout,omitempty"`
+	Credentials fileCredentials   `yaml:"credentials,omitempty"`
+	TLS         fileTLS           `yaml:"tls,omitempty"`
+	Attributes  map[string]string `yaml:"attributes,omitempty"`
+}
+
+type fileCredentials struct {
+	User     string `yaml:"user,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+type fileTLS struct {
+	CaCert   string `yaml:"caCert,omitempty"`
+	Cert     string `yaml:"cert,omitempty"`
+	Key      string `yaml:"key,omitempty"`
+	Plain    bool   `yaml:"plain,omitempty"`
+	Insecure bool   `yaml:"insecure,omitempty"`
+}
+
+func (d fileDevice) toDevice(untyped bool) (*device.Device, error) {
+	if d.ID == "" {
+		return nil, errors.NewInvalid("device with address '%s' has no id", d.Address)
+	}
+	if d.Address == "" {
+		return nil, errors.NewInvalid("device %s has no address", d.ID)
+	}
+	if !untyped && (d.Type == "" || d.Version == "") {
+		return nil, errors.NewInvalid("device %s has no type or version", d.ID)
+	}
+	result := &device.Device{
+		ID:          device.ID(d.ID),
+		Address:     d.Address,
+		Target:      d.Target,
+		Type:        device.Type(d.Type),
+		Version:     d.Version,
+		Role:        device.Role(d.Role),
+		Displayname: d.Displayname,
+		Credentials: device.Credentials{
+			User:     d.Credentials.User,
+			Password: d.Credentials.Password,
+		},
+		TLS: device.TLSConfig{
+			CaCert:   d.TLS.CaCert,
+			Cert:     d.TLS.Cert,
+			Key:      d.TLS.Key,
+			Plain:    d.TLS.Plain,
+			Insecure: d.TLS.Insecure,
+		},
+		Attributes: make(map[string]string),
+		Revision:   1,
+	}
+	for key, value := range d.Attributes {
+		result.Attributes[key] = value
+	}
+	if d.Timeout != "" {
+		timeout, err := time.ParseDuration(d.Timeout)
+		if err != nil {
+			return nil, errors.NewInvalid("device %s has an invalid timeout: %v", d.ID, err)
+		}
+		result.Timeout = &timeout
+	}
+	return result, nil
+}
+
+// NewFileStore returns a device store of the devices listed in a YAML or JSON file, for
+// running onos-config without the topo service. Updates of the devices, e.g. of their
+// protocol states, are kept in memory and not written back to the file. Devices without a
+// type or version are only allowed if untyped is set, for their model to be detected
+func NewFileStore(path string, untyped bool) (Store, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newFileStore(data, untyped)
+}
+
+func newFileStore(data []byte, untyped bool) (*fileStore, error) {
+	var fileDevices []fileDevice
+	if err := yaml.UnmarshalStrict(data, &fileDevices); err != nil {
+		return nil, errors.NewInvalid("invalid device inventory: %v", err)
+	}
+	store := &fileStore{
+		devices: make(map[device.ID]*device.Device),
+	}
+	for _, fileDevice := range fileDevices {
+		d, err := fileDevice.toDevice(untyped)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := store.devices[d.ID]; ok {
+			return nil, errors.NewInvalid("device %s is listed more than once", d.ID)
+		}
+		store.devices[d.ID] = d
+	}
+	log.Infof("Loaded %d devices from the device inventory", len(store.devices))
+	return store, nil
+}
+
+// A device Store of the devices of an inventory file
+type fileStore struct {
+	devices  map[device.ID]*device.Device
+	watchers []chan<- *device.ListResponse
+	mu       sync.RWMutex
+}
+
+// fileWatchBufferSize is the number of device events buffered for each watcher, so that
+// devices can be updated by the consumers of the events
+const fileWatchBufferSize = 1000
+
+func (s *fileStore) Get(id device.ID) (*device.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.devices[id]
+	if !ok {
+		return nil, errors.NewNotFound("device %s not found", id)
+	}
+	return copyDevice(d), nil
+}
+
+func (s *fileStore) Update(updatedDevice *device.Device) (*device.Device, error) {
+	s.mu.Lock()
+	d, ok := s.devices[updatedDevice.ID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, errors.NewNotFound("device %s not found", updatedDevice.ID)
+	}
+	if updatedDevice.Revision != 0 && updatedDevice.Revision != d.Revision {
+		s.mu.Unlock()
+		return nil, errors.NewConflict("device %s has been updated since revision %d", updatedDevice.ID, updatedDevice.Revision)
+	}
+	d = copyDevice(updatedDevice)
+	d.Revision++
+	s.devices[d.ID] = d
+	for _, ch := range s.watchers {
+		ch <- &device.ListResponse{
+			Type:   device.ListResponseUPDATED,
+			Device: copyDevice(d),
+		}
+	}
+	s.mu.Unlock()
+	return copyDevice(d), nil
+}
+
+func (s *fileStore) List(ch chan<- *device.Device) error {
+	devices := s.list()
+	go func() {
+		for _, d := range devices {
+			ch <- d
+		}
+	}()
+	return nil
+}
+
+// Watch sends the devices of the inventory, then their updates
+func (s *fileStore) Watch(ch chan<- *device.ListResponse) error {
+	s.mu.Lock()
+	devices := s.sortedDevices()
+	events := make(chan *device.ListResponse, len(devices)+fileWatchBufferSize)
+	for _, d := range devices {
+		events <- &device.ListResponse{
+			Type:   device.ListResponseNONE,
+			Device: d,
+		}
+	}
+	s.watchers = append(s.watchers, events)
+	s.mu.Unlock()
+	go func() {
+		for event := range events {
+			ch <- event
+		}
+	}()
+	return nil
+}
+
+// list returns copies of the devices sorted by ID
+func (s *fileStore) list() []*device.Device {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedDevices()
+}
+
+// sortedDevices returns copies of the devices sorted by ID. The store must be locked
+func (s *fileStore) sortedDevices() []*device.Device {
+	devices := make([]*device.Device, 0, len(s.devices))
+	for _, d := range s.devices {
+		devices = append(devices, copyDevice(d))
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ID < devices[j].ID
+	})
+	return devices
+}
+
+// copyDevice copies a device so that it is not changed while in the store
+func copyDevice(d *device.Device) *device.Device {
+	result := *d
+	result.Attributes = make(map[string]string, len(d.Attributes))
+	for key, value := range d.Attributes {
+		result.Attributes[key] = value
+	}
+	result.Protocols = make([]*topo.ProtocolState, len(d.Protocols))
+	copy(result.Protocols, d.Protocols)
+	return &result
+}
@@ -0,0 +1,120 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const inventoryYAML = `
+- id: device-1
+  address: device-1:1234
+  type: Stratum
+  version: 1.0.0
+  timeout: 5s
+  tls:
+    plain: true
+- id: device-2
+  address: device-2:1234
+  credentials:
+    user: admin
+    password: secret
+  attributes:
+    site: lab
+`
+
+const inventoryJSON = `[{"id": "device-3", "address": "device-3:1234", "tls": {"insecure": true}}]`
+
+func TestFileStore(t *testing.T) {
+	store, err := newFileStore([]byte(inventoryYAML), true)
+	assert.NoError(t, err)
+
+	device1, err := store.Get(device1ID)
+	assert.NoError(t, err)
+	assert.Equal(t, device1Addr, device1.Address)
+	assert.Equal(t, topodevice.Type(stratumType), device1.Type)
+	assert.Equal(t, v1, device1.Version)
+	assert.Equal(t, 5*time.Second, *device1.Timeout)
+	assert.True(t, device1.TLS.Plain)
+
+	device2, err := store.Get(device2ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", device2.Credentials.User)
+	assert.Equal(t, "lab", device2.Attributes["site"])
+	assert.Equal(t, "", device2.Version)
+
+	_, err = store.Get(device3ID)
+	assert.True(t, errors.IsNotFound(err))
+
+	listCh := make(chan *topodevice.Device)
+	assert.NoError(t, store.List(listCh))
+	assert.Equal(t, device1ID, (<-listCh).ID)
+	assert.Equal(t, device2ID, (<-listCh).ID)
+
+	watchCh := make(chan *topodevice.ListResponse)
+	assert.NoError(t, store.Watch(watchCh))
+	event := <-watchCh
+	assert.Equal(t, topodevice.ListResponseNONE, event.Type)
+	assert.Equal(t, device1ID, event.Device.ID)
+	event = <-watchCh
+	assert.Equal(t, device2ID, event.Device.ID)
+
+	device1.Version = "2.0.0"
+	updated, err := store.Update(device1)
+	assert.NoError(t, err)
+	assert.Equal(t, device1.Revision+1, updated.Revision)
+	event = <-watchCh
+	assert.Equal(t, topodevice.ListResponseUPDATED, event.Type)
+	assert.Equal(t, "2.0.0", event.Device.Version)
+
+	// An update of an older revision is refused
+	_, err = store.Update(device1)
+	assert.True(t, errors.IsConflict(err))
+}
+
+func TestNewFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "devices.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(inventoryJSON), 0644))
+	store, err := NewFileStore(path, true)
+	assert.NoError(t, err)
+	device3, err := store.Get(device3ID)
+	assert.NoError(t, err)
+	assert.True(t, device3.TLS.Insecure)
+
+	// Devices must have a type and version unless their models are detected
+	_, err = newFileStore([]byte(inventoryJSON), false)
+	assert.True(t, errors.IsInvalid(err))
+	_, err = newFileStore([]byte(`[{"id": "device-1", "address": "a:1", "type": "Stratum", "version": "1.0.0"}]`), false)
+	assert.NoError(t, err)
+
+	_, err = newFileStore([]byte(`[{"id": "device-1"}]`), true)
+	assert.True(t, errors.IsInvalid(err))
+	_, err = newFileStore([]byte(`[{"id": "device-1", "address": "a:1"}, {"id": "device-1", "address": "b:1"}]`), true)
+	assert.True(t, errors.IsInvalid(err))
+	_, err = newFileStore([]byte(`[{"id": "device-1", "address": "a:1", "adress": "b:1"}]`), true)
+	assert.True(t, errors.IsInvalid(err))
+}
@@ -45,8 +45,9 @@ type Store interface {
 	Watch(chan<- *device.ListResponse) error
 }
 
-// NewTopoStore returns a new topo-based device store
-func NewTopoStore(topoEndpoint string, opts ...grpc.DialOption) (Store, error) {
+// NewTopoStore returns a new topo-based device store. Topo entities without a version or a
+// kind are only devices if untyped is set, for their model to be detected from their capabilities
+func NewTopoStore(topoEndpoint string, untyped bool, opts ...grpc.DialOption) (Store, error) {
 	if len(opts) == 0 {
 		return nil, fmt.Errorf("no opts given when creating topo store")
 	}
@@ -58,7 +59,8 @@ func NewTopoStore(topoEndpoint string, opts ...grpc.DialOption) (Store, error) {
 	client := topo.NewTopoClient(conn)
 
 	return &topoStore{
-		client: client,
+		client:  client,
+		untyped: untyped,
 	}, nil
 }
 
@@ -69,7 +71,16 @@ func NewStore(client topo.TopoClient) (Store, error) {
 
 // A device Store that uses the topo service to propagate devices
 type topoStore struct {
-	client topo.TopoClient
+	client  topo.TopoClient
+	untyped bool
+}
+
+// toDevice converts a topo object to a device, which may be untyped if the store allows it
+func (s *topoStore) toDevice(object *topo.Object) (*device.Device, error) {
+	if s.untyped {
+		return device.ToUntypedDevice(object)
+	}
+	return device.ToDevice(object)
 }
 
 func (s *topoStore) Get(id device.ID) (*device.Device, error) {
@@ -81,7 +92,7 @@ func (s *topoStore) Get(id device.ID) (*device.Device, error) {
 	if err != nil {
 		return nil, err
 	}
-	return device.ToDevice(response.Object)
+	return s.toDevice(response.Object)
 }
 
 func (s *topoStore) Update(updatedDevice *device.Device) (*device.Device, error) {
@@ -94,7 +105,7 @@ func (s *topoStore) Update(updatedDevice *device.Device) (*device.Device, error)
 	if err != nil {
 		return nil, err
 	}
-	return device.ToDevice(response.Object)
+	return s.toDevice(response.Object)
 }
 
 func (s *topoStore) List(ch chan<- *device.Device) error {
@@ -105,7 +116,7 @@ func (s *topoStore) List(ch chan<- *device.Device) error {
 
 	go func() {
 		for _, object := range resp.Objects {
-			configDevice, err := device.ToDevice(&object)
+			configDevice, err := s.toDevice(&object)
 			if err != nil {
 				log.Warnf("Ignoring Topo object. %s", err.Error())
 				continue
@@ -133,7 +144,7 @@ func (s *topoStore) Watch(ch chan<- *device.ListResponse) error {
 				break
 			}
 			if resp.Event.Object.Type == topo.Object_ENTITY {
-				configDevice, err := device.ToDevice(&resp.Event.Object)
+				configDevice, err := s.toDevice(&resp.Event.Object)
 				if err != nil {
 					log.Warnf("Ignoring Topo event. %s +v+", err.Error(), resp.Event)
 					continue
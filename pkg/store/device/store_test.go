@@ -150,3 +150,67 @@ func nextDevice(t *testing.T, ch chan *topodevice.Device) *topodevice.Device {
 	}
 	return nil
 }
+
+func TestUntypedDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	// A device left for its model to be detected has no type nor version
+	device1 := &topodevice.Device{
+		ID:       device1ID,
+		Revision: 1,
+		Address:  device1Addr,
+	}
+	client := mocks.NewMockTopoClient(ctrl)
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&topo.GetResponse{Object: topodevice.ToObject(device1)}, nil).Times(2)
+
+	store := topoStore{
+		client: client,
+	}
+	_, err := store.Get(device1.ID)
+	assert.Error(t, err)
+
+	store.untyped = true
+	device, err := store.Get(device1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, device1.ID, device.ID)
+	assert.Equal(t, "", device.Version)
+}
+
+func TestUpdateDeviceCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	device1 := &topodevice.Device{
+		ID:       device1ID,
+		Revision: 1,
+		Address:  device1Addr,
+		Version:  v1,
+		Type:     stratumType,
+		Credentials: topodevice.Credentials{
+			User:     "admin",
+			Password: "secret",
+		},
+	}
+
+	// The credentials are not written to topo by updates of the device
+	client := mocks.NewMockTopoClient(ctrl)
+	client.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, request *topo.UpdateRequest, _ ...interface{}) (*topo.UpdateResponse, error) {
+			assert.NotContains(t, request.Object.Attributes, topo.User)
+			assert.NotContains(t, request.Object.Attributes, topo.Password)
+			return &topo.UpdateResponse{Object: request.Object}, nil
+		})
+	store := topoStore{
+		client: client,
+	}
+	_, err := store.Update(device1)
+	assert.NoError(t, err)
+
+	// They are read from the attributes of the topo object
+	object := topodevice.ToObject(device1)
+	object.Attributes[topo.User] = "admin"
+	object.Attributes[topo.Password] = "secret"
+	device, err := topodevice.ToDevice(object)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", device.Credentials.User)
+	assert.Equal(t, "secret", device.Credentials.Password)
+}
@@ -0,0 +1,272 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package opstate is a store of the operational state of devices shared by all the nodes
+// of the cluster. The master of a device replicates the state it collects in to the store,
+// from which the other nodes serve it. The state of a device is stored as a single snapshot,
+// so that replicating any number of changed values takes a single write.
+package opstate
+
+import (
+	"context"
+	"io"
+	"time"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/atomix/go-client/pkg/client/util/net"
+	"github.com/gogo/protobuf/proto"
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-config/pkg/config"
+	"github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+const opStateName = "opstate"
+
+// NewAtomixStore returns a new persistent Store
+func NewAtomixStore(config config.Config) (Store, error) {
+	database, err := atomix.GetDatabase(config.Atomix, config.Atomix.GetDatabase(atomix.DatabaseTypeConsensus))
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	states, err := database.GetMap(context.Background(), opStateName)
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	return &atomixStore{
+		states: states,
+	}, nil
+}
+
+// NewLocalStore returns a new local operational state store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return newLocalStore(address)
+}
+
+// newLocalStore creates a new local operational state store
+func newLocalStore(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      opStateName,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	session, err := primitive.NewSession(ctx, primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+	states, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	return &atomixStore{
+		states: states,
+	}, nil
+}
+
+// StateValue is an operational state value of a device
+type StateValue struct {
+	// Path is the path of the value
+	Path string
+	// Value is the value
+	Value *devicechange.TypedValue
+	// Timestamp is when the device sampled the value, in nanoseconds since the epoch
+	Timestamp int64
+}
+
+// Snapshot is the operational state of a device
+type Snapshot struct {
+	// DeviceID is the device the state is of
+	DeviceID device.ID
+	// Values are the state values of the device
+	Values []*StateValue
+}
+
+// Store stores the operational state of devices
+type Store interface {
+	io.Closer
+
+	// Put stores the state of a device, replacing the state previously stored
+	Put(snapshot *Snapshot) error
+
+	// Get gets the state of a device. A device with no state stored has an empty snapshot
+	Get(deviceID device.ID) (*Snapshot, error)
+
+	// Purge deletes the state of a device
+	Purge(deviceID device.ID) error
+
+	// Watch watches the state of a device for changes, replaying the current state first.
+	// The events of a purged state are Deleted events with an empty snapshot
+	Watch(deviceID device.ID, ch chan<- stream.Event) (stream.Context, error)
+}
+
+// atomixStore is the default implementation of the operational state store
+type atomixStore struct {
+	states _map.Map
+}
+
+func (s *atomixStore) Put(snapshot *Snapshot) error {
+	if snapshot.DeviceID == "" {
+		return errors.NewInvalid("no device ID specified")
+	}
+
+	bytes, err := encodeSnapshot(snapshot)
+	if err != nil {
+		return errors.NewInvalid("state encoding failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if _, err := s.states.Put(ctx, string(snapshot.DeviceID), bytes); err != nil {
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) Get(deviceID device.ID) (*Snapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	entry, err := s.states.Get(ctx, string(deviceID))
+	if err != nil {
+		err = errors.FromAtomix(err)
+		if errors.IsNotFound(err) {
+			return &Snapshot{DeviceID: deviceID}, nil
+		}
+		return nil, err
+	} else if entry == nil {
+		return &Snapshot{DeviceID: deviceID}, nil
+	}
+	return decodeSnapshot(entry)
+}
+
+func (s *atomixStore) Purge(deviceID device.ID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if _, err := s.states.Remove(ctx, string(deviceID)); err != nil {
+		err = errors.FromAtomix(err)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *atomixStore) Watch(deviceID device.ID, ch chan<- stream.Event) (stream.Context, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mapCh := make(chan *_map.Event)
+	if err := s.states.Watch(ctx, mapCh, _map.WithReplay(), _map.WithFilter(_map.Filter{Key: string(deviceID)})); err != nil {
+		cancel()
+		return nil, errors.FromAtomix(err)
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if event.Entry.Key != string(deviceID) {
+				continue
+			}
+			if event.Type == _map.EventRemoved {
+				ch <- stream.Event{
+					Type:   stream.Deleted,
+					Object: &Snapshot{DeviceID: deviceID},
+				}
+				continue
+			}
+			if snapshot, err := decodeSnapshot(event.Entry); err == nil {
+				switch event.Type {
+				case _map.EventNone:
+					ch <- stream.Event{
+						Type:   stream.None,
+						Object: snapshot,
+					}
+				case _map.EventInserted:
+					ch <- stream.Event{
+						Type:   stream.Created,
+						Object: snapshot,
+					}
+				case _map.EventUpdated:
+					ch <- stream.Event{
+						Type:   stream.Updated,
+						Object: snapshot,
+					}
+				}
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+func (s *atomixStore) Close() error {
+	if err := s.states.Close(context.Background()); err != nil {
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+// encodeSnapshot encodes the values of a snapshot as a count followed by each value as a
+// length delimited PathValue message and its timestamp
+func encodeSnapshot(snapshot *Snapshot) ([]byte, error) {
+	buf := proto.NewBuffer(nil)
+	if err := buf.EncodeVarint(uint64(len(snapshot.Values))); err != nil {
+		return nil, err
+	}
+	for _, value := range snapshot.Values {
+		if value.Path == "" {
+			return nil, errors.NewInvalid("no path specified")
+		}
+		if err := buf.EncodeMessage(&devicechange.PathValue{Path: value.Path, Value: value.Value}); err != nil {
+			return nil, err
+		}
+		if err := buf.EncodeVarint(uint64(value.Timestamp)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSnapshot(entry *_map.Entry) (*Snapshot, error) {
+	buf := proto.NewBuffer(entry.Value)
+	count, err := buf.DecodeVarint()
+	if err != nil {
+		return nil, errors.NewInvalid("state decoding failed: %v", err)
+	}
+	snapshot := &Snapshot{
+		DeviceID: device.ID(entry.Key),
+		Values:   make([]*StateValue, 0, count),
+	}
+	for i := uint64(0); i < count; i++ {
+		pathValue := &devicechange.PathValue{}
+		if err := buf.DecodeMessage(pathValue); err != nil {
+			return nil, errors.NewInvalid("state decoding failed: %v", err)
+		}
+		timestamp, err := buf.DecodeVarint()
+		if err != nil {
+			return nil, errors.NewInvalid("state decoding failed: %v", err)
+		}
+		snapshot.Values = append(snapshot.Values, &StateValue{
+			Path:      pathValue.Path,
+			Value:     pathValue.Value,
+			Timestamp: int64(timestamp),
+		})
+	}
+	return snapshot, nil
+}
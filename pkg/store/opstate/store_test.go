@@ -0,0 +1,132 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opstate
+
+import (
+	"testing"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	mtuPath      = "/interfaces/interface[name=eth1]/state/mtu"
+	hostnamePath = "/system/state/hostname"
+)
+
+func TestOpStateStore(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store1, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store1.Close()
+
+	store2, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store2.Close()
+
+	device1 := device.ID("device-1")
+	device2 := device.ID("device-2")
+
+	// A device with no state has an empty snapshot
+	snapshot, err := store2.Get(device1)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Values, 0)
+
+	err = store1.Put(&Snapshot{
+		DeviceID: device1,
+		Values: []*StateValue{
+			{Path: mtuPath, Value: devicechange.NewTypedValueUint(1500, 16), Timestamp: 1000},
+		},
+	})
+	assert.NoError(t, err)
+
+	// The state stored on one node is watched from the other
+	ch := make(chan stream.Event)
+	_, err = store2.Watch(device1, ch)
+	assert.NoError(t, err)
+
+	event := nextEvent(t, ch)
+	assert.Equal(t, stream.None, event.Type)
+	snapshot = event.Object.(*Snapshot)
+	assert.Equal(t, device1, snapshot.DeviceID)
+	assert.Len(t, snapshot.Values, 1)
+	assert.Equal(t, mtuPath, snapshot.Values[0].Path)
+	assert.Equal(t, "1500", snapshot.Values[0].Value.ValueToString())
+	assert.Equal(t, int64(1000), snapshot.Values[0].Timestamp)
+
+	// The state of other devices is not watched
+	err = store1.Put(&Snapshot{
+		DeviceID: device2,
+		Values: []*StateValue{
+			{Path: hostnamePath, Value: devicechange.NewTypedValueString("switch2")},
+		},
+	})
+	assert.NoError(t, err)
+
+	// All the values of a device are replaced at once
+	err = store1.Put(&Snapshot{
+		DeviceID: device1,
+		Values: []*StateValue{
+			{Path: mtuPath, Value: devicechange.NewTypedValueUint(9000, 16), Timestamp: 2000},
+			{Path: hostnamePath, Value: devicechange.NewTypedValueString("switch1"), Timestamp: 2000},
+		},
+	})
+	assert.NoError(t, err)
+	event = nextEvent(t, ch)
+	assert.Equal(t, stream.Updated, event.Type)
+	snapshot = event.Object.(*Snapshot)
+	assert.Equal(t, device1, snapshot.DeviceID)
+	assert.Len(t, snapshot.Values, 2)
+	assert.Equal(t, "9000", snapshot.Values[0].Value.ValueToString())
+	assert.Equal(t, "switch1", snapshot.Values[1].Value.ValueToString())
+
+	snapshot, err = store2.Get(device1)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Values, 2)
+
+	err = store1.Put(&Snapshot{DeviceID: device1, Values: []*StateValue{{}}})
+	assert.Error(t, err)
+	err = store1.Put(&Snapshot{})
+	assert.Error(t, err)
+
+	err = store1.Purge(device1)
+	assert.NoError(t, err)
+	event = nextEvent(t, ch)
+	assert.Equal(t, stream.Deleted, event.Type)
+	assert.Len(t, event.Object.(*Snapshot).Values, 0)
+
+	snapshot, err = store2.Get(device1)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Values, 0)
+
+	// Purging a device with no state is not an error
+	err = store1.Purge(device1)
+	assert.NoError(t, err)
+}
+
+func nextEvent(t *testing.T, ch chan stream.Event) stream.Event {
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	return stream.Event{}
+}
@@ -0,0 +1,286 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/onosproject/onos-api/go/onos/config/device"
+	devicesnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/device"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// NewBoltStore returns a device snapshot store kept in an embedded database file
+func NewBoltStore(db *boltdb.DB) (Store, error) {
+	deviceSnapshots, err := db.GetIndexedMap(deviceSnapshotsName)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := db.GetIndexedMap(snapshotsName)
+	if err != nil {
+		return nil, err
+	}
+	return &boltStore{
+		deviceSnapshots: deviceSnapshots,
+		snapshots:       snapshots,
+	}, nil
+}
+
+// boltStore is a device snapshot store kept in an embedded database file
+type boltStore struct {
+	deviceSnapshots *boltdb.IndexedMap
+	snapshots       *boltdb.IndexedMap
+}
+
+func (s *boltStore) Get(id devicesnapshot.ID) (*devicesnapshot.DeviceSnapshot, error) {
+	entry, err := s.deviceSnapshots.Get(string(id))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, errors.NewNotFound("device snapshot %s not found", id)
+	}
+	return decodeBoltDeviceSnapshot(entry)
+}
+
+func (s *boltStore) Create(snapshot *devicesnapshot.DeviceSnapshot) error {
+	if snapshot.Revision != 0 {
+		return errors.NewInvalid("not a new object")
+	}
+	if snapshot.DeviceID == "" {
+		return errors.NewInvalid("no device ID specified")
+	}
+	if snapshot.DeviceVersion == "" {
+		return errors.NewInvalid("no device version specified")
+	}
+
+	snapshot.ID = devicesnapshot.GetSnapshotID(snapshot.NetworkSnapshot.ID, snapshot.DeviceID, snapshot.DeviceVersion)
+
+	bytes, err := proto.Marshal(snapshot)
+	if err != nil {
+		return errors.NewInvalid("snapshot encoding failed: %v", err)
+	}
+
+	entry, err := s.deviceSnapshots.Put(string(snapshot.ID), bytes, boltdb.IfNotSet())
+	if err != nil {
+		return err
+	}
+
+	snapshot.Revision = devicesnapshot.Revision(entry.Version)
+	snapshot.Created = entry.Created
+	snapshot.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) Update(snapshot *devicesnapshot.DeviceSnapshot) error {
+	if snapshot.Revision == 0 {
+		return errors.NewInvalid("not a stored object")
+	}
+	if snapshot.DeviceID == "" {
+		return errors.NewInvalid("no device ID specified")
+	}
+	if snapshot.DeviceVersion == "" {
+		return errors.NewInvalid("no device version specified")
+	}
+
+	snapshot.Updated = time.Now()
+	bytes, err := proto.Marshal(snapshot)
+	if err != nil {
+		return errors.NewInvalid("snapshot encoding failed: %v", err)
+	}
+
+	entry, err := s.deviceSnapshots.Put(string(snapshot.ID), bytes, boltdb.IfVersion(boltdb.Version(snapshot.Revision)))
+	if err != nil {
+		return err
+	}
+
+	snapshot.Revision = devicesnapshot.Revision(entry.Version)
+	snapshot.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) Delete(snapshot *devicesnapshot.DeviceSnapshot) error {
+	if snapshot.Revision == 0 {
+		return errors.NewInvalid("not a stored object")
+	}
+
+	entry, err := s.deviceSnapshots.Remove(string(snapshot.ID), boltdb.IfVersion(boltdb.Version(snapshot.Revision)))
+	if err != nil {
+		return err
+	}
+
+	snapshot.Revision = 0
+	snapshot.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) List(ch chan<- *devicesnapshot.DeviceSnapshot) (stream.Context, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Entry)
+	if err := s.deviceSnapshots.Entries(ctx, mapCh); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for entry := range mapCh {
+			if snapshot, err := decodeBoltDeviceSnapshot(entry); err == nil {
+				ch <- snapshot
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+func (s *boltStore) Watch(ch chan<- stream.Event) (stream.Context, error) {
+	return watchBolt(s.deviceSnapshots, ch, func(entry *boltdb.Entry) (interface{}, error) {
+		return decodeBoltDeviceSnapshot(entry)
+	})
+}
+
+func (s *boltStore) Store(snapshot *devicesnapshot.Snapshot) error {
+	if snapshot.DeviceID == "" {
+		return errors.NewInvalid("no device ID specified")
+	}
+	if snapshot.DeviceVersion == "" {
+		return errors.NewInvalid("no device version specified")
+	}
+
+	bytes, err := proto.Marshal(snapshot)
+	if err != nil {
+		return errors.NewInvalid("snapshot encoding failed: %v", err)
+	}
+
+	_, err = s.snapshots.Put(string(snapshot.GetVersionedDeviceID()), bytes)
+	return err
+}
+
+func (s *boltStore) Load(deviceID device.VersionedID) (*devicesnapshot.Snapshot, error) {
+	entry, err := s.snapshots.Get(string(deviceID))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, errors.NewNotFound("snapshot of device %s not found", deviceID)
+	}
+	return decodeBoltSnapshot(entry)
+}
+
+func (s *boltStore) Purge(deviceID device.VersionedID) error {
+	_, err := s.snapshots.Remove(string(deviceID))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *boltStore) LoadAll(ch chan<- *devicesnapshot.Snapshot) (stream.Context, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Entry)
+	if err := s.snapshots.Entries(ctx, mapCh); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for entry := range mapCh {
+			if snapshot, err := decodeBoltSnapshot(entry); err == nil {
+				ch <- snapshot
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+// WatchAll is similar to LoadAll for "Snapshot"s, but for continuous streaming
+func (s *boltStore) WatchAll(ch chan<- stream.Event) (stream.Context, error) {
+	return watchBolt(s.snapshots, ch, func(entry *boltdb.Entry) (interface{}, error) {
+		return decodeBoltSnapshot(entry)
+	})
+}
+
+// Close does not close the database, which is shared with the other stores
+func (s *boltStore) Close() error {
+	return nil
+}
+
+// watchBolt sends the events of a map, replaying its entries, as events of the decoded objects
+func watchBolt(m *boltdb.IndexedMap, ch chan<- stream.Event, decode func(*boltdb.Entry) (interface{}, error)) (stream.Context, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Event)
+	if err := m.Watch(ctx, mapCh, boltdb.WithReplay()); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if object, err := decode(event.Entry); err == nil {
+				switch event.Type {
+				case boltdb.EventNone:
+					ch <- stream.Event{
+						Type:   stream.None,
+						Object: object,
+					}
+				case boltdb.EventInserted:
+					ch <- stream.Event{
+						Type:   stream.Created,
+						Object: object,
+					}
+				case boltdb.EventUpdated:
+					ch <- stream.Event{
+						Type:   stream.Updated,
+						Object: object,
+					}
+				case boltdb.EventRemoved:
+					ch <- stream.Event{
+						Type:   stream.Deleted,
+						Object: object,
+					}
+				}
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+func decodeBoltDeviceSnapshot(entry *boltdb.Entry) (*devicesnapshot.DeviceSnapshot, error) {
+	snapshot := &devicesnapshot.DeviceSnapshot{}
+	if err := proto.Unmarshal(entry.Value, snapshot); err != nil {
+		return nil, errors.NewInvalid("device snapshot decoding failed: %v", err)
+	}
+	snapshot.ID = devicesnapshot.ID(entry.Key)
+	snapshot.Revision = devicesnapshot.Revision(entry.Version)
+	snapshot.Created = entry.Created
+	snapshot.Updated = entry.Updated
+	return snapshot, nil
+}
+
+func decodeBoltSnapshot(entry *boltdb.Entry) (*devicesnapshot.Snapshot, error) {
+	snapshot := &devicesnapshot.Snapshot{}
+	if err := proto.Unmarshal(entry.Value, snapshot); err != nil {
+		return nil, errors.NewInvalid("snapshot decoding failed: %v", err)
+	}
+	snapshot.ID = devicesnapshot.ID(entry.Key)
+	return snapshot, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package device
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-api/go/onos/config/device"
+	devicesnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/device"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "device-snapshots")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "onos-config.db")
+
+	db, err := boltdb.Open(path)
+	assert.NoError(t, err)
+	store, err := NewBoltStore(db)
+	assert.NoError(t, err)
+
+	snapshot1 := &devicesnapshot.DeviceSnapshot{
+		DeviceID:      "device-1",
+		DeviceVersion: "1.0.0",
+		NetworkSnapshot: devicesnapshot.NetworkSnapshotRef{
+			ID:    "snapshot-1",
+			Index: 1,
+		},
+	}
+	assert.NoError(t, store.Create(snapshot1))
+	assert.Equal(t, devicesnapshot.ID("snapshot-1:device-1:1.0.0"), snapshot1.ID)
+	snapshot1.Revision = 0
+	assert.True(t, errors.IsAlreadyExists(store.Create(snapshot1)))
+
+	deviceID := device.NewVersionedID("device-1", "1.0.0")
+	err = store.Store(&devicesnapshot.Snapshot{
+		DeviceID:      "device-1",
+		DeviceVersion: "1.0.0",
+		ChangeIndex:   1,
+	})
+	assert.NoError(t, err)
+
+	// The snapshots are kept when the database is reopened
+	assert.NoError(t, db.Close())
+	db, err = boltdb.Open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	store, err = NewBoltStore(db)
+	assert.NoError(t, err)
+
+	stored, err := store.Get(snapshot1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, device.ID("device-1"), stored.DeviceID)
+	snapshot, err := store.Load(deviceID)
+	assert.NoError(t, err)
+	assert.Equal(t, devicechange.Index(1), snapshot.ChangeIndex)
+
+	ch := make(chan stream.Event)
+	ctx, err := store.WatchAll(ch)
+	assert.NoError(t, err)
+	defer ctx.Close()
+	event := <-ch
+	assert.Equal(t, stream.None, event.Type)
+	assert.Equal(t, deviceID, event.Object.(*devicesnapshot.Snapshot).GetVersionedDeviceID())
+
+	assert.NoError(t, store.Purge(deviceID))
+	event = <-ch
+	assert.Equal(t, stream.Deleted, event.Type)
+	_, err = store.Load(deviceID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.NoError(t, store.Purge(deviceID))
+
+	assert.NoError(t, store.Delete(stored))
+	_, err = store.Get(snapshot1.ID)
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -122,6 +122,9 @@ type Store interface {
 	// Load loads a snapshot
 	Load(deviceID device.VersionedID) (*devicesnapshot.Snapshot, error)
 
+	// Purge removes the stored snapshot of a device version
+	Purge(deviceID device.VersionedID) error
+
 	// Load loads all snapshots
 	LoadAll(ch chan<- *devicesnapshot.Snapshot) (stream.Context, error)
 
@@ -320,6 +323,21 @@ func (s *atomixStore) Load(deviceID device.VersionedID) (*devicesnapshot.Snapsho
 	return decodeSnapshot(entry)
 }
 
+func (s *atomixStore) Purge(deviceID device.VersionedID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	_, err := s.snapshots.Remove(ctx, string(deviceID))
+	if err != nil {
+		err = errors.FromAtomix(err)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *atomixStore) LoadAll(ch chan<- *devicesnapshot.Snapshot) (stream.Context, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -173,6 +173,36 @@ func TestDeviceSnapshotStore(t *testing.T) {
 	assert.NotNil(t, snapshot)
 }
 
+func TestPurgeSnapshot(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	deviceID := device.NewVersionedID("device-1", "1.0.0")
+	err = store.Store(&devicesnapshot.Snapshot{
+		DeviceID:      "device-1",
+		DeviceVersion: "1.0.0",
+		ChangeIndex:   1,
+	})
+	assert.NoError(t, err)
+
+	snapshot, err := store.Load(deviceID)
+	assert.NoError(t, err)
+	assert.NotNil(t, snapshot)
+
+	err = store.Purge(deviceID)
+	assert.NoError(t, err)
+	snapshot, err = store.Load(deviceID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.Nil(t, snapshot)
+
+	// Purging a device version without a snapshot is not an error
+	err = store.Purge(deviceID)
+	assert.NoError(t, err)
+}
+
 func nextEvent(t *testing.T, ch chan stream.Event) *devicesnapshot.DeviceSnapshot {
 	select {
 	case c := <-ch:
@@ -0,0 +1,200 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/proto"
+	networksnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/network"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// NewBoltStore returns a network snapshot store kept in an embedded database file
+func NewBoltStore(db *boltdb.DB) (Store, error) {
+	snapshots, err := db.GetIndexedMap(snapshotsName)
+	if err != nil {
+		return nil, err
+	}
+	return &boltStore{
+		snapshots: snapshots,
+	}, nil
+}
+
+// boltStore is a network snapshot store kept in an embedded database file
+type boltStore struct {
+	snapshots *boltdb.IndexedMap
+}
+
+func (s *boltStore) Get(id networksnapshot.ID) (*networksnapshot.NetworkSnapshot, error) {
+	entry, err := s.snapshots.Get(string(id))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, nil
+	}
+	return decodeBoltSnapshot(entry)
+}
+
+func (s *boltStore) GetByIndex(index networksnapshot.Index) (*networksnapshot.NetworkSnapshot, error) {
+	entry, err := s.snapshots.GetIndex(boltdb.Index(index))
+	if err != nil {
+		return nil, err
+	} else if entry == nil {
+		return nil, nil
+	}
+	return decodeBoltSnapshot(entry)
+}
+
+func (s *boltStore) Create(snapshot *networksnapshot.NetworkSnapshot) error {
+	if snapshot.ID == "" {
+		snapshot.ID = newSnapshotID()
+	}
+	if snapshot.Revision != 0 {
+		return errors.NewInvalid("not a new object")
+	}
+
+	bytes, err := proto.Marshal(snapshot)
+	if err != nil {
+		return errors.NewInvalid("snapshot encoding failed: %v", err)
+	}
+
+	entry, err := s.snapshots.Append(string(snapshot.ID), bytes)
+	if err != nil {
+		return err
+	}
+
+	snapshot.Index = networksnapshot.Index(entry.Index)
+	snapshot.Revision = networksnapshot.Revision(entry.Version)
+	snapshot.Created = entry.Created
+	snapshot.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) Update(snapshot *networksnapshot.NetworkSnapshot) error {
+	if snapshot.Revision == 0 {
+		return errors.NewInvalid("not a stored object")
+	}
+
+	bytes, err := proto.Marshal(snapshot)
+	if err != nil {
+		return errors.NewInvalid("snapshot encoding failed: %v", err)
+	}
+
+	entry, err := s.snapshots.Set(boltdb.Index(snapshot.Index), string(snapshot.ID), bytes, boltdb.IfVersion(boltdb.Version(snapshot.Revision)))
+	if err != nil {
+		return err
+	}
+
+	snapshot.Revision = networksnapshot.Revision(entry.Version)
+	snapshot.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) Delete(snapshot *networksnapshot.NetworkSnapshot) error {
+	if snapshot.Revision == 0 {
+		return errors.NewInvalid("not a stored object")
+	}
+
+	entry, err := s.snapshots.RemoveIndex(boltdb.Index(snapshot.Index), boltdb.IfVersion(boltdb.Version(snapshot.Revision)))
+	if err != nil {
+		return err
+	}
+
+	snapshot.Revision = 0
+	snapshot.Updated = entry.Updated
+	return nil
+}
+
+func (s *boltStore) List(ch chan<- *networksnapshot.NetworkSnapshot) (stream.Context, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Entry)
+	if err := s.snapshots.Entries(ctx, mapCh); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for entry := range mapCh {
+			if snapshot, err := decodeBoltSnapshot(entry); err == nil {
+				ch <- snapshot
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+func (s *boltStore) Watch(ch chan<- stream.Event) (stream.Context, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mapCh := make(chan *boltdb.Event)
+	if err := s.snapshots.Watch(ctx, mapCh, boltdb.WithReplay()); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if snapshot, err := decodeBoltSnapshot(event.Entry); err == nil {
+				switch event.Type {
+				case boltdb.EventNone:
+					ch <- stream.Event{
+						Type:   stream.None,
+						Object: snapshot,
+					}
+				case boltdb.EventInserted:
+					ch <- stream.Event{
+						Type:   stream.Created,
+						Object: snapshot,
+					}
+				case boltdb.EventUpdated:
+					ch <- stream.Event{
+						Type:   stream.Updated,
+						Object: snapshot,
+					}
+				case boltdb.EventRemoved:
+					ch <- stream.Event{
+						Type:   stream.Deleted,
+						Object: snapshot,
+					}
+				}
+			}
+		}
+	}()
+	return stream.NewCancelContext(cancel), nil
+}
+
+// Close does not close the database, which is shared with the other stores
+func (s *boltStore) Close() error {
+	return nil
+}
+
+func decodeBoltSnapshot(entry *boltdb.Entry) (*networksnapshot.NetworkSnapshot, error) {
+	snapshot := &networksnapshot.NetworkSnapshot{}
+	if err := proto.Unmarshal(entry.Value, snapshot); err != nil {
+		return nil, errors.NewInvalid("snapshot decoding failed: %v", err)
+	}
+	snapshot.ID = networksnapshot.ID(entry.Key)
+	snapshot.Index = networksnapshot.Index(entry.Index)
+	snapshot.Revision = networksnapshot.Revision(entry.Version)
+	snapshot.Created = entry.Created
+	snapshot.Updated = entry.Updated
+	return snapshot, nil
+}
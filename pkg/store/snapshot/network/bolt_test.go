@@ -0,0 +1,79 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/config/snapshot"
+	networksnapshot "github.com/onosproject/onos-api/go/onos/config/snapshot/network"
+	"github.com/onosproject/onos-config/pkg/store/boltdb"
+	"github.com/onosproject/onos-config/pkg/store/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network-snapshots")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "onos-config.db")
+
+	db, err := boltdb.Open(path)
+	assert.NoError(t, err)
+	store, err := NewBoltStore(db)
+	assert.NoError(t, err)
+
+	retainWindow := 24 * time.Hour
+	snapshot1 := &networksnapshot.NetworkSnapshot{
+		Retention: snapshot.RetentionOptions{
+			RetainWindow: &retainWindow,
+		},
+	}
+	assert.NoError(t, store.Create(snapshot1))
+	assert.NotEqual(t, networksnapshot.ID(""), snapshot1.ID)
+	assert.Equal(t, networksnapshot.Index(1), snapshot1.Index)
+	snapshot1.Status.State = snapshot.State_RUNNING
+	assert.NoError(t, store.Update(snapshot1))
+
+	// The snapshots are kept when the database is reopened
+	assert.NoError(t, db.Close())
+	db, err = boltdb.Open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	store, err = NewBoltStore(db)
+	assert.NoError(t, err)
+
+	stored, err := store.GetByIndex(1)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot1.ID, stored.ID)
+	assert.Equal(t, snapshot.State_RUNNING, stored.Status.State)
+	assert.Equal(t, retainWindow, *stored.Retention.RetainWindow)
+
+	ch := make(chan stream.Event)
+	ctx, err := store.Watch(ch)
+	assert.NoError(t, err)
+	defer ctx.Close()
+	assert.Equal(t, snapshot1.ID, nextEvent(t, ch).ID)
+
+	assert.NoError(t, store.Delete(stored))
+	assert.Equal(t, snapshot1.ID, nextEvent(t, ch).ID)
+	stored, err = store.Get(snapshot1.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+}
@@ -0,0 +1,334 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package emulator runs a gNMI device in process for tests. The device behaves after a
+// model plugin: its configuration is validated by the plugin and kept as the ygot
+// GoStruct of the plugin, and its state is set by the test or generated. Latency,
+// errors and disconnections can be injected to exercise the handling of faulty devices.
+package emulator
+
+import (
+	"crypto/tls"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var log = logging.GetLogger("test", "emulator")
+
+// gnmiVersion is the gNMI version the emulator advertises
+const gnmiVersion = "0.7.0"
+
+// StateFunc generates the state of the device at a time. The values returned are set,
+// those of other paths are left as they are
+type StateFunc func(now time.Time) []*devicechange.PathValue
+
+// Option is an option of the emulator
+type Option func(e *Emulator)
+
+// WithAddress sets the address the emulator listens on, by default a free local port
+func WithAddress(address string) Option {
+	return func(e *Emulator) {
+		e.address = address
+	}
+}
+
+// WithTLS serves gNMI over TLS with the given configuration rather than plaintext
+func WithTLS(config *tls.Config) Option {
+	return func(e *Emulator) {
+		e.tlsConfig = config
+	}
+}
+
+// WithModels sets the models advertised in the capabilities, by default those of the plugin
+func WithModels(models ...*gnmi.ModelData) Option {
+	return func(e *Emulator) {
+		e.models = models
+	}
+}
+
+// WithEncodings sets the encodings advertised in the capabilities
+func WithEncodings(encodings ...gnmi.Encoding) Option {
+	return func(e *Emulator) {
+		e.encodings = encodings
+	}
+}
+
+// WithConfig sets the initial configuration of the device
+func WithConfig(values ...*devicechange.PathValue) Option {
+	return func(e *Emulator) {
+		for _, value := range values {
+			e.config[value.Path] = value.Value
+		}
+	}
+}
+
+// WithState sets the initial state of the device
+func WithState(values ...*devicechange.PathValue) Option {
+	return func(e *Emulator) {
+		for _, value := range values {
+			e.state[value.Path] = value.Value
+		}
+	}
+}
+
+// WithStateFunc generates the state of the device every interval while it is started
+func WithStateFunc(interval time.Duration, f StateFunc) Option {
+	return func(e *Emulator) {
+		e.stateInterval = interval
+		e.stateFunc = f
+	}
+}
+
+// Emulator is a gNMI device emulated in process
+type Emulator struct {
+	plugin        *modelregistry.ModelPlugin
+	address       string
+	tlsConfig     *tls.Config
+	models        []*gnmi.ModelData
+	encodings     []gnmi.Encoding
+	stateInterval time.Duration
+	stateFunc     StateFunc
+	server        *grpc.Server
+	listener      net.Listener
+	done          chan struct{}
+	config        devicechange.TypedValueMap
+	goStruct      *ygot.ValidatedGoStruct
+	state         devicechange.TypedValueMap
+	faults        []*Fault
+	listeners     map[*listener]struct{}
+	mu            sync.RWMutex
+}
+
+// New returns an emulator of a device of the model plugin. The initial configuration is
+// validated by the plugin
+func New(plugin *modelregistry.ModelPlugin, opts ...Option) (*Emulator, error) {
+	e := &Emulator{
+		plugin:    plugin,
+		address:   "localhost:0",
+		encodings: []gnmi.Encoding{gnmi.Encoding_PROTO, gnmi.Encoding_JSON, gnmi.Encoding_JSON_IETF},
+		config:    make(devicechange.TypedValueMap),
+		state:     make(devicechange.TypedValueMap),
+		listeners: make(map[*listener]struct{}),
+	}
+	if plugin != nil && plugin.Model != nil {
+		e.models = plugin.Model.Data()
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	goStruct, err := e.validate(e.config)
+	if err != nil {
+		return nil, err
+	}
+	e.goStruct = goStruct
+	return e, nil
+}
+
+// Start serves gNMI on the address of the emulator. After a disconnection, the emulator
+// is started again on the same address
+func (e *Emulator) Start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.server != nil {
+		return errors.NewConflict("emulator is already started on %s", e.address)
+	}
+	listener, err := net.Listen("tcp", e.address)
+	if err != nil {
+		return err
+	}
+	var opts []grpc.ServerOption
+	if e.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(e.tlsConfig)))
+	}
+	e.server = grpc.NewServer(opts...)
+	gnmi.RegisterGNMIServer(e.server, e)
+	e.listener = listener
+	e.address = listener.Addr().String()
+	e.done = make(chan struct{})
+	go func(server *grpc.Server) {
+		if err := server.Serve(listener); err != nil {
+			log.Warnf("Emulator on %s stopped serving: %v", listener.Addr(), err)
+		}
+	}(e.server)
+	if e.stateFunc != nil && e.stateInterval > 0 {
+		go e.generateState(e.done)
+	}
+	log.Infof("Emulating %s on %s", e.modelName(), e.address)
+	return nil
+}
+
+// Address returns the address the emulator listens on
+func (e *Emulator) Address() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.address
+}
+
+// Disconnect stops serving and drops the connections and streams of the clients. The
+// emulator can be started again
+func (e *Emulator) Disconnect() {
+	e.mu.Lock()
+	server, done := e.server, e.done
+	e.server, e.listener, e.done = nil, nil, nil
+	e.mu.Unlock()
+	if server == nil {
+		return
+	}
+	close(done)
+	server.Stop()
+	log.Infof("Emulator on %s disconnected", e.Address())
+}
+
+// Stop stops the emulator
+func (e *Emulator) Stop() {
+	e.Disconnect()
+}
+
+// Config returns the configuration of the device as the GoStruct of the plugin, nil if
+// the plugin has no unmarshaler
+func (e *Emulator) Config() *ygot.ValidatedGoStruct {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.goStruct
+}
+
+// ConfigValues returns the configuration of the device sorted by path
+func (e *Emulator) ConfigValues() []*devicechange.PathValue {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return pathValues(e.config)
+}
+
+// StateValues returns the state of the device sorted by path
+func (e *Emulator) StateValues() []*devicechange.PathValue {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return pathValues(e.state)
+}
+
+// SetState sets state values of the device and notifies them to its subscribers
+func (e *Emulator) SetState(values ...*devicechange.PathValue) {
+	e.mu.Lock()
+	changes := make([]*devicechange.PathValue, 0, len(values))
+	for _, value := range values {
+		if current, ok := e.state[value.Path]; !ok || !equalValues(current, value.Value) {
+			e.state[value.Path] = value.Value
+			changes = append(changes, value)
+		}
+	}
+	e.mu.Unlock()
+	e.notify(changes)
+}
+
+// DeleteState removes state values of the device and notifies their removal
+func (e *Emulator) DeleteState(paths ...string) {
+	e.mu.Lock()
+	changes := make([]*devicechange.PathValue, 0, len(paths))
+	for _, path := range paths {
+		if _, ok := e.state[path]; ok {
+			delete(e.state, path)
+			changes = append(changes, &devicechange.PathValue{Path: path})
+		}
+	}
+	e.mu.Unlock()
+	e.notify(changes)
+}
+
+// generateState sets the state generated by the state function every interval until done
+func (e *Emulator) generateState(done <-chan struct{}) {
+	ticker := time.NewTicker(e.stateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			e.SetState(e.stateFunc(now)...)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (e *Emulator) modelName() string {
+	if e.plugin == nil {
+		return "a device"
+	}
+	return e.plugin.Info.String()
+}
+
+// listener is a subscribe stream notified of the changed values of the device. A value
+// without a value is a deleted path
+type listener struct {
+	ch   chan []*devicechange.PathValue
+	done chan struct{}
+}
+
+func (e *Emulator) listen() *listener {
+	l := &listener{
+		ch:   make(chan []*devicechange.PathValue, 100),
+		done: make(chan struct{}),
+	}
+	e.mu.Lock()
+	e.listeners[l] = struct{}{}
+	e.mu.Unlock()
+	return l
+}
+
+func (e *Emulator) unlisten(l *listener) {
+	e.mu.Lock()
+	delete(e.listeners, l)
+	e.mu.Unlock()
+	close(l.done)
+}
+
+// notify sends changed values to the listeners
+func (e *Emulator) notify(changes []*devicechange.PathValue) {
+	if len(changes) == 0 {
+		return
+	}
+	e.mu.RLock()
+	listeners := make([]*listener, 0, len(e.listeners))
+	for l := range e.listeners {
+		listeners = append(listeners, l)
+	}
+	e.mu.RUnlock()
+	for _, l := range listeners {
+		select {
+		case l.ch <- changes:
+		case <-l.done:
+		}
+	}
+}
+
+// pathValues returns the values of a map sorted by path
+func pathValues(values devicechange.TypedValueMap) []*devicechange.PathValue {
+	result := make([]*devicechange.PathValue, 0, len(values))
+	for path, value := range values {
+		result = append(result, &devicechange.PathValue{Path: path, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
@@ -0,0 +1,284 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	configmodel "github.com/onosproject/onos-config-model/pkg/model"
+	topodevice "github.com/onosproject/onos-config/pkg/device"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-config/pkg/southbound"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gotest.tools/assert"
+)
+
+const (
+	hostnamePath = "/system/config/hostname"
+	uptimePath   = "/system/state/uptime"
+)
+
+// testDevice is the GoStruct of the test model
+type testDevice struct {
+	System struct {
+		Config struct {
+			Hostname string `json:"hostname"`
+		} `json:"config"`
+	} `json:"system"`
+}
+
+func (*testDevice) IsYANGGoStruct() {}
+
+func (d *testDevice) Validate(...ygot.ValidationOption) error {
+	if d.System.Config.Hostname == "invalid" {
+		return fmt.Errorf("invalid hostname")
+	}
+	return nil
+}
+
+func (*testDevice) ΛEnumTypeMap() map[string][]reflect.Type {
+	return nil
+}
+
+// testModel is a config model of a device with a hostname
+type testModel struct {
+	configmodel.ConfigModel
+}
+
+func (testModel) Data() []*gnmi.ModelData {
+	return []*gnmi.ModelData{{Name: "openconfig-system", Organization: "OpenConfig working group", Version: "0.9.1"}}
+}
+
+func (testModel) Unmarshaler() configmodel.Unmarshaler {
+	return func(jsonTree []byte) (*ygot.ValidatedGoStruct, error) {
+		device := &testDevice{}
+		if err := json.Unmarshal(jsonTree, device); err != nil {
+			return nil, err
+		}
+		goStruct := ygot.ValidatedGoStruct(device)
+		return &goStruct, nil
+	}
+}
+
+func (testModel) Validator() configmodel.Validator {
+	return func(model *ygot.ValidatedGoStruct, opts ...ygot.ValidationOption) error {
+		return (*model).Validate(opts...)
+	}
+}
+
+func newTestEmulator(t *testing.T, opts ...Option) (*Emulator, gnmi.GNMIClient) {
+	plugin := &modelregistry.ModelPlugin{
+		Info:  configmodel.ModelInfo{Name: "Test", Version: "1.0.0"},
+		Model: testModel{},
+		ReadWritePaths: modelregistry.ReadWritePathMap{
+			hostnamePath: modelregistry.ReadWritePathElem{
+				ReadOnlyAttrib: modelregistry.ReadOnlyAttrib{ValueType: devicechange.ValueType_STRING},
+			},
+		},
+	}
+	emulator, err := New(plugin, opts...)
+	assert.NilError(t, err)
+	assert.NilError(t, emulator.Start())
+	t.Cleanup(emulator.Stop)
+
+	conn, err := grpc.Dial(emulator.Address(), grpc.WithInsecure())
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return emulator, gnmi.NewGNMIClient(conn)
+}
+
+func stringUpdate(path string, value string) *gnmi.Update {
+	return &gnmi.Update{
+		Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "config"}, {Name: path}}},
+		Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: value}},
+	}
+}
+
+func Test_Capabilities(t *testing.T) {
+	_, client := newTestEmulator(t, WithEncodings(gnmi.Encoding_PROTO))
+	response, err := client.Capabilities(context.Background(), &gnmi.CapabilityRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(response.SupportedModels), 1)
+	assert.Equal(t, response.SupportedModels[0].Name, "openconfig-system")
+	assert.DeepEqual(t, response.SupportedEncodings, []gnmi.Encoding{gnmi.Encoding_PROTO})
+}
+
+func Test_SetGet(t *testing.T) {
+	emulator, client := newTestEmulator(t,
+		WithState(&devicechange.PathValue{Path: uptimePath, Value: devicechange.NewTypedValueUint(10, 64)}))
+	ctx := context.Background()
+
+	_, err := client.Set(ctx, &gnmi.SetRequest{Update: []*gnmi.Update{stringUpdate("hostname", "switch1")}})
+	assert.NilError(t, err)
+	assert.Equal(t, (*emulator.Config()).(*testDevice).System.Config.Hostname, "switch1")
+
+	// Invalid configuration is refused and leaves the configuration as it was
+	_, err = client.Set(ctx, &gnmi.SetRequest{Update: []*gnmi.Update{stringUpdate("hostname", "invalid")}})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	assert.Equal(t, emulator.ConfigValues()[0].Value.ValueToString(), "switch1")
+
+	response, err := client.Get(ctx, &gnmi.GetRequest{
+		Path:     []*gnmi.Path{{Elem: []*gnmi.PathElem{{Name: "system"}}}},
+		Type:     gnmi.GetRequest_CONFIG,
+		Encoding: gnmi.Encoding_PROTO,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(response.Notification[0].Update), 1)
+	assert.Equal(t, response.Notification[0].Update[0].Val.GetStringVal(), "switch1")
+
+	response, err = client.Get(ctx, &gnmi.GetRequest{
+		Path:     []*gnmi.Path{{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "*"}}}},
+		Encoding: gnmi.Encoding_JSON_IETF,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, string(response.Notification[0].Update[0].Val.GetJsonIetfVal()),
+		"{\n  \"system\": {\n    \"config\": {\n      \"hostname\": \"switch1\"\n    },\n    \"state\": {\n      \"uptime\": \"10\"\n    }\n  }\n}")
+
+	_, err = client.Set(ctx, &gnmi.SetRequest{Delete: []*gnmi.Path{{Elem: []*gnmi.PathElem{{Name: "system"}}}}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(emulator.ConfigValues()), 0)
+}
+
+func Test_Subscribe(t *testing.T) {
+	emulator, client := newTestEmulator(t,
+		WithState(&devicechange.PathValue{Path: uptimePath, Value: devicechange.NewTypedValueUint(10, 64)}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Subscribe(ctx)
+	assert.NilError(t, err)
+	err = stream.Send(&gnmi.SubscribeRequest{Request: &gnmi.SubscribeRequest_Subscribe{
+		Subscribe: &gnmi.SubscriptionList{
+			Encoding: gnmi.Encoding_PROTO,
+			Subscription: []*gnmi.Subscription{{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "state"}}},
+				Mode: gnmi.SubscriptionMode_ON_CHANGE,
+			}},
+		},
+	}})
+	assert.NilError(t, err)
+
+	response, err := stream.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, response.GetUpdate().Update[0].Val.GetUintVal(), uint64(10))
+	response, err = stream.Recv()
+	assert.NilError(t, err)
+	assert.Assert(t, response.GetSyncResponse())
+
+	// Configuration changes are not subscribed to
+	_, err = client.Set(ctx, &gnmi.SetRequest{Update: []*gnmi.Update{stringUpdate("hostname", "switch1")}})
+	assert.NilError(t, err)
+	emulator.SetState(&devicechange.PathValue{Path: uptimePath, Value: devicechange.NewTypedValueUint(20, 64)})
+	response, err = stream.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, response.GetUpdate().Update[0].Val.GetUintVal(), uint64(20))
+
+	emulator.DeleteState(uptimePath)
+	response, err = stream.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, len(response.GetUpdate().Delete), 1)
+}
+
+func Test_SampleStateFunc(t *testing.T) {
+	uptime := uint(0)
+	_, client := newTestEmulator(t, WithStateFunc(5*time.Millisecond, func(time.Time) []*devicechange.PathValue {
+		uptime++
+		return []*devicechange.PathValue{{Path: uptimePath, Value: devicechange.NewTypedValueUint(uptime, 64)}}
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Subscribe(ctx)
+	assert.NilError(t, err)
+	err = stream.Send(&gnmi.SubscribeRequest{Request: &gnmi.SubscribeRequest_Subscribe{
+		Subscribe: &gnmi.SubscriptionList{
+			Encoding: gnmi.Encoding_PROTO,
+			Subscription: []*gnmi.Subscription{{
+				Path:           &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "state"}, {Name: "uptime"}}},
+				Mode:           gnmi.SubscriptionMode_SAMPLE,
+				SampleInterval: uint64(20 * time.Millisecond),
+			}},
+			UpdatesOnly: true,
+		},
+	}})
+	assert.NilError(t, err)
+	response, err := stream.Recv()
+	assert.NilError(t, err)
+	assert.Assert(t, response.GetSyncResponse())
+
+	var last uint64
+	for i := 0; i < 2; i++ {
+		response, err = stream.Recv()
+		assert.NilError(t, err)
+		value := response.GetUpdate().Update[0].Val.GetUintVal()
+		assert.Assert(t, value > last)
+		last = value
+	}
+}
+
+func Test_Faults(t *testing.T) {
+	emulator, client := newTestEmulator(t)
+	ctx := context.Background()
+	request := &gnmi.GetRequest{Path: []*gnmi.Path{{}}}
+
+	emulator.InjectFault(Fault{RPC: GetRPC, Error: status.Error(codes.Internal, "get failed"), Times: 1})
+	_, err := client.Get(ctx, request)
+	assert.Equal(t, status.Code(err), codes.Internal)
+	_, err = client.Get(ctx, request)
+	assert.NilError(t, err)
+
+	emulator.InjectFault(Fault{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	_, err = client.Capabilities(ctx, &gnmi.CapabilityRequest{})
+	assert.NilError(t, err)
+	assert.Assert(t, time.Since(start) >= 50*time.Millisecond)
+	emulator.ClearFaults()
+
+	emulator.Disconnect()
+	_, err = client.Get(ctx, request)
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+	assert.NilError(t, emulator.Start())
+	_, err = client.Get(ctx, request, grpc.WaitForReady(true))
+	assert.NilError(t, err)
+}
+
+func Test_SouthboundTarget(t *testing.T) {
+	emulator, _ := newTestEmulator(t)
+	device := topodevice.Device{
+		ID:      "emulated",
+		Address: emulator.Address(),
+		Timeout: &[]time.Duration{5 * time.Second}[0],
+		TLS:     topodevice.TLSConfig{Plain: true},
+	}
+	target := &southbound.Target{}
+	_, err := target.ConnectTarget(context.Background(), device)
+	assert.NilError(t, err)
+
+	response, err := target.Capabilities(context.Background(), &gnmi.CapabilityRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, response.SupportedModels[0].Version, "0.9.1")
+}
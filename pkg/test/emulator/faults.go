@@ -0,0 +1,93 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emulator
+
+import (
+	"time"
+)
+
+// RPC is a gNMI RPC of the emulator
+type RPC string
+
+const (
+	// AnyRPC is any of the RPCs
+	AnyRPC RPC = ""
+	// CapabilitiesRPC is the Capabilities RPC
+	CapabilitiesRPC RPC = "Capabilities"
+	// GetRPC is the Get RPC
+	GetRPC RPC = "Get"
+	// SetRPC is the Set RPC
+	SetRPC RPC = "Set"
+	// SubscribeRPC is the Subscribe RPC
+	SubscribeRPC RPC = "Subscribe"
+)
+
+// Fault is a fault of the calls of an RPC. The calls are delayed by the latency, then
+// fail with the error if any
+type Fault struct {
+	// RPC is the RPC whose calls are faulty
+	RPC RPC
+	// Latency delays the calls
+	Latency time.Duration
+	// Error is returned by the calls, typically a gRPC status error
+	Error error
+	// Times is the number of calls that are faulty, all calls until the faults are
+	// cleared if 0
+	Times int
+}
+
+// InjectFault makes the calls of an RPC faulty
+func (e *Emulator) InjectFault(fault Fault) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.faults = append(e.faults, &fault)
+}
+
+// ClearFaults removes the injected faults
+func (e *Emulator) ClearFaults() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.faults = nil
+}
+
+// fault applies the faults of a call of an RPC, returning the error it is to fail with
+func (e *Emulator) fault(rpc RPC) error {
+	var latency time.Duration
+	var err error
+	e.mu.Lock()
+	faults := e.faults[:0]
+	for _, fault := range e.faults {
+		if fault.RPC == AnyRPC || fault.RPC == rpc {
+			latency += fault.Latency
+			if err == nil {
+				err = fault.Error
+			}
+			if fault.Times > 0 {
+				fault.Times--
+				if fault.Times == 0 {
+					continue
+				}
+			}
+		}
+		faults = append(faults, fault)
+	}
+	e.faults = faults
+	e.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return err
+}
@@ -0,0 +1,466 @@
+// Copyright 2021-present Open Networking Foundation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	devicechange "github.com/onosproject/onos-api/go/onos/config/change/device"
+	"github.com/onosproject/onos-config/pkg/modelregistry"
+	"github.com/onosproject/onos-config/pkg/modelregistry/jsonvalues"
+	"github.com/onosproject/onos-config/pkg/store"
+	"github.com/onosproject/onos-config/pkg/utils"
+	"github.com/onosproject/onos-config/pkg/utils/values"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultSampleInterval is the interval of sample subscriptions that give none
+const defaultSampleInterval = time.Second
+
+// Capabilities returns the models of the plugin and the supported encodings
+func (e *Emulator) Capabilities(ctx context.Context, request *gnmi.CapabilityRequest) (*gnmi.CapabilityResponse, error) {
+	if err := e.fault(CapabilitiesRPC); err != nil {
+		return nil, err
+	}
+	return &gnmi.CapabilityResponse{
+		SupportedModels:    e.models,
+		SupportedEncodings: e.encodings,
+		GNMIVersion:        gnmiVersion,
+	}, nil
+}
+
+// Get returns the configuration, the state or both under the requested paths
+func (e *Emulator) Get(ctx context.Context, request *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	if err := e.fault(GetRPC); err != nil {
+		return nil, err
+	}
+	if !e.supportsEncoding(request.Encoding) {
+		return nil, status.Errorf(codes.Unimplemented, "encoding %s is not supported", request.Encoding)
+	}
+	paths := request.Path
+	if len(paths) == 0 {
+		paths = []*gnmi.Path{{}}
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	notifications := make([]*gnmi.Notification, 0, len(paths))
+	for _, path := range paths {
+		notification, err := newNotification(e.values(joinPath(request.Prefix, path), request.Type), request.Encoding)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		notifications = append(notifications, notification)
+	}
+	return &gnmi.GetResponse{Notification: notifications}, nil
+}
+
+// Set applies the deletes, replaces and updates of the request to the configuration,
+// which is changed only if the result is valid for the plugin
+func (e *Emulator) Set(ctx context.Context, request *gnmi.SetRequest) (*gnmi.SetResponse, error) {
+	if err := e.fault(SetRPC); err != nil {
+		return nil, err
+	}
+	results, changes, err := e.setConfig(request)
+	if err != nil {
+		return nil, err
+	}
+	e.notify(changes)
+	return &gnmi.SetResponse{
+		Prefix:    request.Prefix,
+		Response:  results,
+		Timestamp: time.Now().UnixNano(),
+	}, nil
+}
+
+func (e *Emulator) setConfig(request *gnmi.SetRequest) ([]*gnmi.UpdateResult, []*devicechange.PathValue, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	config := make(devicechange.TypedValueMap, len(e.config))
+	for path, value := range e.config {
+		config[path] = value
+	}
+
+	results := make([]*gnmi.UpdateResult, 0, len(request.Delete)+len(request.Replace)+len(request.Update))
+	for _, path := range request.Delete {
+		deletePaths(config, joinPath(request.Prefix, path))
+		results = append(results, &gnmi.UpdateResult{Path: path, Op: gnmi.UpdateResult_DELETE})
+	}
+	for _, update := range request.Replace {
+		path := joinPath(request.Prefix, update.Path)
+		deletePaths(config, path)
+		if err := e.setValues(config, path, update.Val); err != nil {
+			return nil, nil, err
+		}
+		results = append(results, &gnmi.UpdateResult{Path: update.Path, Op: gnmi.UpdateResult_REPLACE})
+	}
+	for _, update := range request.Update {
+		if err := e.setValues(config, joinPath(request.Prefix, update.Path), update.Val); err != nil {
+			return nil, nil, err
+		}
+		results = append(results, &gnmi.UpdateResult{Path: update.Path, Op: gnmi.UpdateResult_UPDATE})
+	}
+
+	goStruct, err := e.validate(config)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	changes := diffValues(e.config, config)
+	e.config, e.goStruct = config, goStruct
+	return results, changes, nil
+}
+
+// setValues sets the values of an update in the configuration, decomposing JSON values
+// after the read write paths of the plugin
+func (e *Emulator) setValues(config devicechange.TypedValueMap, path string, value *gnmi.TypedValue) error {
+	jsonVal := value.GetJsonVal()
+	if jsonVal == nil {
+		jsonVal = value.GetJsonIetfVal()
+	}
+	if jsonVal != nil {
+		prefix := path
+		if prefix == "/" {
+			prefix = ""
+		}
+		pathValues, err := jsonvalues.DecomposeJSONWithPaths(prefix, jsonVal, nil, e.readWritePaths())
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		for _, pathValue := range pathValues {
+			config[pathValue.Path] = pathValue.Value
+		}
+		return nil
+	}
+
+	var modelPath *modelregistry.ReadWritePathElem
+	if elem, ok := e.readWritePaths()[modelregistry.AnonymizePathIndices(path)]; ok {
+		modelPath = &elem
+	}
+	typedValue, err := values.GnmiTypedValueToNativeType(value, modelPath)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	config[path] = typedValue
+	return nil
+}
+
+func (e *Emulator) readWritePaths() modelregistry.ReadWritePathMap {
+	if e.plugin == nil {
+		return nil
+	}
+	return e.plugin.ReadWritePaths
+}
+
+// validate unmarshals a configuration to the GoStruct of the plugin and validates it
+func (e *Emulator) validate(config devicechange.TypedValueMap) (*ygot.ValidatedGoStruct, error) {
+	if e.plugin == nil || e.plugin.Model == nil || e.plugin.Model.Unmarshaler() == nil {
+		return nil, nil
+	}
+	jsonTree, err := store.BuildTree(pathValues(config), true)
+	if err != nil {
+		return nil, err
+	}
+	goStruct, err := e.plugin.Model.Unmarshaler()(jsonTree)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaller error: %v", err)
+	}
+	if validator := e.plugin.Model.Validator(); validator != nil {
+		if err := validator(goStruct); err != nil {
+			return nil, fmt.Errorf("validation error %s", err.Error())
+		}
+	}
+	return goStruct, nil
+}
+
+// Subscribe streams the values under the subscribed paths once, on each poll or on
+// change and at sample intervals
+func (e *Emulator) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	if err := e.fault(SubscribeRPC); err != nil {
+		return err
+	}
+	request, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	list := request.GetSubscribe()
+	if list == nil {
+		return status.Error(codes.InvalidArgument, "the first subscribe request must be a subscription list")
+	}
+	if !e.supportsEncoding(list.Encoding) {
+		return status.Errorf(codes.Unimplemented, "encoding %s is not supported", list.Encoding)
+	}
+	queries := make([]string, 0, len(list.Subscription))
+	for _, subscription := range list.Subscription {
+		queries = append(queries, joinPath(list.Prefix, subscription.Path))
+	}
+
+	// Listen before sending the current values for no change to be missed
+	l := e.listen()
+	defer e.unlisten(l)
+	if !list.UpdatesOnly {
+		if err := e.send(stream, e.subscribedValues(queries), list.Encoding); err != nil {
+			return err
+		}
+	}
+	if err := stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}); err != nil {
+		return err
+	}
+
+	switch list.Mode {
+	case gnmi.SubscriptionList_ONCE:
+		return nil
+	case gnmi.SubscriptionList_POLL:
+		for {
+			request, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if request.GetPoll() == nil {
+				return status.Error(codes.InvalidArgument, "only polls are expected after the subscription list")
+			}
+			if err := e.send(stream, e.subscribedValues(queries), list.Encoding); err != nil {
+				return err
+			}
+			if err := stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}); err != nil {
+				return err
+			}
+		}
+	}
+	return e.stream(stream, list, queries, l)
+}
+
+// stream sends the changes of the values of on change subscriptions and the values of
+// sample subscriptions at their interval until the stream is done
+func (e *Emulator) stream(stream gnmi.GNMI_SubscribeServer, list *gnmi.SubscriptionList, queries []string, l *listener) error {
+	ctx := stream.Context()
+	samples := make(chan int)
+	onChange := make([]string, 0, len(queries))
+	for i, subscription := range list.Subscription {
+		if subscription.Mode != gnmi.SubscriptionMode_SAMPLE {
+			onChange = append(onChange, queries[i])
+			continue
+		}
+		interval := time.Duration(subscription.SampleInterval)
+		if interval == 0 {
+			interval = defaultSampleInterval
+		}
+		go func(i int, interval time.Duration) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					select {
+					case samples <- i:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i, interval)
+	}
+
+	for {
+		select {
+		case changes := <-l.ch:
+			matched := make([]*devicechange.PathValue, 0, len(changes))
+			for _, change := range changes {
+				if matchesAny(onChange, change.Path) {
+					matched = append(matched, change)
+				}
+			}
+			if err := e.send(stream, matched, list.Encoding); err != nil {
+				return err
+			}
+		case i := <-samples:
+			if err := e.send(stream, e.subscribedValues(queries[i:i+1]), list.Encoding); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// send sends the values as a notification, unless there are none
+func (e *Emulator) send(stream gnmi.GNMI_SubscribeServer, pathValues []*devicechange.PathValue, encoding gnmi.Encoding) error {
+	if len(pathValues) == 0 {
+		return nil
+	}
+	notification, err := newNotification(pathValues, encoding)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: notification}})
+}
+
+// subscribedValues returns the configuration and state values under any of the paths
+func (e *Emulator) subscribedValues(queries []string) []*devicechange.PathValue {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	result := make([]*devicechange.PathValue, 0)
+	for _, value := range append(pathValues(e.config), pathValues(e.state)...) {
+		if matchesAny(queries, value.Path) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// values returns the values of a data type under a path
+func (e *Emulator) values(query string, dataType gnmi.GetRequest_DataType) []*devicechange.PathValue {
+	var candidates []*devicechange.PathValue
+	switch dataType {
+	case gnmi.GetRequest_CONFIG:
+		candidates = pathValues(e.config)
+	case gnmi.GetRequest_STATE, gnmi.GetRequest_OPERATIONAL:
+		candidates = pathValues(e.state)
+	default:
+		candidates = append(pathValues(e.config), pathValues(e.state)...)
+	}
+	result := make([]*devicechange.PathValue, 0)
+	queryRegexp := pathRegexp(query)
+	for _, value := range candidates {
+		if queryRegexp.MatchString(value.Path) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (e *Emulator) supportsEncoding(encoding gnmi.Encoding) bool {
+	for _, supported := range e.encodings {
+		if supported == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// newNotification returns a notification of values, deleted where they have no value.
+// With a JSON encoding, the values are sent as one tree from the root
+func newNotification(pathValues []*devicechange.PathValue, encoding gnmi.Encoding) (*gnmi.Notification, error) {
+	notification := &gnmi.Notification{
+		Timestamp: time.Now().UnixNano(),
+	}
+	updates := make([]*devicechange.PathValue, 0, len(pathValues))
+	for _, pathValue := range pathValues {
+		if pathValue.Value == nil {
+			path, err := utils.ParseGNMIElements(utils.SplitPath(pathValue.Path))
+			if err != nil {
+				return nil, err
+			}
+			notification.Delete = append(notification.Delete, path)
+		} else {
+			updates = append(updates, pathValue)
+		}
+	}
+	if len(updates) == 0 {
+		return notification, nil
+	}
+
+	if encoding == gnmi.Encoding_JSON || encoding == gnmi.Encoding_JSON_IETF {
+		jsonTree, err := store.BuildTree(updates, encoding == gnmi.Encoding_JSON_IETF)
+		if err != nil {
+			return nil, err
+		}
+		value := &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: jsonTree}}
+		if encoding == gnmi.Encoding_JSON_IETF {
+			value = &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonIetfVal{JsonIetfVal: jsonTree}}
+		}
+		notification.Update = []*gnmi.Update{{Path: &gnmi.Path{}, Val: value}}
+		return notification, nil
+	}
+
+	for _, pathValue := range updates {
+		path, err := utils.ParseGNMIElements(utils.SplitPath(pathValue.Path))
+		if err != nil {
+			return nil, err
+		}
+		value, err := values.NativeTypeToGnmiTypedValue(pathValue.Value)
+		if err != nil {
+			return nil, err
+		}
+		notification.Update = append(notification.Update, &gnmi.Update{Path: path, Val: value})
+	}
+	return notification, nil
+}
+
+// joinPath returns the string form of a path prefixed with the prefix of its request
+func joinPath(prefix *gnmi.Path, path *gnmi.Path) string {
+	elems := make([]*gnmi.PathElem, 0, len(prefix.GetElem())+len(path.GetElem()))
+	elems = append(elems, prefix.GetElem()...)
+	return utils.StrPath(&gnmi.Path{Elem: append(elems, path.GetElem()...)})
+}
+
+// pathRegexp matches the paths at or under a path, which may have wildcards
+func pathRegexp(query string) *regexp.Regexp {
+	if query == "/" {
+		return regexp.MustCompile(`^/`)
+	}
+	exact := utils.MatchWildcardRegexp(query, true).String()
+	return regexp.MustCompile(strings.TrimSuffix(exact, "$") + `(/.*)?$`)
+}
+
+func matchesAny(queries []string, path string) bool {
+	for _, query := range queries {
+		if pathRegexp(query).MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// deletePaths removes the values at or under a path
+func deletePaths(config devicechange.TypedValueMap, path string) {
+	pathRegexp := pathRegexp(path)
+	for configPath := range config {
+		if pathRegexp.MatchString(configPath) {
+			delete(config, configPath)
+		}
+	}
+}
+
+// diffValues returns the values that changed between two configurations, without a
+// value for the deleted paths
+func diffValues(before devicechange.TypedValueMap, after devicechange.TypedValueMap) []*devicechange.PathValue {
+	changes := make([]*devicechange.PathValue, 0)
+	for _, value := range pathValues(after) {
+		if previous, ok := before[value.Path]; !ok || !equalValues(previous, value.Value) {
+			changes = append(changes, value)
+		}
+	}
+	for _, value := range pathValues(before) {
+		if _, ok := after[value.Path]; !ok {
+			changes = append(changes, &devicechange.PathValue{Path: value.Path})
+		}
+	}
+	return changes
+}
+
+func equalValues(a *devicechange.TypedValue, b *devicechange.TypedValue) bool {
+	return a.Type == b.Type && bytes.Equal(a.Bytes, b.Bytes) && reflect.DeepEqual(a.TypeOpts, b.TypeOpts)
+}
@@ -165,6 +165,20 @@ func (mr *MockDeviceSnapshotStoreMockRecorder) Load(deviceID interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockDeviceSnapshotStore)(nil).Load), deviceID)
 }
 
+// Purge mocks base method
+func (m *MockDeviceSnapshotStore) Purge(deviceID device.VersionedID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", deviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge
+func (mr *MockDeviceSnapshotStoreMockRecorder) Purge(deviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockDeviceSnapshotStore)(nil).Purge), deviceID)
+}
+
 // LoadAll mocks base method
 func (m *MockDeviceSnapshotStore) LoadAll(ch chan<- *device0.Snapshot) (stream.Context, error) {
 	m.ctrl.T.Helper()
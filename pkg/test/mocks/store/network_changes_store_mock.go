@@ -167,6 +167,21 @@ func (mr *MockNetworkChangesStoreMockRecorder) List(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockNetworkChangesStore)(nil).List), arg0)
 }
 
+// Query mocks base method
+func (m *MockNetworkChangesStore) Query(query network.Query) (*network.QueryResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", query)
+	ret0, _ := ret[0].(*network.QueryResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query
+func (mr *MockNetworkChangesStoreMockRecorder) Query(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockNetworkChangesStore)(nil).Query), query)
+}
+
 // Watch mocks base method
 func (m *MockNetworkChangesStore) Watch(arg0 chan<- stream.Event, arg1 ...network.WatchOption) (stream.Context, error) {
 	m.ctrl.T.Helper()
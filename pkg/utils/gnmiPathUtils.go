@@ -151,10 +151,15 @@ func SplitPath(path string) []string {
 	for len(path) > 0 {
 		i := nextTokenIndex(path)
 		part := path[:i]
-		partsNs := strings.Split(part, ":")
+		name, keys := part, ""
+		if k := strings.IndexByte(part, '['); k >= 0 {
+			// The keys may contain colons, which are not namespaces
+			name, keys = part[:k], part[k:]
+		}
+		partsNs := strings.Split(name, ":")
 		if len(partsNs) == 2 {
 			// We have to discard the namespace as gNMI doesn't handle it
-			part = partsNs[1]
+			part = partsNs[1] + keys
 		}
 		result = append(result, part)
 		path = path[i:]
@@ -133,6 +133,18 @@ func Test_ParseNamespace(t *testing.T) {
 	checkElement(t, parsed, 0, "a", "x", "y")
 }
 
+func Test_ParseKeyWithColon(t *testing.T) {
+	elements := SplitPath("/a[name=eth1:1]/b")
+	parsed, err := ParseGNMIElements(elements)
+	assert.NoError(t, err)
+	checkElement(t, parsed, 0, "a", "name", "eth1:1")
+
+	elements = SplitPath("/ns:a[name=eth1:1]/b")
+	parsed, err = ParseGNMIElements(elements)
+	assert.NoError(t, err)
+	checkElement(t, parsed, 0, "a", "name", "eth1:1")
+}
+
 func Test_StrPath(t *testing.T) {
 	elements := SplitPath(path1)
 	parsed, err := ParseGNMIElements(elements)